@@ -0,0 +1,500 @@
+// Package checker statically checks the types of Owl programs. Types come
+// from the optional annotations on let bindings and on function parameters
+// and results, and are inferred for everything else, following imports into
+// other modules. The checker is lenient: it only reports operations that can
+// never succeed, so a value of unknown type, or one that may be of several
+// types, is assumed to be used correctly.
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/lexer"
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+// Problem is a type error found by the checker, at Token in the file at
+// Path.
+type Problem struct {
+	Path    string
+	Token   lexer.Token
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.Path, p.Token.Line, p.Token.Column, p.Message)
+}
+
+// program is the state shared by the files of a program while it is checked.
+type program struct {
+	problems []Problem
+	modules  map[string]*Type // module types by absolute path, nil while a module is being checked
+}
+
+// CheckTypes checks the program in the file at path, and the modules it
+// imports. The problems are sorted by file and position. Files that cannot
+// be parsed are reported with their parser errors.
+func CheckTypes(path string) ([]Problem, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	p := &program{modules: map[string]*Type{}}
+	p.check(path, filepath.Base(path))
+
+	sort.SliceStable(p.problems, func(i, j int) bool {
+		a, b := p.problems[i], p.problems[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Token.Line != b.Token.Line {
+			return a.Token.Line < b.Token.Line
+		}
+		return a.Token.Column < b.Token.Column
+	})
+
+	return p.problems, nil
+}
+
+// check checks the file at path and returns the type of the module it
+// defines, named alias.
+func (p *program) check(path string, alias string) *Type {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+
+	if t, ok := p.modules[key]; ok {
+		if t == nil {
+			// An import cycle, which the executor reports when it runs
+			return objectType
+		}
+		return t
+	}
+	p.modules[key] = nil
+
+	f := &file{program: p, path: path, scope: newScope(nil), annotations: map[*parser.Type]*Type{}}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		f.report("Unable to read module, "+err.Error(), lexer.Token{Line: 1, Column: 1})
+		p.modules[key] = objectType
+		return objectType
+	}
+
+	params, errs := exec.LoadProgram(string(bytes), path)
+	if errs != nil {
+		for _, e := range errs {
+			f.report(e.Message, e.Token)
+		}
+		p.modules[key] = objectType
+		return objectType
+	}
+
+	f.block(params.Program.Body)
+
+	module := &Type{Name: "object", Module: alias, Attrs: map[string]*Type{}}
+	for name, b := range f.scope.vars {
+		if f.exports == nil || f.exports[name] {
+			module.Attrs[name] = b.typ
+		}
+	}
+
+	p.modules[key] = module
+	return module
+}
+
+// binding is what is known about a variable. Variables with an annotation
+// always have the declared type, values assigned to them are checked
+// against it.
+type binding struct {
+	typ      *Type
+	declared *Type
+}
+
+// scope holds the variables of a module or a function call. Blocks do not
+// have their own scope.
+type scope struct {
+	vars   map[string]*binding
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: map[string]*binding{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.vars[name]; ok {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+// snapshot copies the variables of s, to be restored or joined after a
+// branch.
+func (s *scope) snapshot() map[string]binding {
+	vars := make(map[string]binding, len(s.vars))
+	for k, b := range s.vars {
+		vars[k] = *b
+	}
+
+	return vars
+}
+
+func (s *scope) restore(vars map[string]binding) {
+	s.vars = make(map[string]*binding, len(vars))
+	for k, b := range vars {
+		b := b
+		s.vars[k] = &b
+	}
+}
+
+// join widens the variables of s to also hold the types they have in vars,
+// when the code may have taken either path.
+func (s *scope) join(vars map[string]binding) {
+	for k, b := range vars {
+		if current, ok := s.vars[k]; ok {
+			current.typ = union(current.typ, b.typ)
+		} else {
+			b := b
+			s.vars[k] = &b
+		}
+	}
+}
+
+// fn is the function whose body is being checked.
+type fn struct {
+	returns  *Type // the annotated result, nil when there is none
+	returned []*Type
+}
+
+// file is the state of the checker in a single file.
+type file struct {
+	*program
+	path        string
+	scope       *scope
+	fn          *fn
+	exports     map[string]bool // the exported names, nil when the module exports none
+	quiet       int             // problems are not reported while this is above 0
+	annotations map[*parser.Type]*Type
+}
+
+func (f *file) report(msg string, tok lexer.Token) {
+	if f.quiet > 0 {
+		return
+	}
+
+	f.problems = append(f.problems, Problem{Path: f.path, Token: tok, Message: msg})
+}
+
+// annotation converts a type annotation, reporting it if it is invalid.
+// Each annotation is converted once, so it is only reported once.
+func (f *file) annotation(t *parser.Type) *Type {
+	if t == nil {
+		return nil
+	}
+
+	if typ, ok := f.annotations[t]; ok {
+		return typ
+	}
+
+	// Reported even while quiet, as it is not reported again
+	typ, msg := annotation(t)
+	if msg != "" {
+		f.problems = append(f.problems, Problem{Path: f.path, Token: t.Token(), Message: msg})
+	}
+	f.annotations[t] = typ
+
+	return typ
+}
+
+func (f *file) block(body []parser.Statement) {
+	for _, s := range body {
+		f.statement(s)
+	}
+}
+
+func (f *file) statement(s parser.Statement) {
+	switch s := s.(type) {
+	case *parser.Let:
+		v, items := f.value(s.Value)
+		f.assign(s.Target, v, items)
+
+	case *parser.ExpressionStatement:
+		f.expr(s.Value)
+
+	case *parser.Print:
+		f.expr(s.Value)
+
+	case *parser.Throw:
+		f.expr(s.Value)
+
+	case *parser.Return:
+		v := nullType
+		if s.Value != nil {
+			v = f.expr(s.Value)
+		}
+		f.returned(v, s)
+
+	case *parser.If:
+		f.expr(s.Test)
+
+		before := f.scope.snapshot()
+		f.block(s.Body)
+		after := f.scope.snapshot()
+
+		f.scope.restore(before)
+		f.block(s.Else)
+		f.scope.join(after)
+
+	case *parser.While:
+		f.expr(s.Test)
+		f.loop(func() {
+			f.block(s.Body)
+			f.expr(s.Test)
+		})
+
+	case *parser.For:
+		item := f.item(f.expr(s.Iter), s.Iter)
+		f.loop(func() {
+			f.assign(s.Target, item, nil)
+			f.block(s.Body)
+		})
+
+	case *parser.Try:
+		before := f.scope.snapshot()
+		f.block(s.Body)
+		f.scope.join(before)
+		f.block(s.Catch)
+		f.block(s.Finally)
+
+	case *parser.Import:
+		f.importStatement(s)
+
+	case *parser.Export:
+		if s.Value != nil {
+			f.statement(s.Value)
+		}
+
+		if f.exports == nil {
+			f.exports = map[string]bool{}
+		}
+		for _, name := range s.Names {
+			f.exports[name] = true
+		}
+	}
+}
+
+// loop checks the body of a loop. The variables of later iterations may have
+// the types given to them by earlier ones, so the body is checked once
+// without reporting problems to find those types, then again with them.
+func (f *file) loop(body func()) {
+	before := f.scope.snapshot()
+
+	f.quiet++
+	body()
+	f.quiet--
+
+	f.scope.join(before)
+	body()
+	f.scope.join(before)
+}
+
+// item returns the type of the items of a value of type t, reporting it if
+// it cannot be iterated over.
+func (f *file) item(t *Type, expr parser.Expression) *Type {
+	items := []*Type{}
+
+	for _, o := range t.options() {
+		switch o.Name {
+		case "any", "object", "function":
+			return anyType
+		case "list":
+			if o.Elem == nil {
+				return anyType
+			}
+			items = append(items, o.Elem)
+		case "string":
+			items = append(items, stringType)
+		}
+	}
+
+	if len(items) == 0 {
+		f.report("Unable to iterate over "+t.String()+" '"+expr.ToString()+"'", expr.Token())
+		return anyType
+	}
+
+	return union(items...)
+}
+
+func (f *file) returned(v *Type, s *parser.Return) {
+	if f.fn == nil {
+		return
+	}
+
+	f.fn.returned = append(f.fn.returned, v)
+
+	if f.fn.returns != nil && !assignable(v, f.fn.returns) {
+		f.report("Cannot return "+v.String()+" from a function that returns "+f.fn.returns.String(), s.Token())
+	}
+}
+
+// assign gives the names in target the type v. items are the types of the
+// values when they were written as a list, which are matched to the parts
+// of a list target.
+func (f *file) assign(target parser.Assign, v *Type, items []*Type) {
+	switch a := target.(type) {
+	case *parser.AssignName:
+		f.assignName(a, v)
+
+	case *parser.AssignList:
+		spread := false
+		for _, part := range a.Parts {
+			if _, ok := part.(*parser.AssignSpread); ok {
+				spread = true
+			}
+		}
+
+		for i, part := range a.Parts {
+			switch {
+			case items != nil && !spread && len(items) == len(a.Parts):
+				f.assign(part, items[i], nil)
+			case isSpread(part):
+				f.assign(part, v, nil)
+			case v.is("list") && v.Elem != nil:
+				f.assign(part, v.Elem, nil)
+			default:
+				f.assign(part, anyType, nil)
+			}
+		}
+
+	case *parser.AssignSpread:
+		elem := anyType
+		if v.is("list") && v.Elem != nil {
+			elem = v.Elem
+		}
+		f.assign(a.Target, listOf(elem), nil)
+
+	case *parser.AssignIndex:
+		f.index(f.getAssign(a.Target), a.Index, a)
+
+	case *parser.AssignAttribute:
+		t := f.expr(a.Target)
+
+		// The attributes of an object written out are known, until they
+		// are set to something else
+		n, ok := a.Target.(*parser.Name)
+		if !ok || a.IsDeep || !t.is("object") || t.Module != "" || t.Attrs == nil {
+			return
+		}
+
+		if b, ok := f.scope.lookup(n.Name); ok {
+			attrs := make(map[string]*Type, len(t.Attrs)+1)
+			for k, v := range t.Attrs {
+				attrs[k] = v
+			}
+			attrs[a.Attribute] = union(attrs[a.Attribute], v)
+			b.typ = &Type{Name: "object", Attrs: attrs}
+		}
+	}
+}
+
+func isSpread(a parser.Assign) bool {
+	_, ok := a.(*parser.AssignSpread)
+	return ok
+}
+
+func (f *file) assignName(a *parser.AssignName, v *Type) {
+	declared := f.annotation(a.Type)
+
+	if declared == nil {
+		if b, ok := f.scope.vars[a.Name]; ok {
+			declared = b.declared
+		}
+	}
+
+	if declared == nil {
+		f.scope.vars[a.Name] = &binding{typ: v}
+		return
+	}
+
+	if !assignable(v, declared) {
+		f.report("Cannot assign "+v.String()+" to '"+a.Name+"', which is "+declared.String(), a.Token())
+	}
+
+	f.scope.vars[a.Name] = &binding{typ: declared, declared: declared}
+}
+
+// getAssign returns the current type of the target of an assignment, for
+// operators that update it.
+func (f *file) getAssign(target parser.Assign) *Type {
+	switch a := target.(type) {
+	case *parser.AssignName:
+		return f.name(a.Name)
+	case *parser.AssignIndex:
+		return f.index(f.getAssign(a.Target), a.Index, a)
+	case *parser.AssignAttribute:
+		return f.attribute(f.expr(a.Target), a.Attribute, a.ToString(), a)
+	}
+
+	return anyType
+}
+
+// importStatement binds the names of an import to the types of the module
+// and its attributes. Modules implemented in Go are not checked.
+func (f *file) importStatement(i *parser.Import) {
+	path, isGo, err := exec.ResolveModule(i.Name, f.path)
+	if err != nil {
+		f.report(err.Error(), i.Token())
+		return
+	}
+
+	alias := filepath.Base(filepath.FromSlash(i.Name))
+	module := objectType
+	if !isGo {
+		if _, err := os.Stat(path); err != nil {
+			f.report("Failed to locate module: "+i.Name, i.Token())
+			return
+		}
+		module = f.check(path, alias)
+	}
+
+	if len(i.Names) > 0 {
+		for _, n := range i.Names {
+			v := anyType
+			if module.Module != "" {
+				var ok bool
+				if v, ok = module.Attrs[n.Name]; !ok {
+					f.report("Module '"+i.Name+"' does not export '"+n.Name+"'", i.Token())
+					v = anyType
+				}
+			}
+
+			f.scope.vars[n.Alias] = &binding{typ: v}
+		}
+
+		return
+	}
+
+	if i.Alias != "" {
+		alias = i.Alias
+	}
+	f.scope.vars[alias] = &binding{typ: module}
+}
+
+// arguments describes a number of arguments.
+func arguments(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+
+	return strconv.Itoa(n) + " arguments"
+}
@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/internal/testutil"
+)
+
+// check checks main.hoot in files, and returns the problems found with the
+// path relative to the program's directory.
+func check(t *testing.T, files map[string]string) []string {
+	dir := testutil.TempFiles(t, files)
+
+	problems, err := CheckTypes(filepath.Join(dir, "main.hoot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := []string{}
+	for _, p := range problems {
+		rel, _ := filepath.Rel(dir, p.Path)
+		found = append(found, fmt.Sprintf("%s:%d:%d: %s", filepath.ToSlash(rel), p.Token.Line, p.Token.Column, p.Message))
+	}
+
+	return found
+}
+
+func TestMismatches(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"let x: int = 'a'", "main.hoot:1:5: Cannot assign string to 'x', which is int"},
+		{"let x: int = 1\nx = [1]", "main.hoot:2:1: Cannot assign list[int] to 'x', which is int"},
+		{"let x: list[string] = [1, 2]", "main.hoot:1:5: Cannot assign list[int] to 'x', which is list[string]"},
+		{"x = 'a' - 1", "main.hoot:1:9: Unable to evaluate binary operator '(\"a\" - 1)' on string and int"},
+		{"x = 1.5 + 2d", "main.hoot:1:9: Unable to evaluate binary operator '(1.5 + 2d)' on float and decimal"},
+		{"x = -[1]", "main.hoot:1:5: Unable to evaluate unary operator '(-[1])' on list[int]"},
+		{"x = 'a'\nx++", "main.hoot:2:2: Unable to evaluate increment/decrement 'x++' on string"},
+		{"f = (a: int, b: string) => a\nf(1, 2)", "main.hoot:2:6: Cannot pass int as 'b' to f, which takes string"},
+		{"f = (a: int, b: int) => a\nf(1)", "main.hoot:2:2: f takes 2 arguments, got 1"},
+		{"f = (a, ...rest) => a\nf()", "main.hoot:2:2: f takes at least 1 argument, got 0"},
+		{"f = () => 1\nf(2)", "main.hoot:2:2: f takes no arguments"},
+		{"f = (a, ...rest: list[int]) => a\nf(1, 2, 'c')", "main.hoot:2:9: Cannot pass string as 'rest' to f, which takes int"},
+		{"f = (s: string) -> int => s", "main.hoot:1:27: Cannot return string from a function that returns int"},
+		{"f = (n: int) => n * 2\nx = f(1) + 'a' - 1", "main.hoot:2:16: Unable to evaluate binary operator '((f(1) + \"a\") - 1)' on string and int"},
+		{"x = 'abc'.Shout()", "main.hoot:1:10: Unable to evaluate attribute '\"abc\".Shout', string has no attribute 'Shout'"},
+		{"x = 'abc'.Len() + []", "main.hoot:1:17: Unable to evaluate binary operator '(\"abc\".Len() + [])' on int and list"},
+		{"x = 5\nx()", "main.hoot:2:2: Unable to call int 'x'"},
+		{"x = 5[0]", "main.hoot:1:6: Unable to index int '5[0]'"},
+		{"x = [1]['a']", "main.hoot:1:8: Unable to index list[int] with string '[1][\"a\"]', indexes must be ints"},
+		{"for c in 5 {}", "main.hoot:1:10: Unable to iterate over int '5'"},
+		{"let x: strng = 1", "main.hoot:1:8: Unknown type 'strng'"},
+		{"let x: int[string] = 1", "main.hoot:1:8: Only list types can have an item type, int cannot"},
+	}
+
+	for _, tt := range tests {
+		found := check(t, map[string]string{"main.hoot": tt.src})
+
+		if len(found) != 1 || found[0] != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.src, found)
+		}
+	}
+}
+
+func TestAccepted(t *testing.T) {
+	tests := []string{
+		// Annotations that match, with ints used as floats
+		"let x: float = 1\nlet y: number = 2d\nlet z: int? = null\nz = 3",
+		"add = (a: int, b: int) -> int => a + b\nadd(1, 2)\nadd([1, 2])",
+		"f = (a: string, ...rest: list[int]) -> string => a + rest::str()\nf('a')\nf('a', 1, 2)",
+		// Values of unknown type, or that may be the right type
+		"f = (a) => a - 1\nf('a')",
+		"x = null\nif true { x = 1 }\ny = x + 1",
+		"acc = null\nfor i in [1, 2] {\n  if acc == null { acc = i } else { acc = acc + i }\n}",
+		// Operators that work across types
+		"x = 'a' + 1\ny = 1 + [1]::str() + 'b'\nz = 'a' < 1\nw = 'ab' * 2",
+		"import 'time'\nx = time.Now() - time.second\ny = time.second * 2 / 2",
+		// Objects define operators and can have any attribute
+		"v = {x: 1}\nv::add = (a, b) => a.x + b\nw = v + 1\nv.y = 'a'\nz = v.y + v.z",
+		"o = {a: 1}\no.a = 'b'\nx = o.a - 1",
+		// Patterns and overloads are not checked
+		"f = (0) => 1 | (n: int) => n * 2\nf(3)",
+		"f = (a, [b, c]) => a\nf(1)",
+		"g = () => {\n  yield 1\n}\nfor x in g() {}",
+	}
+
+	for _, src := range tests {
+		found := check(t, map[string]string{"main.hoot": src})
+
+		if len(found) != 0 {
+			t.Errorf("Expected no problems for %q, got %q", src, found)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	found := check(t, map[string]string{
+		"main.hoot": `import "./util"
+from "./util" import greet, missing
+import "./lib/nums" as n
+
+util.add(1, "2")
+greet(3)
+x = util.hidden
+y = n.double(2) - "a"
+z = n.double(2) + 1`,
+		"util.hoot": `export add = (a: int, b: int) -> int => a + b
+export greet = (name: string) => "hi " + name
+hidden = 1`,
+		"lib/nums.hoot": `double = (x: int) -> int => x * 2
+bad = 1 - "a"`,
+	})
+
+	expected := []string{
+		`lib/nums.hoot:2:9: Unable to evaluate binary operator '(1 - "a")' on int and string`,
+		`main.hoot:2:1: Module './util' does not export 'missing'`,
+		`main.hoot:5:13: Cannot pass string as 'b' to util.add, which takes int`,
+		`main.hoot:6:7: Cannot pass int as 'name' to greet, which takes string`,
+		`main.hoot:7:9: Module 'util' does not export 'hidden'`,
+		`main.hoot:8:17: Unable to evaluate binary operator '(n.double(2) - "a")' on int and string`,
+	}
+
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	found := check(t, map[string]string{
+		"main.hoot":  "import './other'\nimport './missing'",
+		"other.hoot": "x = (1 +",
+	})
+
+	if len(found) != 2 || found[0] != "main.hoot:2:1: Failed to locate module: ./missing" || !strings.HasPrefix(found[1], "other.hoot:1:9: Expected") {
+		t.Errorf("Expected a parser error and a missing module, got %q", found)
+	}
+
+	if _, err := CheckTypes(filepath.Join(t.TempDir(), "main.hoot")); err == nil {
+		t.Error("Expected an error for a missing program")
+	}
+}
@@ -0,0 +1,583 @@
+package checker
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+// builtins are the types of the variables every program starts with.
+var builtins = map[string]*Type{
+	"len":    returning(intType),
+	"hash":   returning(intType),
+	"typeof": returning(stringType),
+	"int":    returning(intType),
+	"float":  returning(floatType),
+	"str":    returning(stringType),
+	"bool":   returning(boolType),
+	"list":   returning(listType),
+	"iter":   returning(anyType),
+	"range":  returning(anyType),
+	"Object": objectType,
+	"Done":   anyType,
+}
+
+func (f *file) name(name string) *Type {
+	if b, ok := f.scope.lookup(name); ok {
+		return b.typ
+	}
+
+	if t, ok := builtins[name]; ok {
+		return t
+	}
+
+	return anyType
+}
+
+// value returns the type of expr, and the types of its items when it is a
+// list written out without spreads, such as the arguments of a call.
+func (f *file) value(expr parser.Expression) (*Type, []*Type) {
+	list, ok := expr.(*parser.List)
+	if !ok {
+		return f.expr(expr), nil
+	}
+
+	items := []*Type{}
+	elems := []*Type{}
+	spread := false
+
+	for _, part := range list.Parts {
+		if s, ok := part.(*parser.Spread); ok {
+			spread = true
+			elems = append(elems, f.item(f.expr(s.Target), s.Target))
+			continue
+		}
+
+		t := f.expr(part)
+		items = append(items, t)
+		elems = append(elems, t)
+	}
+
+	var elem *Type
+	if len(elems) > 0 {
+		elem = union(elems...)
+	}
+
+	if spread {
+		return listOf(elem), nil
+	}
+
+	return listOf(elem), items
+}
+
+func (f *file) expr(expr parser.Expression) *Type {
+	switch e := expr.(type) {
+	case nil:
+		return nullType
+
+	case *parser.Const:
+		return constType(e.Value)
+
+	case *parser.Null:
+		return nullType
+
+	case *parser.Name:
+		return f.name(e.Name)
+
+	case *parser.List:
+		t, _ := f.value(e)
+		return t
+
+	case *parser.Map:
+		t := &Type{Name: "object", Attrs: map[string]*Type{}}
+		for i, k := range e.Keys {
+			t.Attrs[k] = f.expr(e.Values[i])
+		}
+		return t
+
+	case *parser.BinOp:
+		return f.binOp(e)
+
+	case *parser.UnaryOp:
+		return f.unary(e.Op, f.expr(e.Value), e)
+
+	case *parser.IfExpression:
+		f.expr(e.Test)
+		return union(f.expr(e.IfTrue), f.expr(e.IfFalse))
+
+	case *parser.AssignExpression:
+		var v *Type
+		var items []*Type
+
+		if e.Op == "=" {
+			v, items = f.value(e.Value)
+		} else {
+			current := f.getAssign(e.Target)
+			v = f.binary(strings.TrimSuffix(e.Op, "="), current, f.expr(e.Value), e)
+		}
+
+		f.assign(e.Target, v, items)
+		return v
+
+	case *parser.IncDec:
+		v := f.getAssign(e.Target)
+		if !v.isAny() && !v.canBe("int") && !v.canBe("float") && !v.canBe("decimal") && !v.canBe("object") {
+			f.report("Unable to evaluate increment/decrement '"+e.ToString()+"' on "+v.String(), e.Token())
+		}
+		return v
+
+	case *parser.Yield:
+		f.expr(e.Value)
+		return anyType
+
+	case *parser.FunctionDef:
+		return f.function(e)
+
+	case *parser.Overload:
+		for i := range e.Cases {
+			f.function(&e.Cases[i])
+		}
+		return functionType
+
+	case *parser.FunctionCall:
+		return f.call(e)
+
+	case *parser.Attribute:
+		target := f.expr(e.Target)
+		if e.IsDeep {
+			return anyType
+		}
+		if e.IsCoalesce {
+			target = target.without("null")
+		}
+		return f.attribute(target, e.Attribute, e.ToString(), e)
+
+	case *parser.Index:
+		return f.index(f.expr(e.Target), e.Index, e)
+
+	case *parser.Slice:
+		t := f.expr(e.Target)
+		f.expr(e.Start)
+		f.expr(e.End)
+		return f.slice(t, e)
+
+	case *parser.Spread:
+		f.expr(e.Target)
+	}
+
+	return anyType
+}
+
+func constType(v interface{}) *Type {
+	switch v.(type) {
+	case int64, *big.Int:
+		return intType
+	case float64:
+		return floatType
+	case decimal.Decimal:
+		return decimalType
+	case string:
+		return stringType
+	case bool:
+		return boolType
+	}
+
+	return anyType
+}
+
+func (f *file) binOp(b *parser.BinOp) *Type {
+	l := f.expr(b.Left)
+
+	switch b.Op {
+	case "and", "or":
+		return union(l, f.expr(b.Right))
+	case "??":
+		return union(l.without("null"), f.expr(b.Right))
+	}
+
+	return f.binary(b.Op, l, f.expr(b.Right), b)
+}
+
+// binary returns the type of l op r, reporting it if the operator is not
+// defined for any of the types they may be. Objects can define operators,
+// so any operation on them may succeed.
+func (f *file) binary(op string, l *Type, r *Type, node parser.Node) *Type {
+	if l.isAny() || r.isAny() || l.canBe("object") || r.canBe("object") {
+		switch op {
+		case "==", "!=", "<", "<=", ">", ">=", "has":
+			return boolType
+		}
+		return anyType
+	}
+
+	results := []*Type{}
+	for _, lo := range l.options() {
+		for _, ro := range r.options() {
+			name, ok := binaryResult(op, lo.Name, ro.Name)
+			if !ok {
+				continue
+			}
+
+			if name == "list" && lo.Elem != nil && ro.Elem != nil {
+				results = append(results, listOf(union(lo.Elem, ro.Elem)))
+			} else {
+				results = append(results, named(name))
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		f.report("Unable to evaluate binary operator '"+node.ToString()+"' on "+l.String()+" and "+r.String(), node.Token())
+		return anyType
+	}
+
+	return union(results...)
+}
+
+func (f *file) unary(op string, v *Type, node parser.Node) *Type {
+	if v.isAny() || v.canBe("object") {
+		if op == "-" {
+			return anyType
+		}
+		return boolType
+	}
+
+	results := []*Type{}
+	for _, o := range v.options() {
+		if name, ok := unaryResult(op, o.Name); ok {
+			results = append(results, named(name))
+		}
+	}
+
+	if len(results) == 0 {
+		f.report("Unable to evaluate unary operator '"+node.ToString()+"' on "+v.String(), node.Token())
+		return anyType
+	}
+
+	return union(results...)
+}
+
+// attribute returns the type of the attribute name of a value of type
+// target, reporting it if no value of the type has it. desc is the
+// attribute's source, for messages.
+func (f *file) attribute(target *Type, name string, desc string, node parser.Node) *Type {
+	if target.isAny() {
+		return anyType
+	}
+
+	results := []*Type{}
+	for _, o := range target.options() {
+		switch {
+		case o.Module != "":
+			if t, ok := o.Attrs[name]; ok {
+				results = append(results, t)
+			}
+		case o.Name == "object":
+			if t, ok := o.Attrs[name]; ok {
+				results = append(results, t)
+			} else {
+				results = append(results, anyType)
+			}
+		case o.Name == "function":
+			results = append(results, anyType)
+		case exec.HasAttr(o.Name, name):
+			results = append(results, returning(methodResult(o, name)))
+		}
+	}
+
+	if len(results) > 0 {
+		return union(results...)
+	}
+
+	if target.Module != "" {
+		f.report("Module '"+target.Module+"' does not export '"+name+"'", node.Token())
+	} else {
+		f.report("Unable to evaluate attribute '"+desc+"', "+target.String()+" has no attribute '"+name+"'", node.Token())
+	}
+
+	return anyType
+}
+
+// index returns the type of indexing a value of type target with index,
+// reporting it if the value cannot be indexed, or not by the index's type.
+func (f *file) index(target *Type, index parser.Expression, node parser.Node) *Type {
+	i := f.expr(index)
+	if target.isAny() {
+		return anyType
+	}
+
+	results := []*Type{}
+	indexable := false
+
+	for _, o := range target.options() {
+		switch o.Name {
+		case "object", "function":
+			indexable = true
+			results = append(results, anyType)
+		case "list", "string":
+			indexable = true
+			if !i.canBe("int") {
+				continue
+			}
+
+			if o.Name == "string" {
+				results = append(results, stringType)
+			} else if o.Elem != nil {
+				results = append(results, o.Elem)
+			} else {
+				results = append(results, anyType)
+			}
+		}
+	}
+
+	switch {
+	case !indexable:
+		f.report("Unable to index "+target.String()+" '"+node.ToString()+"'", node.Token())
+	case len(results) == 0:
+		f.report("Unable to index "+target.String()+" with "+i.String()+" '"+node.ToString()+"', indexes must be ints", node.Token())
+	default:
+		return union(results...)
+	}
+
+	return anyType
+}
+
+func (f *file) slice(target *Type, node parser.Node) *Type {
+	if target.isAny() {
+		return anyType
+	}
+
+	results := []*Type{}
+	for _, o := range target.options() {
+		switch o.Name {
+		case "object", "function":
+			results = append(results, anyType)
+		case "list", "string":
+			results = append(results, o)
+		}
+	}
+
+	if len(results) == 0 {
+		f.report("Unable to slice "+target.String()+" '"+node.ToString()+"'", node.Token())
+		return anyType
+	}
+
+	return union(results...)
+}
+
+// call returns the type of a function call's result, reporting calls to
+// values that are not functions, and arguments that do not match a
+// function's parameters.
+func (f *file) call(c *parser.FunctionCall) *Type {
+	target := f.expr(c.Target)
+	arg, items := f.value(c.Arg)
+
+	if c.IsCoalesce {
+		target = target.without("null")
+	}
+
+	if target.isAny() {
+		return anyType
+	}
+
+	results := []*Type{}
+	callable := false
+
+	for _, o := range target.options() {
+		switch o.Name {
+		case "function":
+			callable = true
+			if o.Func != nil && o.Func.Returns != nil {
+				results = append(results, o.Func.Returns)
+			} else {
+				results = append(results, anyType)
+			}
+		case "object":
+			callable = true
+			results = append(results, anyType)
+		}
+	}
+
+	if !callable {
+		f.report("Unable to call "+target.String()+" '"+c.Target.ToString()+"'", c.Token())
+		return anyType
+	}
+
+	if target.Func != nil {
+		f.checkCall(c, target.Func, arg, items)
+	}
+
+	return union(results...)
+}
+
+// checkCall checks the arguments of a call against the parameters of the
+// function called. Like an assignment, a list argument is spread over the
+// parameters when there are several of them.
+func (f *file) checkCall(c *parser.FunctionCall, sig *Signature, arg *Type, items []*Type) {
+	if !sig.Checked {
+		return
+	}
+
+	name := c.Target.ToString()
+	n := len(sig.Params)
+
+	if n == 0 {
+		if c.Arg != nil && !arg.canBe("null") {
+			f.report(name+" takes no arguments", c.Token())
+		}
+		return
+	}
+
+	if sig.Whole {
+		f.checkArg(name, sig.Params[0], arg, c.Arg)
+		return
+	}
+
+	var parts []parser.Expression
+	switch {
+	case c.Arg == nil:
+		items = []*Type{}
+	case items != nil:
+		parts = c.Arg.(*parser.List).Parts
+	case arg.canBe("list") || arg.canBe("string") || arg.canBe("object"):
+		// Spread over the parameters when the function is called
+		return
+	default:
+		items = []*Type{arg}
+		parts = []parser.Expression{c.Arg}
+	}
+
+	spread := -1
+	for i, p := range sig.Params {
+		if p.Spread {
+			spread = i
+		}
+	}
+
+	m := len(items)
+	if spread < 0 && m != n {
+		f.report(name+" takes "+arguments(n)+", got "+strconv.Itoa(m), c.Token())
+		return
+	}
+	if spread >= 0 && m < n-1 {
+		f.report(name+" takes at least "+arguments(n-1)+", got "+strconv.Itoa(m), c.Token())
+		return
+	}
+
+	for i, p := range sig.Params {
+		if !p.Spread {
+			j := i
+			if spread >= 0 && i > spread {
+				j = m - (n - i)
+			}
+			f.checkArg(name, p, items[j], parts[j])
+			continue
+		}
+
+		if p.Type == nil || !p.Type.is("list") || p.Type.Elem == nil {
+			continue
+		}
+
+		item := Param{Name: p.Name, Type: p.Type.Elem}
+		for j := i; j < i+m-(n-1); j++ {
+			f.checkArg(name, item, items[j], parts[j])
+		}
+	}
+}
+
+func (f *file) checkArg(name string, p Param, v *Type, expr parser.Expression) {
+	if p.Type == nil || assignable(v, p.Type) {
+		return
+	}
+
+	f.report("Cannot pass "+v.String()+" as '"+p.Name+"' to "+name+", which takes "+p.Type.String(), expr.Token())
+}
+
+// function checks the body of a function definition, and returns the type
+// of the function. The result of a function without an annotation is
+// inferred from its return statements.
+func (f *file) function(def *parser.FunctionDef) *Type {
+	sig := f.signature(def)
+
+	outerScope, outerFn := f.scope, f.fn
+	f.scope = newScope(outerScope)
+	f.fn = &fn{returns: sig.Returns}
+
+	f.assign(def.Arg, anyType, nil)
+	if def.Condition != nil {
+		f.expr(def.Condition)
+	}
+	f.block(def.Body)
+
+	if sig.Returns == nil {
+		returned := f.fn.returned
+		if !endsWithReturn(def.Body) {
+			returned = append(returned, nullType)
+		}
+		sig.Returns = union(returned...)
+	}
+
+	f.scope, f.fn = outerScope, outerFn
+
+	return function(sig)
+}
+
+// signature returns what is known about the parameters and result of def.
+// Calls to functions that match constants, or destructure their
+// parameters, are not checked.
+func (f *file) signature(def *parser.FunctionDef) *Signature {
+	sig := &Signature{Returns: f.annotation(def.Returns)}
+	if def.Generator && sig.Returns == nil {
+		sig.Returns = anyType
+	}
+
+	if def.Condition != nil {
+		return sig
+	}
+
+	switch a := def.Arg.(type) {
+	case *parser.AssignNull:
+		sig.Checked = true
+
+	case *parser.AssignName:
+		sig.Checked = true
+		sig.Whole = true
+		sig.Params = []Param{{Name: a.Name, Type: f.annotation(a.Type)}}
+
+	case *parser.AssignList:
+		for _, part := range a.Parts {
+			switch p := part.(type) {
+			case *parser.AssignName:
+				sig.Params = append(sig.Params, Param{Name: p.Name, Type: f.annotation(p.Type)})
+			case *parser.AssignSpread:
+				name, ok := p.Target.(*parser.AssignName)
+				if !ok {
+					return &Signature{Returns: sig.Returns}
+				}
+				sig.Params = append(sig.Params, Param{Name: name.Name, Type: f.annotation(name.Type), Spread: true})
+			default:
+				return &Signature{Returns: sig.Returns}
+			}
+		}
+		sig.Checked = true
+	}
+
+	return sig
+}
+
+func endsWithReturn(body []parser.Statement) bool {
+	if len(body) == 0 {
+		return false
+	}
+
+	switch body[len(body)-1].(type) {
+	case *parser.Return, *parser.Throw:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,200 @@
+package checker
+
+// The results of operators on the built-in types, following the operator
+// methods of the types in the exec package.
+
+func isNumber(name string) bool {
+	return name == "int" || name == "float" || name == "decimal"
+}
+
+// numberResult is the type of an arithmetic operation on two numbers.
+// Decimals and floats cannot be mixed.
+func numberResult(l string, r string) (string, bool) {
+	switch {
+	case l == "decimal" || r == "decimal":
+		return "decimal", l != "float" && r != "float"
+	case l == "float" || r == "float":
+		return "float", true
+	}
+
+	return "int", true
+}
+
+// binaryResult is the type of l op r for values of the types named l and r,
+// and whether the operation can succeed. Comparisons with == and != always
+// can, and the logical operators are handled by the caller.
+func binaryResult(op string, l string, r string) (string, bool) {
+	numbers := isNumber(l) && isNumber(r)
+
+	switch op {
+	case "==", "!=":
+		return "bool", true
+
+	case "+":
+		switch {
+		case l == "string" || r == "string":
+			return "string", true
+		case numbers:
+			return numberResult(l, r)
+		case l == "list" && r == "list":
+			return "list", true
+		case l == "time" && r == "duration", l == "duration" && r == "time":
+			return "time", true
+		case l == "duration" && r == "duration":
+			return "duration", true
+		}
+
+	case "-":
+		switch {
+		case numbers:
+			return numberResult(l, r)
+		case l == "time" && r == "time":
+			return "duration", true
+		case l == "time" && r == "duration":
+			return "time", true
+		case l == "duration" && r == "duration":
+			return "duration", true
+		}
+
+	case "*":
+		switch {
+		case numbers:
+			return numberResult(l, r)
+		case l == "string" && r == "int", l == "int" && r == "string":
+			return "string", true
+		case l == "duration" && isNumber(r), isNumber(l) && r == "duration":
+			return "duration", true
+		}
+
+	case "/":
+		switch {
+		case l == "int" && r == "int":
+			return "float", true
+		case numbers:
+			return numberResult(l, r)
+		case l == "duration" && isNumber(r):
+			return "duration", true
+		case l == "duration" && r == "duration":
+			return "float", true
+		}
+
+	case "//":
+		if numbers {
+			return numberResult(l, r)
+		}
+
+	case "%":
+		switch {
+		case numbers:
+			return numberResult(l, r)
+		case l == "duration" && r == "duration":
+			return "duration", true
+		}
+
+	case "**":
+		if numbers && r != "decimal" {
+			return numberResult(l, r)
+		}
+
+	case "&", "|", "^":
+		switch {
+		case l == "int" && r == "int":
+			return "int", true
+		case l == "bool" && r == "bool":
+			return "bool", true
+		}
+
+	case "<<", ">>":
+		if l == "int" && r == "int" {
+			return "int", true
+		}
+
+	case "<", "<=", ">", ">=":
+		// Strings compare with anything by its string form
+		if numbers || l == "string" || r == "string" || (l == r && (l == "time" || l == "duration")) {
+			return "bool", true
+		}
+
+	case "has":
+		switch l {
+		case "string", "list", "object", "function":
+			return "bool", true
+		}
+	}
+
+	return "", false
+}
+
+// unaryResult is the type of op applied to a value of the type named v, and
+// whether it can succeed.
+func unaryResult(op string, v string) (string, bool) {
+	if op == "-" {
+		return v, isNumber(v) || v == "duration"
+	}
+
+	return "bool", true
+}
+
+// methodResult is the type returned by the method name of a built-in type.
+func methodResult(recv *Type, name string) *Type {
+	switch recv.Name {
+	case "string":
+		switch name {
+		case "Len", "Index", "ReIndex", "ToInt":
+			return intType
+		case "ToFloat":
+			return floatType
+		case "StartsWith", "EndsWith", "Contains", "ReMatch":
+			return boolType
+		case "Split", "Lines", "Fields":
+			return listOf(stringType)
+		case "Bytes":
+			return listOf(intType)
+		}
+		return stringType
+
+	case "list":
+		switch name {
+		case "Len", "IndexOf", "Count":
+			return intType
+		case "Join":
+			return stringType
+		case "Any", "All":
+			return boolType
+		case "Add", "Reverse", "Sort", "SortBy", "Filter", "Unique", "Copy":
+			return recv
+		case "Pop", "Min", "Max":
+			if recv.Elem != nil {
+				return recv.Elem
+			}
+		case "Find":
+			if recv.Elem != nil {
+				return union(recv.Elem, nullType)
+			}
+		case "Map", "FlatMap", "Chunk", "Zip", "Insert", "Extend", "Remove":
+			return listType
+		}
+
+	case "time":
+		switch name {
+		case "Weekday", "Zone", "Format":
+			return stringType
+		case "Offset":
+			return durationType
+		case "UTC", "In", "AddDate", "Round", "Truncate":
+			return timeType
+		}
+		return intType
+
+	case "duration":
+		switch name {
+		case "Hours", "Minutes", "Seconds":
+			return floatType
+		case "Round", "Truncate":
+			return durationType
+		}
+		return intType
+	}
+
+	return anyType
+}
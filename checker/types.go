@@ -0,0 +1,285 @@
+package checker
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+// Type is a type known to the checker. It is a type named as by typeof, such
+// as int or list, the unknown type any, or a union of Options when Name is
+// empty.
+type Type struct {
+	Name    string
+	Elem    *Type // the type of a list's items, nil when unknown
+	Options []*Type
+	Func    *Signature       // what is known about a function, nil when nothing is
+	Attrs   map[string]*Type // known attributes of an object
+	Module  string           // the import name of a module, whose attributes are all known
+}
+
+// Signature is what is known about the arguments and result of a function.
+// Calls are only checked against Params when Checked is set.
+type Signature struct {
+	Params  []Param
+	Checked bool
+	Whole   bool // the function has one parameter, which is given the whole argument
+	Returns *Type
+}
+
+// Param is a parameter of a function. A spread parameter collects the
+// arguments that are left over into a list.
+type Param struct {
+	Name   string
+	Type   *Type
+	Spread bool
+}
+
+var (
+	anyType      = named("any")
+	nullType     = named("null")
+	intType      = named("int")
+	floatType    = named("float")
+	decimalType  = named("decimal")
+	stringType   = named("string")
+	boolType     = named("bool")
+	listType     = named("list")
+	objectType   = named("object")
+	functionType = named("function")
+	timeType     = named("time")
+	durationType = named("duration")
+)
+
+// typeNames are the types that can be used in annotations, number is short
+// for int | float | decimal.
+var typeNames = map[string]*Type{
+	"any":      anyType,
+	"null":     nullType,
+	"int":      intType,
+	"float":    floatType,
+	"decimal":  decimalType,
+	"number":   union(intType, floatType, decimalType),
+	"string":   stringType,
+	"bool":     boolType,
+	"list":     listType,
+	"object":   objectType,
+	"function": functionType,
+	"time":     timeType,
+	"duration": durationType,
+}
+
+func named(name string) *Type {
+	return &Type{Name: name}
+}
+
+func listOf(elem *Type) *Type {
+	if elem == nil || elem.Name == "any" {
+		return listType
+	}
+
+	return &Type{Name: "list", Elem: elem}
+}
+
+func function(sig *Signature) *Type {
+	return &Type{Name: "function", Func: sig}
+}
+
+// returning is a function that takes any arguments and returns result.
+func returning(result *Type) *Type {
+	return function(&Signature{Returns: result})
+}
+
+// union is a type that may be any of types. Unions are flattened, and any
+// absorbs every other type.
+func union(types ...*Type) *Type {
+	seen := map[string]bool{}
+	options := []*Type{}
+
+	var add func(t *Type)
+	add = func(t *Type) {
+		if t == nil {
+			return
+		}
+
+		if t.Name == "" {
+			for _, o := range t.Options {
+				add(o)
+			}
+			return
+		}
+
+		key := t.String()
+		if !seen[key] {
+			seen[key] = true
+			options = append(options, t)
+		}
+	}
+
+	for _, t := range types {
+		add(t)
+	}
+
+	if len(options) == 0 {
+		return anyType
+	}
+
+	for _, o := range options {
+		if o.Name == "any" {
+			return anyType
+		}
+	}
+
+	if len(options) == 1 {
+		return options[0]
+	}
+
+	return &Type{Options: options}
+}
+
+// options returns the types t may be.
+func (t *Type) options() []*Type {
+	if t.Name == "" {
+		return t.Options
+	}
+
+	return []*Type{t}
+}
+
+func (t *Type) isAny() bool {
+	return t.Name == "any"
+}
+
+// is reports whether t is exactly the type named name.
+func (t *Type) is(name string) bool {
+	return t.Name == name
+}
+
+// canBe reports whether a value of type t may be of the type named name.
+func (t *Type) canBe(name string) bool {
+	for _, o := range t.options() {
+		if o.Name == name || o.Name == "any" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// without returns t without the options named name.
+func (t *Type) without(name string) *Type {
+	if t.isAny() {
+		return t
+	}
+
+	kept := []*Type{}
+	for _, o := range t.options() {
+		if o.Name != name {
+			kept = append(kept, o)
+		}
+	}
+
+	if len(kept) == 0 {
+		return anyType
+	}
+
+	return union(kept...)
+}
+
+func (t *Type) String() string {
+	if t.Name == "" {
+		names := make([]string, len(t.Options))
+		for i, o := range t.Options {
+			names[i] = o.String()
+		}
+		sort.Strings(names)
+
+		return strings.Join(names, " | ")
+	}
+
+	if t.Elem != nil {
+		return t.Name + "[" + t.Elem.String() + "]"
+	}
+
+	if t.Module != "" {
+		return "module " + t.Module
+	}
+
+	return t.Name
+}
+
+// assignable reports whether a value of type v may be used where want is
+// needed. The checker is lenient, it only reports values that can never be
+// of the type that is needed, so a union is assignable when any of its
+// options is. Ints can be used as floats and decimals.
+func assignable(v *Type, want *Type) bool {
+	if v.isAny() || want.isAny() {
+		return true
+	}
+
+	for _, vo := range v.options() {
+		for _, wo := range want.options() {
+			if assignableTo(vo, wo) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func assignableTo(v *Type, want *Type) bool {
+	if v.isAny() || want.isAny() {
+		return true
+	}
+
+	if v.Name == "int" && (want.Name == "float" || want.Name == "decimal") {
+		return true
+	}
+
+	if v.Name != want.Name {
+		return false
+	}
+
+	if v.Elem != nil && want.Elem != nil {
+		return assignable(v.Elem, want.Elem)
+	}
+
+	return true
+}
+
+// annotation converts the type annotation t, returning a message describing
+// the problem if it is invalid.
+func annotation(t *parser.Type) (*Type, string) {
+	if len(t.Options) > 0 {
+		options := make([]*Type, len(t.Options))
+		for i, o := range t.Options {
+			var msg string
+			if options[i], msg = annotation(o); msg != "" {
+				return anyType, msg
+			}
+		}
+
+		return union(options...), ""
+	}
+
+	typ, ok := typeNames[t.Name]
+	if !ok {
+		return anyType, "Unknown type '" + t.Name + "'"
+	}
+
+	if t.Elem != nil {
+		if t.Name != "list" {
+			return anyType, "Only list types can have an item type, " + t.Name + " cannot"
+		}
+
+		elem, msg := annotation(t.Elem)
+		if msg != "" {
+			return anyType, msg
+		}
+
+		return listOf(elem), ""
+	}
+
+	return typ, ""
+}
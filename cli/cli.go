@@ -0,0 +1,391 @@
+// Package cli implements the owl command. Custom owl binaries that link in
+// extra Go modules can call Main after importing the packages that register
+// them.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/checker"
+	"github.com/AnthonyEdvalson/owl/debugger"
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/linter"
+	"github.com/AnthonyEdvalson/owl/manifest"
+	"github.com/AnthonyEdvalson/owl/profiler"
+	"github.com/AnthonyEdvalson/owl/repl"
+	"github.com/AnthonyEdvalson/owl/testrunner"
+)
+
+// Main runs the owl command with the program's arguments.
+func Main() {
+	argc := len(os.Args)
+
+	if argc == 1 {
+		repl.Start(os.Stdin, os.Stdout)
+	}
+
+	if argc == 2 && os.Args[1] != "run" && os.Args[1] != "test" && os.Args[1] != "mod" && os.Args[1] != "check" && os.Args[1] != "lint" {
+		params, ok := load(os.Args[1])
+		if !ok {
+			return
+		}
+
+		_, _ = exec.ExecuteProgram(params)
+	}
+
+	if argc == 3 && os.Args[1] == "debug" {
+		params, ok := load(os.Args[2])
+		if !ok {
+			return
+		}
+
+		debugger.Start(params, os.Stdin, os.Stdout)
+	}
+
+	if argc >= 2 && os.Args[1] == "run" {
+		run(os.Args[2:])
+	}
+
+	if argc >= 2 && os.Args[1] == "test" {
+		test(os.Args[2:])
+	}
+
+	if argc >= 2 && os.Args[1] == "mod" {
+		mod(os.Args[2:])
+	}
+
+	if argc >= 2 && os.Args[1] == "check" {
+		check(os.Args[2:])
+	}
+
+	if argc >= 2 && os.Args[1] == "lint" {
+		lint(os.Args[2:])
+	}
+}
+
+// run executes a program, optionally under the profiler.
+func run(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	profile := flags.String("profile", "", "write a pprof profile to this file")
+	top := flags.Int("top", 20, "number of functions and lines to show in the profile report")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: owl run [--profile out.prof] [--top n] <dir>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	params, ok := load(flags.Arg(0))
+	if !ok {
+		return
+	}
+
+	if *profile == "" {
+		_, _ = exec.ExecuteProgram(params)
+		return
+	}
+
+	p := profiler.NewProfiler()
+	t := exec.NewTreeExecutor(params.Path)
+	t.Profiler = p
+
+	// Write the profile even if the program fails, that is often when it is
+	// most useful
+	defer func() {
+		p.Stop()
+		p.WriteReport(os.Stderr, *top)
+
+		f, err := os.Create(*profile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to write profile:", err)
+			return
+		}
+		defer f.Close()
+
+		if err := p.WritePprof(f); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to write profile:", err)
+		}
+	}()
+
+	p.Start(filepath.Base(params.Path))
+	t.ExecProgram(params.Program, params.Globals)
+}
+
+func load(dir string) (*exec.OwlParams, bool) {
+	path := filepath.Join(dir, "main.hoot")
+
+	ok, params, parseErr := exec.LoadProgramFromPath(path)
+	if !ok {
+		if parseErr == nil {
+			fmt.Println("Failed to locate program")
+			return nil, false
+		}
+		fmt.Print(exec.FormatParserErrors(path, parseErr))
+		return nil, false
+	}
+
+	return params, true
+}
+
+// test runs the test files in a directory, exiting with a non zero status if
+// any test fails.
+func test(args []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	filter := flags.String("run", "", "only run tests whose names match this regular expression")
+	junit := flags.String("junit", "", "write the results as JUnit XML to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: owl test [-run regexp] [-junit out.xml] [dir]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+
+	var re *regexp.Regexp
+	if *filter != "" {
+		var err error
+		if re, err = regexp.Compile(*filter); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -run pattern:", err)
+			os.Exit(2)
+		}
+	}
+
+	paths, err := testrunner.Discover(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to find tests:", err)
+		os.Exit(2)
+	}
+
+	results := testrunner.Run(paths, re)
+	ok := testrunner.WriteReport(os.Stdout, results)
+
+	if *junit != "" {
+		f, err := os.Create(*junit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to write JUnit report:", err)
+			os.Exit(2)
+		}
+
+		err = testrunner.WriteJUnit(f, results)
+		f.Close()
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to write JUnit report:", err)
+			os.Exit(2)
+		}
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// mod manages the dependencies of the project in a directory. vendor copies
+// them into the project and writes owl.lock, verify checks them against it.
+func mod(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: owl mod vendor|verify [dir]")
+		os.Exit(2)
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		usage()
+	}
+
+	dir := "."
+	if len(args) == 2 {
+		dir = args[1]
+	}
+
+	m, err := manifest.Load(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load manifest:", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "vendor":
+		l, err := m.Vendor()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to vendor dependencies:", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Vendored %d dependencies\n", len(l.Dependencies))
+
+	case "verify":
+		problems, err := m.Verify()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to verify dependencies:", err)
+			os.Exit(1)
+		}
+
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+
+		fmt.Println("All dependencies verified")
+
+	default:
+		usage()
+	}
+}
+
+// check statically checks the program in a directory and the modules it
+// imports, exiting with a non zero status if there are any problems. The
+// flags choose the checks to run, all of them run if none are chosen.
+func check(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	types := flags.Bool("types", false, "check the types of values against their annotations and uses")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: owl check [--types] [dir]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+
+	all := !*types
+	problems := []checker.Problem{}
+
+	if *types || all {
+		found, err := checker.CheckTypes(filepath.Join(dir, "main.hoot"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to check program:", err)
+			os.Exit(2)
+		}
+		problems = append(problems, found...)
+	}
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		if len(problems) == 1 {
+			fmt.Println("1 problem found")
+		} else {
+			fmt.Printf("%d problems found\n", len(problems))
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println("No problems found")
+}
+
+// lint runs the linter over Owl files, and the files in directories and
+// their subdirectories, exiting with a non zero status if there are any
+// problems.
+func lint(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	enable := flags.String("enable", "", "only run these comma separated rules")
+	disable := flags.String("disable", "", "do not run these comma separated rules")
+	asJSON := flags.Bool("json", false, "write the problems as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: owl lint [--enable rules] [--disable rules] [--json] [path ...]")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "Rules:")
+		for _, r := range linter.Rules {
+			fmt.Fprintf(flags.Output(), "  %-20s %s\n", r.Name, r.Description)
+		}
+	}
+	flags.Parse(args)
+
+	rules, err := linter.Select(splitList(*enable), splitList(*disable))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid rules:", err)
+		os.Exit(2)
+	}
+
+	targets := flags.Args()
+	if len(targets) == 0 {
+		targets = []string{"."}
+	}
+
+	paths := []string{}
+	for _, target := range targets {
+		info, err := os.Stat(target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to find files:", err)
+			os.Exit(2)
+		}
+
+		if !info.IsDir() {
+			paths = append(paths, target)
+			continue
+		}
+
+		found, err := linter.Discover(target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to find files:", err)
+			os.Exit(2)
+		}
+		paths = append(paths, found...)
+	}
+
+	problems := []linter.Problem{}
+	for _, path := range paths {
+		found, err := linter.LintFile(path, rules)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to lint file:", err)
+			os.Exit(2)
+		}
+		problems = append(problems, found...)
+	}
+
+	if *asJSON {
+		if err := linter.WriteJSON(os.Stdout, problems); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to write problems:", err)
+			os.Exit(2)
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// splitList splits a comma separated flag value, ignoring empty items.
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,504 @@
+// Package debugger implements an interactive, line oriented debugger for Owl
+// programs. It attaches to a TreeExecutor as its Debugger and pauses before
+// statements to accept commands.
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/lexer"
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+const PROMPT = "(owl) "
+
+const (
+	CONTINUE = iota
+	STEP_INTO
+	STEP_OVER
+	STEP_OUT
+)
+
+// breakpoint is a line in a file, identified by its cleaned absolute path so
+// that files with the same name in different directories are told apart.
+type breakpoint struct {
+	File string
+	Line int
+}
+
+type Session struct {
+	in  *bufio.Scanner
+	out io.Writer
+	dir string
+
+	breakpoints map[breakpoint]bool
+	mode        int
+	depth       int
+	sources     map[string][]string
+	paths       map[string]string
+}
+
+// quit is panicked to unwind the executor when the user quits the session.
+// It is an error so that Eval can tell it apart from errors in the program.
+type quit struct{}
+
+func (quit) Error() string {
+	return "quit"
+}
+
+func NewSession(in io.Reader, out io.Writer, path string) *Session {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		dir = filepath.Dir(path)
+	}
+
+	return &Session{
+		in:          bufio.NewScanner(in),
+		out:         out,
+		dir:         dir,
+		breakpoints: map[breakpoint]bool{},
+		mode:        STEP_INTO,
+		sources:     map[string][]string{},
+		paths:       map[string]string{},
+	}
+}
+
+// Start runs a program under the debugger, pausing before the first
+// statement.
+func Start(params *exec.OwlParams, in io.Reader, out io.Writer) {
+	s := NewSession(in, out, params.Path)
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(quit); ok {
+				return
+			}
+			fmt.Fprintln(out, "Error:", r)
+		}
+	}()
+
+	t := exec.NewTreeExecutor(params.Path)
+	t.Debugger = s
+
+	result := t.ExecProgram(params.Program, params.Globals)
+
+	if result != nil {
+		fmt.Fprintf(out, "Program returned %s\n", result.TrueStr())
+	} else {
+		fmt.Fprintln(out, "Program finished")
+	}
+}
+
+func (s *Session) OnStatement(t *exec.TreeExecutor, token lexer.Token) {
+	if !s.shouldStop(t, token) {
+		return
+	}
+
+	s.show(t, token)
+
+	for {
+		fmt.Fprint(s.out, PROMPT)
+
+		if !s.in.Scan() {
+			panic(quit{})
+		}
+
+		if s.command(t, token, strings.TrimSpace(s.in.Text())) {
+			s.depth = t.CallDepth()
+			return
+		}
+	}
+}
+
+func (s *Session) shouldStop(t *exec.TreeExecutor, token lexer.Token) bool {
+	if s.breakpoints[breakpoint{s.current(t), token.Line}] {
+		return true
+	}
+
+	switch s.mode {
+	case STEP_INTO:
+		return true
+	case STEP_OVER:
+		return t.CallDepth() <= s.depth
+	case STEP_OUT:
+		return t.CallDepth() < s.depth
+	default:
+		return false
+	}
+}
+
+// command runs a single debugger command, and returns true if execution
+// should resume.
+func (s *Session) command(t *exec.TreeExecutor, token lexer.Token, line string) bool {
+	name, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i != -1 {
+		name, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	switch name {
+	case "c", "continue":
+		s.mode = CONTINUE
+		return true
+	case "s", "step":
+		s.mode = STEP_INTO
+		return true
+	case "n", "next":
+		s.mode = STEP_OVER
+		return true
+	case "o", "out":
+		s.mode = STEP_OUT
+		return true
+	case "b", "break":
+		s.setBreakpoint(t, rest, true)
+	case "d", "delete":
+		s.setBreakpoint(t, rest, false)
+	case "bl", "breakpoints":
+		s.listBreakpoints()
+	case "bt", "stack":
+		s.printStack(t, token)
+	case "f", "frames":
+		s.printFrames(t)
+	case "v", "vars":
+		s.printVars(t, rest)
+	case "p", "print":
+		s.printExpression(t, rest)
+	case "set":
+		s.setVariable(t, rest)
+	case "l", "list":
+		s.list(t, token)
+	case "q", "quit":
+		panic(quit{})
+	case "h", "help", "":
+		s.help()
+	default:
+		fmt.Fprintf(s.out, "Unknown command '%s', type 'help' for a list of commands\n", name)
+	}
+
+	return false
+}
+
+func (s *Session) help() {
+	fmt.Fprint(s.out, `Commands:
+  c, continue          run until the next breakpoint
+  s, step              run to the next statement, entering function calls
+  n, next              run to the next statement in this function
+  o, out               run until the current function returns
+  b, break [file:]line set a breakpoint
+  d, delete [file:]line remove a breakpoint
+  bl, breakpoints      list breakpoints
+  bt, stack            show the functions being executed
+  f, frames            list the frames on the stack
+  v, vars [frame]      show the variables in a frame, the top frame by default
+  p, print expr        evaluate an expression in the paused program
+  set [frame] name = expr
+                       assign a variable, in the frame it is defined in by default
+  l, list              show the source around the current line
+  q, quit              stop the program
+`)
+}
+
+// current returns the cleaned absolute path of the file t is executing.
+func (s *Session) current(t *exec.TreeExecutor) string {
+	path := t.Path()
+	if abs, ok := s.paths[path]; ok {
+		return abs
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = filepath.Clean(path)
+	}
+
+	s.paths[path] = abs
+	return abs
+}
+
+// resolve returns the cleaned absolute path of a file given by the user,
+// relative to the directory of the program being debugged.
+func (s *Session) resolve(file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(s.dir, file)
+	}
+
+	return filepath.Clean(file)
+}
+
+// display shortens path for output, relative to the program's directory when
+// it is inside of it.
+func (s *Session) display(path string) string {
+	rel, err := filepath.Rel(s.dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return rel
+}
+
+func (s *Session) parseLocation(t *exec.TreeExecutor, loc string) (breakpoint, bool) {
+	file := s.current(t)
+	lineStr := loc
+
+	if i := strings.LastIndex(loc, ":"); i != -1 {
+		file, lineStr = s.resolve(loc[:i]), loc[i+1:]
+	}
+
+	line, err := strconv.Atoi(lineStr)
+	if err != nil || line < 1 {
+		fmt.Fprintf(s.out, "Invalid location '%s', expected file:line or line\n", loc)
+		return breakpoint{}, false
+	}
+
+	return breakpoint{file, line}, true
+}
+
+func (s *Session) setBreakpoint(t *exec.TreeExecutor, loc string, enabled bool) {
+	b, ok := s.parseLocation(t, loc)
+	if !ok {
+		return
+	}
+
+	if enabled {
+		s.breakpoints[b] = true
+		fmt.Fprintf(s.out, "Breakpoint set at %s:%d\n", s.display(b.File), b.Line)
+	} else if s.breakpoints[b] {
+		delete(s.breakpoints, b)
+		fmt.Fprintf(s.out, "Breakpoint removed from %s:%d\n", s.display(b.File), b.Line)
+	} else {
+		fmt.Fprintf(s.out, "No breakpoint at %s:%d\n", s.display(b.File), b.Line)
+	}
+}
+
+// SetBreakpoint sets a breakpoint at line in file, which is relative to the
+// directory of the program being debugged unless it is absolute.
+func (s *Session) SetBreakpoint(file string, line int) {
+	s.breakpoints[breakpoint{s.resolve(file), line}] = true
+}
+
+func (s *Session) listBreakpoints() {
+	list := []breakpoint{}
+	for b := range s.breakpoints {
+		list = append(list, b)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].File != list[j].File {
+			return list[i].File < list[j].File
+		}
+		return list[i].Line < list[j].Line
+	})
+
+	for _, b := range list {
+		fmt.Fprintf(s.out, "%s:%d\n", s.display(b.File), b.Line)
+	}
+}
+
+func (s *Session) printStack(t *exec.TreeExecutor, token lexer.Token) {
+	fmt.Fprintf(s.out, "  at %s:%d:%d\n", token.File, token.Line, token.Column)
+
+	stack := t.CallStack()
+	for i := len(stack) - 1; i >= 0; i-- {
+		f := stack[i]
+		fmt.Fprintf(s.out, "  in function defined at %s:%d:%d\n", f.File, f.Line, f.Column)
+	}
+}
+
+func (s *Session) printFrames(t *exec.TreeExecutor) {
+	for i, f := range t.Frames {
+		fmt.Fprintf(s.out, "%d: %d variables\n", i, len(f))
+	}
+}
+
+func (s *Session) frameIndex(t *exec.TreeExecutor, str string) (int, bool) {
+	i, err := strconv.Atoi(str)
+	if err != nil || i < 0 || i >= len(t.Frames) {
+		fmt.Fprintf(s.out, "Invalid frame '%s', expected a number from 0 to %d\n", str, len(t.Frames)-1)
+		return 0, false
+	}
+
+	return i, true
+}
+
+func (s *Session) printVars(t *exec.TreeExecutor, arg string) {
+	i := len(t.Frames) - 1
+
+	if arg != "" {
+		var ok bool
+		if i, ok = s.frameIndex(t, arg); !ok {
+			return
+		}
+	}
+
+	names := []string{}
+	for name := range t.Frames[i] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%s = %s\n", name, safeStr(t.Frames[i][name]))
+	}
+}
+
+func (s *Session) printExpression(t *exec.TreeExecutor, src string) {
+	v, err := Eval(t, src)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+
+	fmt.Fprintln(s.out, safeStr(v))
+}
+
+func (s *Session) setVariable(t *exec.TreeExecutor, arg string) {
+	frame := -1
+
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 {
+		fmt.Fprintln(s.out, "Expected set [frame] name = expr")
+		return
+	}
+
+	target := strings.Fields(parts[0])
+	if len(target) == 2 {
+		var ok bool
+		if frame, ok = s.frameIndex(t, target[0]); !ok {
+			return
+		}
+		target = target[1:]
+	}
+
+	if len(target) != 1 {
+		fmt.Fprintln(s.out, "Expected set [frame] name = expr")
+		return
+	}
+
+	name := target[0]
+
+	v, err := Eval(t, parts[1])
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+
+	// Default to the innermost frame that already defines the name, so
+	// that the program sees the change
+	if frame == -1 {
+		frame = len(t.Frames) - 1
+		for i := len(t.Frames) - 1; i >= 0; i-- {
+			if _, ok := t.Frames[i][name]; ok {
+				frame = i
+				break
+			}
+		}
+	}
+
+	t.Frames[frame][name] = v
+	fmt.Fprintf(s.out, "%s = %s\n", name, safeStr(v))
+}
+
+// Eval evaluates an expression in the context of a paused executor.
+func Eval(t *exec.TreeExecutor, src string) (v *exec.OwlObj, err error) {
+	l := lexer.NewLexer(src)
+	p := parser.NewParser(l.Tokenize("<debug>"))
+	program := p.Parse()
+
+	if len(p.Errors) > 0 {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(parser.FormatErrors(p.Errors, src)))
+	}
+
+	if len(program.Body) != 1 {
+		return nil, fmt.Errorf("Expected a single expression")
+	}
+
+	stmt, ok := program.Body[0].(*parser.ExpressionStatement)
+	if !ok {
+		return nil, fmt.Errorf("Expected an expression, got a statement")
+	}
+
+	// Try unwinds the frames of calls that fail, so the program can carry on
+	err = t.Try(func() { v = t.EvalExpression(stmt.Value) })
+
+	// Quitting from a breakpoint hit while evaluating quits the program
+	if _, ok := err.(quit); ok {
+		panic(err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(err.Error()))
+	}
+
+	return v, nil
+}
+
+func safeStr(v *exec.OwlObj) (s string) {
+	if v == nil {
+		return "nil"
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s = "<unprintable>"
+		}
+	}()
+
+	return v.TrueStr()
+}
+
+// source returns the lines of the file at path.
+func (s *Session) source(path string) []string {
+	if lines, ok := s.sources[path]; ok {
+		return lines
+	}
+
+	bytes, err := os.ReadFile(path)
+	var lines []string
+	if err == nil {
+		lines = strings.Split(strings.ReplaceAll(string(bytes), "\r\n", "\n"), "\n")
+	}
+
+	s.sources[path] = lines
+	return lines
+}
+
+func (s *Session) show(t *exec.TreeExecutor, token lexer.Token) {
+	lines := s.source(s.current(t))
+
+	if token.Line >= 1 && token.Line <= len(lines) {
+		fmt.Fprintf(s.out, "%s:%d  %s\n", token.File, token.Line, strings.TrimSpace(lines[token.Line-1]))
+	} else {
+		fmt.Fprintf(s.out, "%s:%d\n", token.File, token.Line)
+	}
+}
+
+func (s *Session) list(t *exec.TreeExecutor, token lexer.Token) {
+	path := s.current(t)
+	lines := s.source(path)
+
+	if lines == nil {
+		fmt.Fprintf(s.out, "Source for %s is not available\n", token.File)
+		return
+	}
+
+	for i := token.Line - 5; i <= token.Line+5; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+
+		marker := "  "
+		if i == token.Line {
+			marker = "->"
+		} else if s.breakpoints[breakpoint{path, i}] {
+			marker = "* "
+		}
+
+		fmt.Fprintf(s.out, "%s %4d  %s\n", marker, i, lines[i-1])
+	}
+}
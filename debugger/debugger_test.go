@@ -0,0 +1,138 @@
+package debugger
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/internal/testutil"
+)
+
+const program = `add = (a, b) => {
+    c = a + b
+    return c
+}
+x = 1
+y = add(x, 2)
+return y`
+
+func run(t *testing.T, commands string) string {
+	params, errs := exec.LoadProgram(program, "debug_test.hoot")
+	if len(errs) > 0 {
+		t.Fatalf("Failed to parse program: %v", errs)
+	}
+
+	out := &bytes.Buffer{}
+	Start(params, strings.NewReader(commands), out)
+
+	return out.String()
+}
+
+func expectOutput(t *testing.T, out string, expected ...string) {
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, out)
+		}
+	}
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		commands string
+		expected []string
+	}{
+		{"s\ns\ns\ns\n", []string{"debug_test.hoot:5", "debug_test.hoot:6", "debug_test.hoot:2"}},
+		{"s\ns\nn\nn\n", []string{"debug_test.hoot:6", "debug_test.hoot:7"}},
+		{"s\ns\ns\no\nc\n", []string{"debug_test.hoot:2", "debug_test.hoot:7", "Program returned 3"}},
+	}
+
+	for _, tt := range tests {
+		out := run(t, tt.commands)
+		expectOutput(t, out, tt.expected...)
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	out := run(t, "b 3\nbl\nc\nbt\nd 3\nc\n")
+
+	expectOutput(t, out,
+		"Breakpoint set at debug_test.hoot:3",
+		"debug_test.hoot:3\n",
+		"in function defined at debug_test.hoot:1:14",
+		"Breakpoint removed from debug_test.hoot:3",
+		"Program returned 3",
+	)
+}
+
+// TestBreakpointFiles checks that breakpoints tell apart files with the same
+// name in different directories.
+func TestBreakpointFiles(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{
+		"main.hoot":   "import \"./a/util\" as a\nimport \"./b/util\" as b\nreturn a.x + b.x",
+		"a/util.hoot": "x = \"a\"",
+		"b/util.hoot": "x = \"b\"",
+	})
+
+	ok, params, errs := exec.LoadProgramFromPath(filepath.Join(dir, "main.hoot"))
+	if !ok || len(errs) > 0 {
+		t.Fatalf("Failed to load program: %v", errs)
+	}
+
+	out := &bytes.Buffer{}
+	Start(params, strings.NewReader("b b/util.hoot:1\nbl\nc\nc\n"), out)
+
+	expectOutput(t, out.String(),
+		"Breakpoint set at "+filepath.Join("b", "util.hoot")+":1",
+		"util.hoot:1  x = \"b\"",
+		"Program returned ab",
+	)
+
+	if strings.Contains(out.String(), "x = \"a\"") {
+		t.Errorf("Expected a/util.hoot not to stop, got:\n%s", out.String())
+	}
+}
+
+func TestInspectAndModify(t *testing.T) {
+	out := run(t, "b 3\nc\nv\np a * 10\nset c = 40\nset 1 x = 5\np x\nc\n")
+
+	expectOutput(t, out,
+		"a = 1\nb = 2\nc = 3\n",
+		"10\n",
+		"c = 40\n",
+		"x = 5\n",
+		"Program returned 40",
+	)
+}
+
+// TestEvalUnwinds checks that a call that fails while evaluating leaves the
+// stack as it was, so that the program can continue.
+func TestEvalUnwinds(t *testing.T) {
+	out := run(t, "b 3\nc\np add(1, [])\nbt\nset c = add(1, [])\nv\nc\n")
+
+	expectOutput(t, out,
+		"Unable to evaluate binary operator",
+		"a = 1\nb = 2\nc = 3\n",
+		"Program returned 3",
+	)
+
+	if strings.Count(out, "in function defined at") != 1 {
+		t.Errorf("Expected the stack to have one call, got:\n%s", out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	out := run(t, "p (1 +\np missing\nset 99 x = 1\nfoo\nq\n")
+
+	expectOutput(t, out,
+		"Expected an expression, got end of file",
+		"Unable to find variable 'missing'",
+		"Invalid frame '99'",
+		"Unknown command 'foo'",
+	)
+
+	if strings.Contains(out, "Program returned") {
+		t.Errorf("Expected quit to stop the program, got:\n%s", out)
+	}
+}
@@ -0,0 +1,309 @@
+// Package decimal implements exact base 10 numbers, for arithmetic such as
+// money where binary floating point rounds values like 0.1.
+//
+// A Decimal is an arbitrary precision integer coefficient and a scale, the
+// number of digits after the decimal point, so 19.99 is 1999 with a scale of
+// 2. Addition, subtraction and multiplication are exact. Division is rounded
+// half to even after DIV_DIGITS more digits than its operands have.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DIV_DIGITS is the number of digits kept by division beyond the larger
+// scale of its operands.
+const DIV_DIGITS = 16
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Decimal is an immutable base 10 number. The zero value is 0.
+type Decimal struct {
+	coef  *big.Int
+	scale int32
+}
+
+var (
+	bigZero = big.NewInt(0)
+	bigOne  = big.NewInt(1)
+	bigTen  = big.NewInt(10)
+)
+
+// New creates the decimal coef * 10^-scale.
+func New(coef *big.Int, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(coef, pow10(-scale))}
+	}
+
+	return Decimal{coef: new(big.Int).Set(coef), scale: scale}
+}
+
+func FromInt(n int64) Decimal {
+	return Decimal{coef: big.NewInt(n)}
+}
+
+func FromBig(n *big.Int) Decimal {
+	return New(n, 0)
+}
+
+// Parse reads a decimal such as "12", "-0.50" or "1.5e3". The digits given
+// after the point are kept, so "1.50" has a scale of 2.
+func Parse(s string) (Decimal, error) {
+	invalid := fmt.Errorf("invalid decimal %q", s)
+	mantissa, exp := s, int64(0)
+
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, invalid
+		}
+		exp = e
+	}
+
+	digits := mantissa
+	if digits != "" && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+
+	whole, frac := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, frac = digits[:i], digits[i+1:]
+	}
+
+	if whole+frac == "" || strings.TrimLeft(whole+frac, "0123456789") != "" {
+		return Decimal{}, invalid
+	}
+
+	coef, _ := new(big.Int).SetString(whole+frac, 10)
+	if mantissa[0] == '-' {
+		coef.Neg(coef)
+	}
+
+	scale := int64(len(frac)) - exp
+	if scale > 1<<31-1 || scale < -(1<<31) {
+		return Decimal{}, invalid
+	}
+
+	return New(coef, int32(scale)), nil
+}
+
+func (d Decimal) c() *big.Int {
+	if d.coef == nil {
+		return bigZero
+	}
+
+	return d.coef
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// rescale returns the coefficient of d with the larger scale, which is
+// exact.
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.c()
+	}
+
+	return new(big.Int).Mul(d.c(), pow10(scale-d.scale))
+}
+
+// align returns the coefficients of d and e with the same scale.
+func align(d Decimal, e Decimal) (*big.Int, *big.Int, int32) {
+	scale := d.scale
+	if e.scale > scale {
+		scale = e.scale
+	}
+
+	return d.rescale(scale), e.rescale(scale), scale
+}
+
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b, scale := align(d, e)
+	return Decimal{coef: new(big.Int).Add(a, b), scale: scale}
+}
+
+func (d Decimal) Sub(e Decimal) Decimal {
+	a, b, scale := align(d, e)
+	return Decimal{coef: new(big.Int).Sub(a, b), scale: scale}
+}
+
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.c(), e.c()), scale: d.scale + e.scale}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.c()), scale: d.scale}
+}
+
+// Div divides d by e, keeping DIV_DIGITS digits more than the larger scale
+// of d and e, and then dropping trailing zeros past that scale.
+func (d Decimal) Div(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+
+	keep := d.scale
+	if e.scale > keep {
+		keep = e.scale
+	}
+	scale := keep + DIV_DIGITS
+
+	// d / e = (d.coef * 10^(scale + e.scale - d.scale)) / e.coef * 10^-scale
+	num := new(big.Int).Set(d.c())
+	if shift := scale + e.scale - d.scale; shift > 0 {
+		num.Mul(num, pow10(shift))
+	}
+
+	q := roundQuo(num, e.c())
+
+	return Decimal{coef: q, scale: scale}.trim(keep), nil
+}
+
+// IntDiv returns the largest integer that is at most d / e.
+func (d Decimal) IntDiv(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+
+	a, b, _ := align(d, e)
+	return Decimal{coef: floorQuo(a, b)}, nil
+}
+
+// Mod returns the remainder of d / e, which is never negative when e is
+// positive.
+func (d Decimal) Mod(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+
+	a, b, scale := align(d, e)
+	r := new(big.Int).Rem(a, b)
+	if r.Sign() < 0 {
+		r.Add(r, b)
+	}
+
+	return Decimal{coef: r, scale: scale}, nil
+}
+
+// Round rounds d half to even to the given number of digits after the
+// point.
+func (d Decimal) Round(places int32) Decimal {
+	if places >= d.scale {
+		return Decimal{coef: d.rescale(places), scale: places}
+	}
+
+	if places < 0 {
+		places = 0
+	}
+
+	return Decimal{coef: roundQuo(d.c(), pow10(d.scale-places)), scale: places}
+}
+
+// trim removes trailing zeros from d, keeping at least min digits after the
+// point.
+func (d Decimal) trim(min int32) Decimal {
+	coef, scale := d.c(), d.scale
+	r := new(big.Int)
+
+	for scale > min {
+		q, rem := new(big.Int).QuoRem(coef, bigTen, r)
+		if rem.Sign() != 0 {
+			break
+		}
+		coef, scale = q, scale-1
+	}
+
+	return Decimal{coef: coef, scale: scale}
+}
+
+// roundQuo divides a by b, rounding half to even.
+func roundQuo(a *big.Int, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// Compare 2|r| with |b| to find which way to round
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	c := twice.Cmp(new(big.Int).Abs(b))
+
+	if c > 0 || (c == 0 && q.Bit(0) == 1) {
+		if (a.Sign() < 0) != (b.Sign() < 0) {
+			q.Sub(q, bigOne)
+		} else {
+			q.Add(q, bigOne)
+		}
+	}
+
+	return q
+}
+
+// floorQuo divides a by b, rounding towards negative infinity.
+func floorQuo(a *big.Int, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() != 0 && (r.Sign() < 0) != (b.Sign() < 0) {
+		q.Sub(q, bigOne)
+	}
+
+	return q
+}
+
+// Cmp returns -1, 0 or 1 when d is less than, equal to or greater than e.
+// Decimals that differ only in trailing zeros are equal.
+func (d Decimal) Cmp(e Decimal) int {
+	a, b, _ := align(d, e)
+	return a.Cmp(b)
+}
+
+func (d Decimal) Sign() int {
+	return d.c().Sign()
+}
+
+// Scale is the number of digits after the point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Int returns d as an integer, if it has no fractional part.
+func (d Decimal) Int() (*big.Int, bool) {
+	q, r := new(big.Int).QuoRem(d.c(), pow10(d.scale), new(big.Int))
+	return q, r.Sign() == 0
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.c(), pow10(d.scale)).Float64()
+	return f
+}
+
+// Key returns the same string for decimals that are equal, for use in hashes
+// and map keys.
+func (d Decimal) Key() string {
+	return d.trim(0).String()
+}
+
+func (d Decimal) String() string {
+	s := new(big.Int).Abs(d.c()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.scale == 0 {
+		return sign + s
+	}
+
+	if pad := int(d.scale) + 1 - len(s); pad > 0 {
+		s = strings.Repeat("0", pad) + s
+	}
+
+	point := len(s) - int(d.scale)
+	return sign + s[:point] + "." + s[point:]
+}
@@ -0,0 +1,135 @@
+package decimal
+
+import (
+	"testing"
+)
+
+func mustParse(t *testing.T, s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"12", "12"},
+		{"-0.50", "-0.50"},
+		{".5", "0.5"},
+		{"+3.", "3"},
+		{"1.5e3", "1500"},
+		{"15e-4", "0.0015"},
+		{"0", "0"},
+		{"123456789012345678901234567890.1", "123456789012345678901234567890.1"},
+	}
+
+	for _, tt := range tests {
+		if s := mustParse(t, tt.input).String(); s != tt.expected {
+			t.Errorf("Parse(%q) is %s, expected %s", tt.input, s, tt.expected)
+		}
+	}
+
+	for _, input := range []string{"", "-", ".", "1.2.3", "1e", "abc", "1_0"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", input)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		a, op, b string
+		expected string
+	}{
+		{"0.1", "+", "0.2", "0.3"},
+		{"19.99", "+", "0.01", "20.00"},
+		{"1", "-", "0.05", "0.95"},
+		{"1.10", "*", "3", "3.30"},
+		{"0.5", "*", "0.5", "0.25"},
+		{"10.00", "/", "4", "2.50"},
+		{"1", "/", "3", "0.3333333333333333"},
+		{"2", "/", "3", "0.6666666666666667"},
+		{"-1", "/", "8", "-0.125"},
+		{"7.5", "//", "2", "3"},
+		{"-7.5", "//", "2", "-4"},
+		{"7.5", "%", "2", "1.5"},
+		{"-7.5", "%", "2", "0.5"},
+	}
+
+	for _, tt := range tests {
+		a, b := mustParse(t, tt.a), mustParse(t, tt.b)
+		var v Decimal
+		var err error
+
+		switch tt.op {
+		case "+":
+			v = a.Add(b)
+		case "-":
+			v = a.Sub(b)
+		case "*":
+			v = a.Mul(b)
+		case "/":
+			v, err = a.Div(b)
+		case "//":
+			v, err = a.IntDiv(b)
+		case "%":
+			v, err = a.Mod(b)
+		}
+
+		if err != nil {
+			t.Errorf("%s %s %s failed: %v", tt.a, tt.op, tt.b, err)
+		} else if v.String() != tt.expected {
+			t.Errorf("%s %s %s is %s, expected %s", tt.a, tt.op, tt.b, v, tt.expected)
+		}
+	}
+
+	if _, err := FromInt(1).Div(Decimal{}); err != ErrDivisionByZero {
+		t.Errorf("Expected division by zero, got %v", err)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		places   int32
+		expected string
+	}{
+		{"2.345", 2, "2.34"},
+		{"2.355", 2, "2.36"},
+		{"-2.345", 2, "-2.34"},
+		{"2.5", 0, "2"},
+		{"3.5", 0, "4"},
+		{"1.2", 3, "1.200"},
+	}
+
+	for _, tt := range tests {
+		if s := mustParse(t, tt.input).Round(tt.places).String(); s != tt.expected {
+			t.Errorf("%s rounded to %d places is %s, expected %s", tt.input, tt.places, s, tt.expected)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a, b := mustParse(t, "1.50"), mustParse(t, "1.5")
+
+	if a.Cmp(b) != 0 || a.Key() != b.Key() {
+		t.Errorf("Expected 1.50 to equal 1.5")
+	}
+	if mustParse(t, "-2").Cmp(mustParse(t, "1.99")) != -1 {
+		t.Errorf("Expected -2 to be less than 1.99")
+	}
+	if n, ok := mustParse(t, "4.00").Int(); !ok || n.Int64() != 4 {
+		t.Errorf("Expected 4.00 to be the integer 4")
+	}
+	if _, ok := mustParse(t, "4.01").Int(); ok {
+		t.Errorf("Expected 4.01 not to be an integer")
+	}
+	if f := mustParse(t, "0.25").Float64(); f != 0.25 {
+		t.Errorf("Expected 0.25, got %v", f)
+	}
+}
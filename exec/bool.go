@@ -1,36 +1,11 @@
 package exec
 
-var cache = [2]*OwlObj{}
-
 func NewBool(v bool) *OwlObj {
-	if v && cache[1] != nil {
-		return cache[1]
-	} else if !v && cache[0] != nil {
-		return cache[0]
-	}
-
-	b := NewOwlObj()
-	b.SetDeepAttr("and", NewCallBridge(boolAnd))
-	b.SetDeepAttr("or", NewCallBridge(boolOr))
-	b.SetDeepAttr("not", NewCallBridge(boolNot))
-	b.SetDeepAttr("eq", NewCallBridge(boolEq))
-	b.SetDeepAttr("ne", NewCallBridge(boolNe))
-	b.SetDeepAttr("str", NewCallBridge(boolStr))
-	b.DeleteDeepAttr("iter")
-	b.DeleteDeepAttr("index")
-	b.DeleteDeepAttr("setIndex")
-	b.DeleteDeepAttr("has")
-
-	//b.BridgeVal = v
-	b.Raw = v
-
 	if v {
-		cache[1] = b
-	} else {
-		cache[0] = b
+		return boolValues[1]
 	}
 
-	return b
+	return boolValues[0]
 }
 
 func boolAnd(args []*OwlObj) (*OwlObj, bool) {
@@ -88,3 +63,18 @@ func boolStr(args []*OwlObj) (*OwlObj, bool) {
 		return NewString("false"), true
 	}
 }
+
+// Bools support the bitwise operators, so that flags can be combined with
+// &= and |= without short circuiting.
+func boolBitwise(op func(a bool, b bool) bool) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		a, aOk := args[1].TrueBool()
+		b, bOk := args[2].TrueBool()
+
+		if !aOk || !bOk {
+			return nil, false
+		}
+
+		return NewBool(op(a, b)), true
+	}
+}
@@ -3,11 +3,16 @@ package exec
 type BridgeData struct {
 	This       *OwlObj
 	BridgeCall func(args []*OwlObj) (*OwlObj, bool)
+
+	// Whole passes the argument of a call as args[1], as it was given,
+	// instead of spreading a list into separate arguments. It is set on
+	// functions taking a single value that can be a list.
+	Whole bool
 }
 
 func NewCallBridge(f func(args []*OwlObj) (*OwlObj, bool)) *OwlObj {
 	b := &OwlObj{}
-	data := &BridgeData{nil, f}
+	data := &BridgeData{BridgeCall: f}
 
 	b.BridgeCall = func(a *OwlObj) (*OwlObj, bool) { return bridgeCall(b, a) }
 	b.Bind = func(this *OwlObj) { data.This = this }
@@ -34,11 +39,31 @@ func flattenArg(arg *OwlObj) []*OwlObj {
 	return args
 }
 
+// bridgeArgs creates the arguments of a call to a bridge. count is the
+// number of arguments the call was written with, or -1 when it is not
+// known. A Whole bridge called with several arguments is given them
+// separately, so that it can reject the extra ones.
+func bridgeArgs(data *BridgeData, this *OwlObj, arg *OwlObj, count int) []*OwlObj {
+	if data.Whole && arg != nil && count <= 1 {
+		return []*OwlObj{this, arg}
+	}
+
+	return append([]*OwlObj{this}, flattenArg(arg)...)
+}
+
 func bridgeCall(callBridge *OwlObj, arg *OwlObj) (*OwlObj, bool) {
 	data := callBridge.Raw.(*BridgeData)
 	this := data.This
 
-	args := append([]*OwlObj{this}, flattenArg(arg)...)
+	return data.BridgeCall(bridgeArgs(data, this, arg, -1))
+}
+
+// callCounted calls fn with arg, which is a list of count arguments when
+// count is more than 1.
+func callCounted(fn *OwlObj, arg *OwlObj, count int) (*OwlObj, bool) {
+	if data, ok := fn.Raw.(*BridgeData); ok && count > 1 {
+		return data.BridgeCall(bridgeArgs(data, data.This, arg, count))
+	}
 
-	return data.BridgeCall(args)
+	return fn.Call(arg)
 }
@@ -0,0 +1,644 @@
+package exec
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
+)
+
+var owlObjType = reflect.TypeOf((*OwlObj)(nil))
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// CallArgs calls fn with Go side arguments, packing them the same way a call
+// written in Owl would. No arguments are passed as nil, a single argument as
+// itself, and several arguments as a list.
+func CallArgs(fn *OwlObj, args ...*OwlObj) (*OwlObj, bool) {
+	switch len(args) {
+	case 0:
+		return fn.Call(nil)
+	case 1:
+		return fn.Call(args[0])
+	default:
+		return callCounted(fn, NewList(args), len(args))
+	}
+}
+
+// fieldName returns the name a struct field is given in Owl, taken from the
+// owl tag if there is one. Fields tagged "-" are skipped.
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+
+	tag := f.Tag.Get("owl")
+	if tag == "-" {
+		return "", false
+	}
+
+	if tag != "" {
+		return tag, true
+	}
+
+	return f.Name, true
+}
+
+// ToOwl converts a Go value to an OwlObj. Numbers, strings, bools, slices,
+// arrays, maps with string keys, structs and pointers to them are converted
+// to their Owl equivalents. Functions become callable objects that convert
+// their arguments and results, and a function may return an error as its
+// last result to make the call fail. *OwlObj values are passed through.
+// Pointers, maps and slices that are reached more than once, including
+// through a cycle, become the same Owl value.
+func ToOwl(v interface{}) (*OwlObj, error) {
+	if v == nil {
+		return NewNull(), nil
+	}
+
+	return toOwl(reflect.ValueOf(v))
+}
+
+func toOwl(v reflect.Value) (*OwlObj, error) {
+	c := &converter{seen: map[visit]*OwlObj{}}
+	return c.toOwl(v)
+}
+
+// converter converts a Go value to an OwlObj, remembering the value each
+// pointer, map and slice was converted to so that cycles end.
+type converter struct {
+	seen map[visit]*OwlObj
+}
+
+// visit identifies a pointer, map or slice. The type is needed as a struct
+// and its first field have the same address, and the length as slices of
+// the same array can start at the same element.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func (c *converter) toOwl(v reflect.Value) (*OwlObj, error) {
+	if v.Type() == owlObjType {
+		if v.IsNil() {
+			return NewNull(), nil
+		}
+		return v.Interface().(*OwlObj), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return NewBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return NewBigInt(new(big.Int).SetUint64(u)), nil
+		}
+		return NewInt(int64(u)), nil
+	case reflect.Float32, reflect.Float64:
+		return NewFloat(v.Float()), nil
+	case reflect.String:
+		return NewString(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NewList([]*OwlObj{}), nil
+		}
+
+		items := make([]*OwlObj, v.Len())
+		o := NewList(items)
+
+		if v.Kind() == reflect.Slice {
+			key := visit{v.Pointer(), v.Type(), v.Len()}
+			if seen, ok := c.seen[key]; ok {
+				return seen, nil
+			}
+			c.seen[key] = o
+		}
+
+		for i := range items {
+			item, err := c.toOwl(v.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			items[i] = item
+		}
+
+		return o, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unable to convert %s, map keys must be strings", v.Type())
+		}
+
+		key := visit{v.Pointer(), v.Type(), 0}
+		if seen, ok := c.seen[key]; ok {
+			return seen, nil
+		}
+
+		o := NewOwlObj()
+		c.seen[key] = o
+
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := c.toOwl(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf(".%s: %w", iter.Key().String(), err)
+			}
+			o.SetAttr(iter.Key().String(), item)
+		}
+
+		return o, nil
+	case reflect.Struct:
+		return c.structToOwl(v, NewOwlObj())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return NewNull(), nil
+		}
+
+		key := visit{v.Pointer(), v.Type(), 0}
+		if seen, ok := c.seen[key]; ok {
+			return seen, nil
+		}
+
+		// The object is remembered before its fields are converted, so
+		// that fields pointing back to it find it
+		if v.Elem().Kind() == reflect.Struct {
+			o := NewOwlObj()
+			c.seen[key] = o
+			return c.structToOwl(v.Elem(), o)
+		}
+
+		o, err := c.toOwl(v.Elem())
+		if err == nil {
+			c.seen[key] = o
+		}
+		return o, err
+	case reflect.Interface:
+		if v.IsNil() {
+			return NewNull(), nil
+		}
+		return c.toOwl(v.Elem())
+	case reflect.Func:
+		if v.IsNil() {
+			return NewNull(), nil
+		}
+		return funcToOwl(v), nil
+	}
+
+	return nil, fmt.Errorf("unable to convert %s to an Owl value", v.Type())
+}
+
+// structToOwl sets the fields of the struct v on o.
+func (c *converter) structToOwl(v reflect.Value, o *OwlObj) (*OwlObj, error) {
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := fieldName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		item, err := c.toOwl(v.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf(".%s: %w", name, err)
+		}
+		o.SetAttr(name, item)
+	}
+
+	return o, nil
+}
+
+// ModuleFrom builds a module from a Go value, for use with RegisterModule.
+// v may be a struct, a pointer to a struct, or a map with string keys. Its
+// fields or entries are converted with ToOwl when the module is built, and
+// the exported methods of v become functions of the module, so state that
+// changes should be kept behind a pointer and reached through methods.
+func ModuleFrom(v interface{}) (*OwlObj, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("unable to build a module from nil")
+	}
+
+	base := rv
+	for base.Kind() == reflect.Ptr {
+		if base.IsNil() {
+			return nil, fmt.Errorf("unable to build a module from a nil %s", rv.Type())
+		}
+		base = base.Elem()
+	}
+
+	if base.Kind() != reflect.Struct && base.Kind() != reflect.Map {
+		return nil, fmt.Errorf("unable to build a module from %s, expected a struct or a map", rv.Type())
+	}
+
+	o, err := toOwl(base)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < rv.NumMethod(); i++ {
+		o.SetAttr(rv.Type().Method(i).Name, funcToOwl(rv.Method(i)))
+	}
+
+	return o, nil
+}
+
+// MustModuleFrom is like ModuleFrom, but panics if v cannot be converted.
+func MustModuleFrom(v interface{}) *OwlObj {
+	o, err := ModuleFrom(v)
+	if err != nil {
+		panic("exec: " + err.Error())
+	}
+
+	return o
+}
+
+func funcToOwl(fn reflect.Value) *OwlObj {
+	typ := fn.Type()
+
+	return NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		args = args[1:]
+
+		variadic := typ.IsVariadic()
+		fixed := typ.NumIn()
+		if variadic {
+			fixed--
+		}
+
+		// Calls flatten a list argument into separate arguments, so a
+		// function taking a single slice gets them back as one list
+		if fixed == 1 && !variadic && isListKind(typ.In(0)) {
+			if _, isList := singleList(args); !isList {
+				args = []*OwlObj{NewList(args)}
+			}
+		}
+
+		if len(args) < fixed || (!variadic && len(args) > fixed) {
+			return NewString(fmt.Sprintf("Expected %d arguments, got %d", fixed, len(args))), false
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var argType reflect.Type
+			if i < fixed {
+				argType = typ.In(i)
+			} else {
+				argType = typ.In(fixed).Elem()
+			}
+
+			v, err := fromOwl(arg, argType)
+			if err != nil {
+				return NewString(fmt.Sprintf("Argument %d: %s", i+1, err)), false
+			}
+			in[i] = v
+		}
+
+		out := fn.Call(in)
+
+		if n := len(out); n > 0 && typ.Out(n-1) == errorType {
+			if err, _ := out[n-1].Interface().(error); err != nil {
+				return NewString(err.Error()), false
+			}
+			out = out[:n-1]
+		}
+
+		switch len(out) {
+		case 0:
+			return NewNull(), true
+		case 1:
+			v, err := toOwl(out[0])
+			if err != nil {
+				return NewString(err.Error()), false
+			}
+			return v, true
+		default:
+			items := make([]*OwlObj, len(out))
+			for i, o := range out {
+				v, err := toOwl(o)
+				if err != nil {
+					return NewString(err.Error()), false
+				}
+				items[i] = v
+			}
+			return NewList(items), true
+		}
+	})
+}
+
+func isListKind(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array
+}
+
+func singleList(args []*OwlObj) ([]*OwlObj, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	return args[0].TrueList()
+}
+
+// FromOwl converts an OwlObj into the Go value that out points to, this is
+// the reverse of ToOwl. Objects can be converted to structs, in which case
+// attributes without a matching field are ignored. Converting to an
+// interface{} gives int64, float64, string, bool, nil, []interface{} or
+// map[string]interface{}, and *OwlObj for anything else.
+func FromOwl(o *OwlObj, out interface{}) error {
+	p := reflect.ValueOf(out)
+	if p.Kind() != reflect.Ptr || p.IsNil() {
+		return fmt.Errorf("expected a non nil pointer, got %T", out)
+	}
+
+	v, err := fromOwl(o, p.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	p.Elem().Set(v)
+	return nil
+}
+
+// ToGo converts an OwlObj to the most natural Go value, see FromOwl.
+func ToGo(o *OwlObj) interface{} {
+	if o == nil || o.IsNullish() {
+		return nil
+	}
+
+	switch raw := o.Raw.(type) {
+	case int64, float64, string, bool, *big.Int, decimal.Decimal, time.Time, time.Duration:
+		return raw
+	case []*OwlObj:
+		items := make([]interface{}, len(raw))
+		for i, item := range raw {
+			items[i] = ToGo(item)
+		}
+		return items
+	}
+
+	if isPlainObj(o) {
+		m := map[string]interface{}{}
+		for k, v := range o.Attr {
+			m[k] = ToGo(v)
+		}
+		return m
+	}
+
+	return o
+}
+
+func typeName(o *OwlObj) string {
+	if o == nil || o.IsNullish() {
+		return "null"
+	}
+
+	switch o.Raw.(type) {
+	case int64, *big.Int:
+		return "int"
+	case decimal.Decimal:
+		return "decimal"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []*OwlObj:
+		return "list"
+	case time.Time:
+		return "time"
+	case time.Duration:
+		return "duration"
+	case *FuncData, *BridgeData:
+		return "function"
+	}
+
+	return "object"
+}
+
+func fromOwl(o *OwlObj, typ reflect.Type) (reflect.Value, error) {
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("unable to convert %s to %s", typeName(o), typ)
+	}
+
+	if typ == owlObjType {
+		return reflect.ValueOf(o), nil
+	}
+
+	v := reflect.New(typ).Elem()
+
+	if o == nil || o.IsNullish() {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return v, nil
+		}
+		return mismatch()
+	}
+
+	// Values that hold a Go value of the type wanted, such as times, are
+	// passed as they are
+	if raw := reflect.ValueOf(o.Raw); raw.IsValid() && raw.Type() == typ {
+		return raw, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		g := ToGo(o)
+		if !reflect.TypeOf(g).AssignableTo(typ) {
+			return mismatch()
+		}
+		v.Set(reflect.ValueOf(g))
+	case reflect.Bool:
+		b, ok := o.TrueBool()
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := o.TrueInt()
+		if !ok || v.OverflowInt(i) {
+			return mismatch()
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// Values above the largest int64 are big ints
+		if b, ok := o.Raw.(*big.Int); ok && b.IsUint64() && !v.OverflowUint(b.Uint64()) {
+			v.SetUint(b.Uint64())
+			break
+		}
+
+		i, ok := o.TrueInt()
+		if !ok || i < 0 || v.OverflowUint(uint64(i)) {
+			return mismatch()
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, ok := o.TrueFloat()
+		if !ok {
+			// Whole numbers are ints in Owl, but are valid floats
+			i, isInt := o.TrueInt()
+			if !isInt {
+				return mismatch()
+			}
+			f = float64(i)
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		s, ok := o.Raw.(string)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		items, ok := o.TrueList()
+		if !ok {
+			return mismatch()
+		}
+
+		if typ.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(typ, len(items), len(items)))
+		} else if len(items) != typ.Len() {
+			return reflect.Value{}, fmt.Errorf("expected %d items, got %d", typ.Len(), len(items))
+		}
+
+		for i, item := range items {
+			iv, err := fromOwl(item, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%d]: %w", i, err)
+			}
+			v.Index(i).Set(iv)
+		}
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String || !isPlainObj(o) {
+			return mismatch()
+		}
+
+		v.Set(reflect.MakeMapWithSize(typ, len(o.Attr)))
+		for k, item := range o.Attr {
+			iv, err := fromOwl(item, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf(".%s: %w", k, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(typ.Key()), iv)
+		}
+	case reflect.Struct:
+		if !isPlainObj(o) {
+			return mismatch()
+		}
+
+		for i := 0; i < typ.NumField(); i++ {
+			name, ok := fieldName(typ.Field(i))
+			if !ok {
+				continue
+			}
+
+			item, ok := o.Attr[name]
+			if !ok {
+				continue
+			}
+
+			iv, err := fromOwl(item, typ.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf(".%s: %w", name, err)
+			}
+			v.Field(i).Set(iv)
+		}
+	case reflect.Ptr:
+		iv, err := fromOwl(o, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(typ.Elem())
+		p.Elem().Set(iv)
+		v.Set(p)
+	case reflect.Func:
+		if o.BridgeCall == nil {
+			return mismatch()
+		}
+		v.Set(funcFromOwl(o, typ))
+	default:
+		return mismatch()
+	}
+
+	return v, nil
+}
+
+// funcFromOwl wraps an Owl function in a Go function of type typ. If typ
+// returns an error, failures are returned as the error, otherwise they
+// panic. When fn is an Owl function, a failed call unwinds the stack of the
+// executor that owns it, so the program can carry on once the Go function
+// returns.
+func funcFromOwl(fn *OwlObj, typ reflect.Type) reflect.Value {
+	try := Try
+	if data, ok := fn.Raw.(*FuncData); ok {
+		try = data.Exec.Try
+	}
+
+	return reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, typ.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(typ.Out(i))
+		}
+
+		hasErr := typ.NumOut() > 0 && typ.Out(typ.NumOut()-1) == errorType
+		fail := func(err error) []reflect.Value {
+			if !hasErr {
+				panic(err)
+			}
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+
+		args := make([]*OwlObj, len(in))
+		for i, v := range in {
+			arg, err := toOwl(v)
+			if err != nil {
+				return fail(err)
+			}
+			args[i] = arg
+		}
+
+		var result *OwlObj
+		var ok bool
+		err := try(func() { result, ok = CallArgs(fn, args...) })
+		if err != nil {
+			return fail(err)
+		}
+		if !ok {
+			msg := "call failed"
+			if result != nil {
+				msg = result.TrueStr()
+			}
+			return fail(fmt.Errorf("%s", msg))
+		}
+
+		results := typ.NumOut()
+		if hasErr {
+			results--
+		}
+
+		switch results {
+		case 0:
+		case 1:
+			v, err := fromOwl(result, typ.Out(0))
+			if err != nil {
+				return fail(err)
+			}
+			out[0] = v
+		default:
+			items, ok := result.TrueList()
+			if !ok || len(items) != results {
+				return fail(fmt.Errorf("expected %d results", results))
+			}
+			for i, item := range items {
+				v, err := fromOwl(item, typ.Out(i))
+				if err != nil {
+					return fail(err)
+				}
+				out[i] = v
+			}
+		}
+
+		return out
+	})
+}
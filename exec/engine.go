@@ -4,6 +4,7 @@
 package exec
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 
@@ -31,6 +32,13 @@ func LoadProgramFromPath(path string) (bool, *OwlParams, []parser.ParserError) {
 	return true, ret, nil
 }
 
+// FormatParserErrors renders parser errors for the file at path, including an
+// excerpt of the offending line when the file can be read.
+func FormatParserErrors(path string, errs []parser.ParserError) string {
+	bytes, _ := os.ReadFile(path)
+	return parser.FormatErrors(errs, string(bytes))
+}
+
 func LoadProgram(contents string, fileName string) (*OwlParams, []parser.ParserError) {
 	l := lexer.NewLexer(contents)
 	tok := l.Tokenize(filepath.Base(fileName))
@@ -48,7 +56,35 @@ func LoadProgram(contents string, fileName string) (*OwlParams, []parser.ParserE
 	}, nil
 }
 
+// ExecuteProgram executes a program, panicking if it fails. The program runs
+// under its own context, which is cancelled when it returns so that any
+// generators it left paused are stopped.
 func ExecuteProgram(params *OwlParams) (*OwlObj, *TreeExecutor) {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	t := NewTreeExecutor(params.Path)
+	t.SetContext(ctx)
+	defer func() {
+		cancel()
+		t.SetContext(context.Background())
+	}()
+
 	return t.ExecProgram(params.Program, params.Globals), t
 }
+
+// ExecuteProgramContext executes a program like ExecuteProgram, but stops it
+// when ctx is cancelled, and returns errors instead of panicking. A
+// cancelled program returns a *CancelledError. The program's context is
+// cancelled when it returns, which stops any generators it left paused.
+func ExecuteProgramContext(ctx context.Context, params *OwlParams) (*OwlObj, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := NewTreeExecutor(params.Path)
+	t.SetContext(ctx)
+
+	var result *OwlObj
+	err := t.Try(func() { result = t.ExecProgram(params.Program, params.Globals) })
+
+	return result, err
+}
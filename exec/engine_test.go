@@ -1,8 +1,13 @@
 package exec
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	osexec "os/exec"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestEngine(t *testing.T) {
@@ -27,3 +32,84 @@ func TestEngine(t *testing.T) {
 		t.Errorf("Expected result to be \"Hello, world!\", got %v", result.TrueStr())
 	}
 }
+
+func TestExecuteProgramContext(t *testing.T) {
+	tests := []struct {
+		input string
+		sleep bool
+	}{
+		{"while (true) {\n}", false},
+		{"for x in [1, 2, 3] {\n    while (true) {\n    }\n}", false},
+		{"f = () => {\n    x = 1\n}\nwhile (true) {\n    f()\n}", false},
+		{"import \"os\"\nos.Exec(\"sleep\", \"5\")", true},
+		{"import \"time\"\ntime.Sleep(5 * time.second)", false},
+		{"x = [...range(0, null)]", false},
+		{"x = list(range(0, null))", false},
+	}
+
+	for _, tt := range tests {
+		if _, err := osexec.LookPath("sleep"); tt.sleep && err != nil {
+			continue
+		}
+
+		params, errs := LoadProgram(tt.input, "test.hoot")
+		if len(errs) > 0 {
+			t.Fatalf("Failed to parse %q: %v", tt.input, errs)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		_, err := ExecuteProgramContext(ctx, params)
+		cancel()
+
+		var cancelled *CancelledError
+		if !errors.As(err, &cancelled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%q: expected a cancellation error, got %v", tt.input, err)
+		}
+
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("%q: took %v to stop", tt.input, time.Since(start))
+		}
+	}
+
+	params, _ := LoadProgram("return 1 + 2", "test.hoot")
+	result, err := ExecuteProgramContext(context.Background(), params)
+	if err != nil || result.TrueStr() != "3" {
+		t.Errorf("Expected 3, got %v, %v", result, err)
+	}
+}
+
+// TestConcurrentPrograms runs programs at the same time, for the race
+// detector to find state they share.
+func TestConcurrentPrograms(t *testing.T) {
+	src := "s = 'abc'\nn = 0\nfor i in [1, 2, 3] {\n    n += i * 1.5\n}\nreturn [s, n, true, 'a']::str()"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			params, _ := LoadProgram(src, "test.hoot")
+			result, err := ExecuteProgramContext(context.Background(), params)
+			if err != nil || result.TrueStr() != "[abc, 9, true, a]" {
+				t.Errorf("Expected [abc, 9, true, a], got %v, %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBuiltinsNotShared(t *testing.T) {
+	params, _ := LoadProgram("Done.x = 1\nreturn Done.x", "test.hoot")
+	result, err := ExecuteProgramContext(context.Background(), params)
+	if err != nil || result.TrueStr() != "1" {
+		t.Fatalf("Expected 1, got %v, %v", result, err)
+	}
+
+	params, _ = LoadProgram("return [Done has 'x', str(Done)]::str()", "test.hoot")
+	result, err = ExecuteProgramContext(context.Background(), params)
+	if err != nil || result.TrueStr() != "[false, Done]" {
+		t.Errorf("Expected [false, Done], got %v, %v", result, err)
+	}
+}
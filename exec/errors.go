@@ -0,0 +1,115 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AnthonyEdvalson/owl/lexer"
+)
+
+// RuntimeError is panicked by the executor when a program fails. Token is
+// the token of the node that was being executed.
+type RuntimeError struct {
+	Message string
+	Token   lexer.Token
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Token.File, e.Token.Line, e.Token.Column, e.Message)
+}
+
+// CancelledError is panicked when the executor's context is cancelled or
+// its deadline passes. It wraps the context's error, so errors.Is can be
+// used to tell the two apart.
+type CancelledError struct {
+	Err   error
+	Token lexer.Token
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: execution cancelled: %s", e.Token.File, e.Token.Line, e.Token.Column, e.Err)
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// checkContext panics with a CancelledError if t's context is done, or with
+// a LimitError if it is done because the policy's time limit passed. It is
+// called on each iteration of a loop and each function call, which is
+// enough to stop any program that does not block in Go code.
+func (t *TreeExecutor) checkContext(token lexer.Token) {
+	done := t.run.ctx.Done()
+	if done == nil {
+		return
+	}
+
+	select {
+	case <-done:
+		if b := t.run.budget; b != nil && b.timedOut() {
+			panic(&LimitError{LIMIT_DURATION, token})
+		}
+		panic(&CancelledError{t.run.ctx.Err(), token})
+	default:
+	}
+}
+
+// SetContext sets the context the executor runs under. When it is
+// cancelled, the program stops with a CancelledError, and bridge calls such
+// as os.Exec are interrupted.
+func (t *TreeExecutor) SetContext(ctx context.Context) {
+	t.setDeadline(ctx)
+}
+
+// Context returns the context the executor runs under.
+func (t *TreeExecutor) Context() context.Context {
+	return t.run.ctx
+}
+
+// Try runs f, and returns the error it panicked with, if any. Runtime errors
+// are returned as is, anything else is wrapped in an error.
+func Try(f func()) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		switch r := r.(type) {
+		case *RuntimeError:
+			err = r
+		case error:
+			err = r
+		default:
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	f()
+
+	return nil
+}
+
+// Try runs f like the Try function. If f fails part way through an Owl
+// function, t's stack is unwound back to where it was when Try was called,
+// so that t can continue to be used.
+func (t *TreeExecutor) Try(f func()) error {
+	frames, calls := len(t.Frames), len(t.calls)
+
+	depth := 0
+	if t.run.budget != nil {
+		depth = t.run.budget.depth
+	}
+
+	err := Try(f)
+	if err != nil {
+		t.Frames = t.Frames[:frames]
+		t.calls = t.calls[:calls]
+
+		if t.run.budget != nil {
+			t.run.budget.depth = depth
+		}
+	}
+
+	return err
+}
@@ -1,28 +1,122 @@
 package exec
 
 import (
+	"context"
 	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 
+	"github.com/AnthonyEdvalson/owl/decimal"
 	"github.com/AnthonyEdvalson/owl/lexer"
 	"github.com/AnthonyEdvalson/owl/parser"
 )
 
 type Frame map[string]*OwlObj
 
+// Debugger is notified before the executor runs each statement. The token
+// is the first token of the statement, and can be used to find its source
+// location.
+type Debugger interface {
+	OnStatement(t *TreeExecutor, token lexer.Token)
+}
+
+// Profiler is notified before each statement, and when the executor enters
+// and leaves an Owl function.
+type Profiler interface {
+	OnStatement(t *TreeExecutor, token lexer.Token)
+	OnCall(t *TreeExecutor, fn *FuncData)
+	OnReturn(t *TreeExecutor, fn *FuncData)
+}
+
 type TreeExecutor struct {
 	Frames      []Frame
+	Debugger    Debugger
+	Profiler    Profiler
+	Tests       *TestSuite
+	Policy      *Policy
 	currentPath string
+	calls       []*FuncData
+	run         *runState
+	exports     []string
+	gen         *generator
+}
+
+// runState is shared by an executor and the executors of the modules it
+// imports, so that they are cancelled and limited as a single program, and
+// import each module once. builtins are the variables every file can use
+// without defining them. clock is read by the time module, the system clock
+// is used if it is nil. call is the token of the call being evaluated, which
+// builtins use to report where they were stopped.
+type runState struct {
+	ctx      context.Context
+	budget   *budget
+	modules  *registry
+	builtins Frame
+	clock    Clock
+	call     lexer.Token
 }
 
 func NewTreeExecutor(path string) *TreeExecutor {
 	t := &TreeExecutor{
 		Frames:      []Frame{},
 		currentPath: path,
+		run:         &runState{ctx: context.Background(), modules: newRegistry(path)},
 	}
+	t.run.builtins = newBuiltins(t)
 
 	return t
 }
 
+// child creates an executor for a module imported by t. It shares t's
+// debugger and profiler so that code in modules can be stepped through and
+// measured, and its test suite so that modules can register tests. Modules
+// are bound by t's policy, count towards the same limits, and are cancelled
+// along with t.
+func (t *TreeExecutor) child(path string) *TreeExecutor {
+	c := NewTreeExecutor(path)
+	c.Debugger = t.Debugger
+	c.Profiler = t.Profiler
+	c.Tests = t.Tests
+	c.Policy = t.Policy
+	c.run = t.run
+
+	return c
+}
+
+// newBuiltins creates the builtins of a program. Each program gets its own
+// copy, so that changes a program makes to them do not affect others. The
+// builtins that iterate are stopped with t when it is cancelled or out of
+// budget.
+func newBuiltins(t *TreeExecutor) Frame {
+	return Frame{
+		"Object": ObjectLibExport(),
+		"Done":   NewDone(),
+		"len":    newUnaryBuiltin("len", builtinLen),
+		"hash":   newUnaryBuiltin("hash", builtinHash),
+		"iter":   newUnaryBuiltin("iter", builtinIter),
+		"range":  NewCallBridge(builtinRange),
+		"typeof": newUnaryBuiltin("typeof", builtinTypeof),
+		"int":    newUnaryBuiltin("int", builtinInt),
+		"float":  newUnaryBuiltin("float", builtinFloat),
+		"str":    newUnaryBuiltin("str", builtinStr),
+		"bool":   newUnaryBuiltin("bool", builtinBool),
+		"list":   newUnaryBuiltin("list", t.builtinList),
+	}
+}
+
+// Builtins returns the names of the builtins every program starts with,
+// sorted.
+func Builtins() []string {
+	names := []string{}
+	for name := range NewTreeExecutor("").run.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 type RunState struct {
 	State  int
 	Return *OwlObj
@@ -39,6 +133,74 @@ func (t *TreeExecutor) set(name string, value *OwlObj) {
 	(*t.bottomFrame())[name] = value
 }
 
+// evalTarget evaluates expr, the target of an attribute assignment. A shared
+// value, such as a small number, is replaced by a copy, which is stored back
+// where it was read from, so that setting an attribute on it does not affect
+// other uses of the value. Each part of expr is evaluated only once. If
+// coalesce is set, a null value is returned as is, since nothing will be set
+// on it.
+func (t *TreeExecutor) evalTarget(expr parser.Expression, token lexer.Token, coalesce bool) *OwlObj {
+	switch e := expr.(type) {
+	case *parser.Name:
+		value := t.evalName(e)
+		if !value.shared || coalesce && value.IsNullish() {
+			return value
+		}
+
+		value = value.unshared()
+		for i := len(t.Frames) - 1; i >= 0; i-- {
+			if _, ok := t.Frames[i][e.Name]; ok {
+				t.Frames[i][e.Name] = value
+				return value
+			}
+		}
+
+		t.set(e.Name, value)
+
+		return value
+	case *parser.Attribute:
+		if e.IsCoalesce {
+			break
+		}
+
+		holder := t.evalTarget(e.Target, token, false)
+		value := t.attribute(holder, e)
+		if !value.shared || coalesce && value.IsNullish() {
+			return value
+		}
+
+		value = value.unshared()
+		if e.IsDeep {
+			holder.SetDeepAttr(e.Attribute, value)
+		} else {
+			holder.SetAttr(e.Attribute, value)
+		}
+
+		return value
+	case *parser.Index:
+		holder := t.EvalExpression(e.Target)
+		index := t.EvalExpression(e.Index)
+		value := t.index(holder, index, e)
+		if !value.shared || coalesce && value.IsNullish() {
+			return value
+		}
+
+		value = value.unshared()
+		if _, ok := holder.SetIndex(index, value); !ok {
+			t.panic("Unable to set an attribute on "+typeName(value)+" '"+expr.ToString()+"', "+e.Target.ToString()+" can not be changed", token)
+		}
+
+		return value
+	}
+
+	value := t.EvalExpression(expr)
+	if value.shared && !(coalesce && value.IsNullish()) {
+		t.panic("Unable to set an attribute on "+typeName(value)+" '"+expr.ToString()+"', assign it to a variable first", token)
+	}
+
+	return value
+}
+
 func (t *TreeExecutor) get(name string, token lexer.Token) *OwlObj {
 	for i := len(t.Frames) - 1; i >= 0; i-- {
 		f := t.Frames[i]
@@ -47,12 +209,16 @@ func (t *TreeExecutor) get(name string, token lexer.Token) *OwlObj {
 		}
 	}
 
+	if value, ok := t.run.builtins[name]; ok {
+		return value
+	}
+
 	t.panic("Unable to find variable '"+name+"'", token)
 	return nil
 }
 
 func (t *TreeExecutor) panic(msg string, token lexer.Token) {
-	panic(fmt.Sprintf("%s:%d:%d:  %s\r\n", token.File, token.Line, token.Column, msg))
+	panic(&RuntimeError{msg, token})
 }
 
 func (t *TreeExecutor) resetStack() {
@@ -77,12 +243,35 @@ func (t *TreeExecutor) popFrame() {
 	t.Frames = t.Frames[:len(t.Frames)-1]
 }
 
+// CallDepth returns the number of Owl functions currently being executed.
+func (t *TreeExecutor) CallDepth() int {
+	return len(t.calls)
+}
+
+// CallStack returns the tokens of the function definitions currently being
+// executed, outermost first.
+func (t *TreeExecutor) CallStack() []lexer.Token {
+	stack := make([]lexer.Token, len(t.calls))
+
+	for i, f := range t.calls {
+		stack[i] = f.Token
+	}
+
+	return stack
+}
+
+// Path returns the path of the file being executed.
+func (t *TreeExecutor) Path() string {
+	return t.currentPath
+}
+
 func (t *TreeExecutor) Assign(assign parser.Assign, value *OwlObj) {
 	switch a := assign.(type) {
 	case *parser.AssignName:
+		nameFunc(value, a.Name)
 		t.set(a.Name, value)
 	case *parser.AssignList:
-		values, ok := value.AsList()
+		values, ok := t.asList(value, a.Token())
 		if !ok {
 			values = []*OwlObj{value}
 		}
@@ -123,23 +312,34 @@ func (t *TreeExecutor) Assign(assign parser.Assign, value *OwlObj) {
 			t.Assign(part, values[len(values)-len(afterSpread)+i])
 		}
 	case *parser.AssignAttribute:
-		target := t.EvalExpression(a.Target)
+		target := t.evalTarget(a.Target, a.Token(), a.IsCoalesce)
 		if a.IsCoalesce && target.IsNullish() {
 			break
 		}
+		nameFunc(value, a.Attribute)
 		if a.IsDeep {
 			target.SetDeepAttr(a.Attribute, value)
+		} else if hook, ok := target.GetDeepAttr("setattr"); ok {
+			if _, ok := hook.Call(NewList([]*OwlObj{NewString(a.Attribute), value})); !ok {
+				t.panic("Unable to set attribute '"+a.ToString()+"'", a.Token())
+			}
 		} else {
 			target.SetAttr(a.Attribute, value)
 		}
 	case *parser.AssignIndex:
 		target := t.getFromAssign(a.Target)
 		index := t.EvalExpression(a.Index)
-		target.SetIndex(index, value)
+		if val, ok := target.SetIndex(index, value); !ok {
+			msg := "Unable to set index '" + a.ToString() + "'"
+			if val != nil {
+				msg += ", " + val.TrueStr()
+			}
+			t.panic(msg, a.Token())
+		}
 	/*TODO case *parser.AssignMap:*/
 	case *parser.AssignSpread:
 		// Convert value to list if it isn't already
-		_, isList := value.AsList()
+		_, isList := t.asList(value, a.Token())
 		if isList {
 			t.Assign(a.Target, value)
 		} else {
@@ -186,6 +386,7 @@ func (t *TreeExecutor) getFromAssign(assign parser.Assign) *OwlObj {
 
 func (t *TreeExecutor) ExecProgram(program *parser.Program, globals map[string]*OwlObj) *OwlObj {
 	t.resetStack()
+	t.exports = nil
 	for k, v := range globals {
 		t.set(k, v)
 	}
@@ -196,6 +397,18 @@ func (t *TreeExecutor) ExecProgram(program *parser.Program, globals map[string]*
 
 func (t *TreeExecutor) ExecBlock(block []parser.Statement) RunState {
 	for _, stmt := range block {
+		if t.Debugger != nil {
+			t.Debugger.OnStatement(t, stmt.Token())
+		}
+
+		if t.Profiler != nil {
+			t.Profiler.OnStatement(t, stmt.Token())
+		}
+
+		if t.Policy != nil {
+			t.step(stmt.Token())
+		}
+
 		s := t.execStatement(stmt)
 
 		if s.State != RUN {
@@ -229,6 +442,8 @@ func (t *TreeExecutor) execStatement(stmt parser.Statement) RunState {
 		return RunState{CONTINUE, nil}
 	case *parser.Import:
 		return t.execImportStatement(stmt)
+	case *parser.Export:
+		return t.execExportStatement(stmt)
 	case *parser.Print:
 		return t.execPrintStatement(stmt)
 	default:
@@ -247,30 +462,60 @@ func (t *TreeExecutor) execReturnStatement(stmt *parser.Return) *OwlObj {
 	return v
 }
 
-func (t *TreeExecutor) execForStatement(f *parser.For) RunState {
-	iter := t.EvalExpression(f.Iter)
+// iterate calls fn with each value of o like Iterate, checking t's context
+// and limits before each value, so that iterating an endless source such as
+// range(0, null) can be stopped.
+func (t *TreeExecutor) iterate(o *OwlObj, token lexer.Token, fn func(v *OwlObj) bool) bool {
+	return Iterate(o, func(v *OwlObj) bool {
+		t.checkContext(token)
+
+		if t.Policy != nil {
+			t.step(token)
+		}
 
-	list, ok := iter.AsList()
+		return fn(v)
+	})
+}
 
-	if !ok {
-		t.panic("For loop iter is not a list", f.Token())
+// asList is AsList, iterating with t.iterate.
+func (t *TreeExecutor) asList(o *OwlObj, token lexer.Token) ([]*OwlObj, bool) {
+	if list, ok := o.TrueList(); ok {
+		return list, true
 	}
 
-	for _, item := range list {
+	list := []*OwlObj{}
+	ok := t.iterate(o, token, func(v *OwlObj) bool {
+		list = append(list, v)
+		return true
+	})
+
+	return list, ok
+}
+
+func (t *TreeExecutor) execForStatement(f *parser.For) RunState {
+	iter := t.EvalExpression(f.Iter)
+	result := RunState{RUN, nil}
+
+	ok := t.iterate(iter, f.Token(), func(item *OwlObj) bool {
 		t.Assign(f.Target, item)
 		state := t.ExecBlock(f.Body)
 
 		switch state.State {
-		case CONTINUE:
-			continue
 		case BREAK:
-			return RunState{RUN, nil} // TODO add broken block? Similar to Python for else
+			return false // TODO add broken block? Similar to Python for else
 		case RETURN:
-			return state
+			result = state
+			return false
 		}
+
+		return true
+	})
+
+	if !ok {
+		t.panic("For loop iter is not iterable", f.Token())
 	}
 
-	return RunState{RUN, nil}
+	return result
 }
 
 func (t *TreeExecutor) execIfStatement(i *parser.If) RunState {
@@ -285,6 +530,12 @@ func (t *TreeExecutor) execIfStatement(i *parser.If) RunState {
 
 func (t *TreeExecutor) execWhileStatement(w *parser.While) RunState {
 	for t.EvalExpression(w.Test).IsTruthy() {
+		t.checkContext(w.Token())
+
+		if t.Policy != nil {
+			t.step(w.Token())
+		}
+
 		state := t.ExecBlock(w.Body)
 
 		switch state.State {
@@ -305,16 +556,64 @@ func (t *TreeExecutor) execWhileStatement(w *parser.While) RunState {
 //  1. A relative path starting with ./ or ../ will be resolved relative to the
 //     current file.
 //  2. An absolute path (starting with /) will be resolved to the given path.
-//  3. A name beginning with no slashes or dots will be resolved to the
-//     standard library.
+//  3. A name beginning with no slashes or dots is a Go module, a dependency
+//     declared in the project's owl.json, or is searched for in the lib
+//     directories of the current file's directory and its
+//     parents, then the directories in OWL_PATH, then the standard library.
+//
+// The module is assigned to the last part of its name, or to the name given
+// with `as`.
 func (t *TreeExecutor) execImportStatement(i *parser.Import) RunState {
-	module, alias := NewModule(i.Name, t.currentPath)
+	if msg, ok := t.checkImport(i.Name); !ok {
+		t.panic(msg, i.Token())
+	}
+
+	module, alias, err := NewModule(i.Name, t)
+	if err != nil {
+		t.panic(err.Error(), i.Token())
+	}
+
+	if len(i.Names) > 0 {
+		for _, n := range i.Names {
+			value, ok := module.Attr[n.Name]
+			if !ok {
+				t.panic("Module '"+i.Name+"' does not export '"+n.Name+"'", i.Token())
+			}
+
+			t.set(n.Alias, value)
+		}
+
+		return RunState{RUN, nil}
+	}
+
+	if i.Alias != "" {
+		alias = i.Alias
+	}
 
 	t.set(alias, module)
 
 	return RunState{RUN, nil}
 }
 
+// execExportStatement executes an export AST node. Once a module exports a
+// name, only its exported names are visible to files that import it.
+func (t *TreeExecutor) execExportStatement(e *parser.Export) RunState {
+	if len(t.Frames) > 1 {
+		t.panic("export can only be used at the top level of a module", e.Token())
+	}
+
+	if e.Value != nil {
+		t.execStatement(e.Value)
+	}
+
+	for _, name := range e.Names {
+		t.get(name, e.Token())
+		t.exports = append(t.exports, name)
+	}
+
+	return RunState{RUN, nil}
+}
+
 func (t *TreeExecutor) execPrintStatement(i *parser.Print) RunState {
 	v := t.EvalExpression(i.Value)
 
@@ -332,6 +631,8 @@ func (t *TreeExecutor) EvalExpression(expr parser.Expression) *OwlObj {
 		return t.evalBinOp(expr)
 	case *parser.UnaryOp:
 		return t.evalUnaryOp(expr)
+	case *parser.Yield:
+		return t.evalYield(expr)
 	case *parser.FunctionDef:
 		return t.evalFunctionDef(expr)
 	case *parser.Overload:
@@ -368,16 +669,22 @@ func (t *TreeExecutor) EvalExpression(expr parser.Expression) *OwlObj {
 	return nil
 }
 
+// evalConst creates the value of a constant. Small numbers, short strings
+// and bools are shared values, so most constants do not allocate.
 func (t *TreeExecutor) evalConst(c *parser.Const) *OwlObj {
-	switch c.Value.(type) {
+	switch v := c.Value.(type) {
 	case string:
-		return NewString(c.Value.(string))
+		return NewString(v)
 	case int64:
-		return NewInt(c.Value.(int64))
+		return NewInt(v)
 	case float64:
-		return NewFloat(c.Value.(float64))
+		return NewFloat(v)
+	case *big.Int:
+		return NewBigInt(v)
+	case decimal.Decimal:
+		return NewDecimal(v)
 	case bool:
-		return NewBool(c.Value.(bool))
+		return NewBool(v)
 	}
 
 	t.panic("Unable to evaluate constant '"+c.ToString()+"'", c.Token())
@@ -399,7 +706,7 @@ func (t *TreeExecutor) evalCommaOp(c *parser.List) *OwlObj {
 		spread, isSpread := expr.(*parser.Spread)
 		if isSpread {
 			value := t.EvalExpression(spread.Target)
-			valueList, ok := value.AsList()
+			valueList, ok := t.asList(value, spread.Token())
 			if !ok {
 				t.panic("Spread value is not a list", spread.Token())
 			}
@@ -416,25 +723,11 @@ func (t *TreeExecutor) evalAssignExpression(a *parser.AssignExpression) *OwlObj
 	val := t.EvalExpression(a.Value)
 	ok := false
 
-	// TODO: this can break if RHS has not implemented the operation
-	// Would be better for the parser to replace a += b with a = a + b, then it can use BinOp implementation.
 	switch a.Op {
 	case "=":
 		ok = true
-	case "+=":
-		val, ok = t.getFromAssign(a.Target).Add(t.getFromAssign(a.Target), val)
-	case "-=":
-		val, ok = t.getFromAssign(a.Target).Sub(t.getFromAssign(a.Target), val)
-	case "*=":
-		val, ok = t.getFromAssign(a.Target).Mul(t.getFromAssign(a.Target), val)
-	case "/=":
-		val, ok = t.getFromAssign(a.Target).Div(t.getFromAssign(a.Target), val)
-	case "%=":
-		val, ok = t.getFromAssign(a.Target).Mod(t.getFromAssign(a.Target), val)
-	case "&=":
-		val, ok = t.getFromAssign(a.Target).And(t.getFromAssign(a.Target), val)
-	case "|=":
-		val, ok = t.getFromAssign(a.Target).Or(t.getFromAssign(a.Target), val)
+	default:
+		val, ok = BinaryOp(strings.TrimSuffix(a.Op, "="), t.getFromAssign(a.Target), val)
 	}
 
 	if !ok {
@@ -463,62 +756,14 @@ func (t *TreeExecutor) evalBinOp(b *parser.BinOp) *OwlObj {
 	} else {
 		right := t.EvalExpression(b.Right)
 
-		switch b.Op {
-		case "+":
-			val, ok = left.Add(left, right)
-			if !ok {
-				val, ok = right.Add(left, right)
-			}
-		case "-":
-			val, ok = left.Sub(left, right)
-		case "*":
-			val, ok = left.Mul(left, right)
-			if !ok {
-				val, ok = right.Mul(left, right)
-			}
-		case "/":
-			val, ok = left.Div(left, right)
-		case "**":
-			val, ok = left.Pow(left, right)
-		case "%":
-			val, ok = left.Mod(left, right)
-		case "==":
-			val, ok = left.Eq(left, right)
-			if !ok {
-				val, ok = right.Eq(left, right)
-			}
-		case "!=":
-			val, ok = left.Ne(left, right)
-			if !ok {
-				val, ok = right.Ne(left, right)
-			}
-		case ">":
-			val, ok = left.Gt(left, right)
-			if !ok {
-				val, ok = right.Le(left, right)
-			}
-		case "<":
-			val, ok = left.Lt(left, right)
-			if !ok {
-				val, ok = right.Ge(left, right)
-			}
-		case ">=":
-			val, ok = left.Ge(left, right)
-			if !ok {
-				val, ok = right.Lt(left, right)
-			}
-		case "<=":
-			val, ok = left.Le(left, right)
-			if !ok {
-				val, ok = right.Gt(left, right)
-			}
-		case "has":
+		if b.Op == "has" {
 			val, ok = left.Has(right)
-		default:
+		} else if _, known := binaryOps[b.Op]; known {
+			val, ok = BinaryOp(b.Op, left, right)
+		} else {
 			t.panic("Unknown binary operator '"+b.ToString()+"'", b.Token())
 			return nil
 		}
-
 	}
 
 	if !ok {
@@ -572,7 +817,11 @@ func (t *TreeExecutor) evalIncDec(i *parser.IncDec) *OwlObj {
 }
 
 func (t *TreeExecutor) evalAttribute(a *parser.Attribute) *OwlObj {
-	target := t.EvalExpression(a.Target)
+	return t.attribute(t.EvalExpression(a.Target), a)
+}
+
+// attribute looks up the attribute a on target.
+func (t *TreeExecutor) attribute(target *OwlObj, a *parser.Attribute) *OwlObj {
 	var val *OwlObj
 	var ok bool
 
@@ -584,6 +833,10 @@ func (t *TreeExecutor) evalAttribute(a *parser.Attribute) *OwlObj {
 			val, ok = target.GetDeepAttr(a.Attribute)
 		} else {
 			val, ok = target.GetAttr(a.Attribute)
+
+			if !ok {
+				val, ok = target.DeepCall("getattr", NewString(a.Attribute))
+			}
 		}
 	}
 
@@ -595,8 +848,11 @@ func (t *TreeExecutor) evalAttribute(a *parser.Attribute) *OwlObj {
 }
 
 func (t *TreeExecutor) evalIndex(i *parser.Index) *OwlObj {
-	target := t.EvalExpression(i.Target)
-	index := t.EvalExpression(i.Index)
+	return t.index(t.EvalExpression(i.Target), t.EvalExpression(i.Index), i)
+}
+
+// index looks up index in target, where i is the expression being evaluated.
+func (t *TreeExecutor) index(target, index *OwlObj, i *parser.Index) *OwlObj {
 	val, ok := target.Index(index)
 
 	if !ok {
@@ -645,9 +901,25 @@ func (t *TreeExecutor) evalFunctionCall(c *parser.FunctionCall) *OwlObj {
 		return fn
 	}
 	arg := t.EvalExpression(c.Arg)
-	val, ok := fn.Call(arg)
+
+	outer := t.run.call
+	t.run.call = c.Token()
+	val, ok := callCounted(fn, arg, c.ArgCount())
+	t.run.call = outer
 
 	if !ok {
+		// Bridges fail when they are interrupted, report that as a
+		// cancellation rather than as an error in the call
+		t.checkContext(c.Token())
+
+		if val == nil {
+			t.panic("Unable to evaluate function call '"+c.ToString()+"'", c.Token())
+		}
+
+		if _, isAssert := val.Raw.(*AssertionError); isAssert {
+			t.panic(val.TrueStr(), c.Token())
+		}
+
 		msg := val.TrueStr()
 		t.panic("Unable to evaluate function call '"+c.ToString()+"', "+msg, c.Token())
 	}
@@ -660,6 +932,7 @@ func (t *TreeExecutor) evalMap(m *parser.Map) *OwlObj {
 
 	for i, attr := range m.Keys {
 		val := t.EvalExpression(m.Values[i])
+		nameFunc(val, attr)
 		o.SetAttr(attr, val)
 	}
 
@@ -1,9 +1,13 @@
 package exec
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/AnthonyEdvalson/owl/lexer"
 	"github.com/AnthonyEdvalson/owl/parser"
@@ -257,6 +261,36 @@ func TestStringExpression(t *testing.T) {
 	}
 }
 
+func TestStringMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s = 'héllo wörld'\nreturn [s.Len(), len(s), s[1], s[-1], s[1:4], s[7:], s[-5:-3], s[4:2], s[:100]]::str()", "[11, 11, é, d, éll, örld, wö, , héllo wörld]"},
+		{"return [...'añb'].Join('-') + ' ' + 'añb'.Index('b')::str() + ' ' + 'añb'.ReIndex('b')::str()", "a-ñ-b 2 [2, 3]"},
+		{"return ['ñandú'.Upper(), 'ÀB'.Lower(), 'éLAN vital'.Title()]::str()", "[ÑANDÚ, àb, Élan Vital]"},
+		{"s = 'naïve'\nreturn [s.StartsWith('na'), s.EndsWith('ïve'), s.Contains('ï'), s.Contains('x')]::str()", "[true, true, true, false]"},
+		{"return ['ab'.Repeat(3), 'é' * 2, 3 * '-', 'x' * 0]::str()", "[ababab, éé, ---, ]"},
+		{"return ['é'.PadLeft(3), 'é'.PadRight(3, '·') + '|', 'long'.PadLeft(2)]::str()", "[  é, é··|, long]"},
+		{"return ['a\\r\\nb\\n\\nc\\n'.Lines(), ''.Lines(), ' a \\t b\\n'.Fields()]::str()", "[[a, b, , c], [], [a, b]]"},
+		{"return '%s has %d items costing %.2f, %5s|%-3d|%x|%c|%v %%'.Format('ñ', 3, 2.5d, 'ab', 7, 255, 233, [1])", "ñ has 3 items costing 2.50,    ab|7  |ff|é|[1] %"},
+		{"return [' 42 '.ToInt(), 'ff'.ToInt(16), '0b101'.ToInt(0), '-99999999999999999999'.ToInt(), '1.5e2'.ToFloat(), '-0.25'.ToFloat()]::str()", "[42, 255, 5, -99999999999999999999, 150, -0.25]"},
+		{"return ['é'.Bytes(), 'a'.Bytes()]::str()", "[[195, 169], [97]]"},
+		{"return ['  x '.Trim(), '--x-'.Trim('-')]::str()", "[x, x]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, input := range []string{"'é'[1]", "'abc'['a']", "'abc'.ToInt()", "'1.5'.ToInt()", "'x'.ToFloat()", "'10'.ToInt(1)", "'a' * -1", "'a' * 1.5", "'a'.PadLeft(3, 'ab')", "'%d'.Format('x')", "'%d %d'.Format(1)", "'%d'.Format(1, 2)", "'%y'.Format(1)", "'%'.Format()"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, input)); err == nil {
+			t.Errorf("Expected %s to fail", input)
+		}
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -498,6 +532,218 @@ func TestDeepAttribute(t *testing.T) {
 	}
 }
 
+func TestPrototype(t *testing.T) {
+	animal := "let Animal = {init: (name) => {\n this.name = name\n return this\n}, speak: () => this.name + ' speaks'}\n"
+	dog := animal + "let Dog = Object.Extend(Animal, {speak: () => super.speak() + ', woof'})\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{animal + "a = Object.Extend(Animal).init('a')\nreturn a.speak()", "a speaks"},
+		{dog + "return Object.Extend(Dog).init('rex').speak()", "rex speaks, woof"},
+		{dog + "p = Object.Extend(Dog, {speak: () => super.speak() + '!'})\nreturn Object.Extend(p).init('bit').speak()", "bit speaks, woof!"},
+		{dog + "d = Object.Extend(Dog).init('rex')\nreturn [Object.IsA(d, Animal), Object.IsA(Dog, d), d has 'speak', Animal has 'name']::str()", "[true, false, true, false]"},
+		{dog + "d = Object.Extend(Dog, {name: 'own'})\nd.speak = () => 'shadowed'\nreturn d.speak() + ' ' + Object.Extend(Dog, {name: 'x'}).speak()", "shadowed x speaks, woof"},
+		{"let V = {}\nV::add = (a, b) => Object.Extend(V, {x: a.x + b.x})\nV::str = () => 'V' + this.x\nv = Object.Extend(V, {x: 2})\nreturn (v + v)::str()", "V4"},
+		{"return (Object.Proto(Object.Extend(null)) ?? 'none')::str()", "none"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	vec := "let V = {}\nV::add = (a, b) => Object.Extend(V, {x: a.x + b.x})\nV::radd = (a, b) => Object.Extend(V, {x: a + b.x})\nV::rsub = (a, b) => Object.Extend(V, {x: a - b.x})\nV::lt = (a, b) => a.x < b.x\nV::eq = (a, b) => a.x == b.x\nV::str = () => 'V' + this.x\nv = Object.Extend(V, {x: 2})\nw = Object.Extend(V, {x: 5})\n"
+	count := "let c = {i: 0}\nc::next = () => {\n if this.i >= 3 {\n  return Done\n }\n this.i += 1\n return this.i\n}\n"
+	rng := "let R = {n: 2}\nR::iter = () => {\n it = {i: 0, n: this.n}\n it::next = () => {\n  if this.i >= this.n {\n   return Done\n  }\n  this.i += 1\n  return this.i * 10\n }\n return it\n}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{vec + "return [v + w, 1 + v, 10 - v]::str()", "[V7, V3, V8]"},
+		{vec + "x = 1\nx += v\nreturn x::str()", "V3"},
+		{vec + "return [v < w, w > v, v == w, v != w, v == Object.Extend(V, {x: 2})]::str()", "[true, true, false, true, true]"},
+		{"o = {}\nreturn [o == o, o == {}, o != {}]::str()", "[true, false, true]"},
+		{"c = {n: 3}\nc::call = (x) => this.n * x\nreturn c(4)::str()", "12"},
+		{"L = {}\nL::len = () => 42\nreturn [len(L), len([1, 2, 3]), len('abc'), len({a: 1})]::str()", "[42, 3, 3, 1]"},
+		{"H = {}\nH::hash = () => 7\nreturn [hash(1) == hash(1.0), hash('a') == hash('a'), hash('a') == hash('b'), hash(H)]::str()", "[true, true, false, 7]"},
+		{count + "s = 0\nfor x in c {\n s += x\n}\nreturn s::str()", "6"},
+		{count + "for x in c {\n if x == 2 {\n  return 'found ' + x\n }\n}\nreturn 'none'", "found 2"},
+		{rng + "s = []\nfor x in R {\n s.Add(x)\n}\nreturn [s, [...R]]::str()", "[[10, 20], [10, 20]]"},
+		{"g = {a: 1}\ng::getattr = (name) => 'dynamic ' + name\nreturn g.foo + ', ' + g.a", "dynamic foo, 1"},
+		{"s = {log: []}\ns::setattr = (name, value) => {\n this.log.Add(name)\n Object.Set(this, name, value * 2)\n}\ns.a = 5\nreturn [s.a, s.log]::str()", "[10, [a]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	if _, ok := Hash(NewOwlObj()); ok {
+		t.Errorf("Expected an object without ::hash to be unhashable")
+	}
+
+	if _, ok := Len(NewInt(1)); ok {
+		t.Errorf("Expected a number to have no length")
+	}
+}
+
+func TestIterators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return [[...range(4)], [...range(2, 5)], [...range(10, 0, -3)], [...range(3, 3)]]::str()", "[[0, 1, 2, 3], [2, 3, 4], [10, 7, 4, 1], []]"},
+		{"return range(0, null, 2)::str()", "range(0, null, 2)"},
+		{"s = []\nfor x in range(1, null).Map((x) => x * x).Filter((x) => x % 2 == 0).Take(3) {\n s.Add(x)\n}\nreturn s::str()", "[4, 16, 36]"},
+		{"s = []\nfor x in range(0, null) {\n if x == 3 {\n  break\n }\n s.Add(x)\n}\nreturn s::str()", "[0, 1, 2]"},
+		{"s = []\nfor i, x in iter(['a', 'b']).Enumerate(1) {\n s.Add(x + i)\n}\nreturn s::str()", "[a1, b2]"},
+		{"return [...range(3).Zip(['a', 'b', 'c', 'd'], range(10, null))]::str()", "[[0, a, 10], [1, b, 11], [2, c, 12]]"},
+		{"return [...iter([1, 2, 3]).Enumerate().Map((i, x) => i * x)]::str()", "[0, 2, 6]"},
+		{"r = range(3)\nreturn [[...r], [...r]]::str()", "[[0, 1, 2], []]"},
+		{"calls = []\nr = range(5).Map((x) => {\n calls.Add(x)\n return x\n}).Take(2)\nbefore = len(calls)\nreturn [before, [...r], calls]::str()", "[0, [0, 1], [0, 1]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, args := range [][]*OwlObj{{}, {NewInt(0), NewInt(1), NewInt(0)}, {NewString("a")}} {
+		if _, ok := builtinRange(append([]*OwlObj{NewNull()}, args...)); ok {
+			t.Errorf("Expected range%v to fail", args)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	count := "let count = (n) => {\n i = 0\n while i < n {\n  sent = yield i\n  i += sent ?? 1\n }\n return 'ignored'\n}\n"
+	fib := "let fib = () => {\n a, b = 0, 1\n while true {\n  yield a\n  a, b = b, a + b\n }\n}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{count + "return [...count(4)]::str()", "[0, 1, 2, 3]"},
+		{count + "s = 0\nfor x in count(4) {\n s += x\n}\nreturn s::str()", "6"},
+		{count + "g = count(10)\nreturn [g::next(), g.Send(3), g::next(), g::str()]::str()", "[0, 3, 4, <generator>]"},
+		{count + "g = count(10)\ng::next()\ng.Close()\nreturn [g::next() == Done, [...g]]::str()", "[true, []]"},
+		{fib + "return [...fib().Take(8)]::str()", "[0, 1, 1, 2, 3, 5, 8, 13]"},
+		{fib + "for x in fib() {\n if x > 10 {\n  return x::str()\n }\n}", "13"},
+		{count + "nested = () => {\n for x in [1, 2] {\n  for y in count(2) {\n   yield x * 10 + y\n  }\n }\n}\nreturn [...nested()]::str()", "[10, 11, 20, 21]"},
+		{"calls = []\ng = () => {\n calls.Add('start')\n yield 1\n}\nx = g()\nbefore = len(calls)\nx::next()\nreturn [before, len(calls)]::str()", "[0, 1]"},
+		{"g = (x) => (x == 0) ? 'none' : yield x\nreturn [...g(2)]::str()", "[2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"bad = () => {\n yield 1\n return missing\n}\nb = bad()\nb::next()\nb::next()", "Unable to find variable 'missing'"},
+		{"g = () => yield 1\ng().Send(1)", "call next first"},
+		{"f = () => {\n g = () => yield 1\n return g\n}\nf()()::next()\nyield 2", "yield can only be used inside a function"},
+	}
+
+	for _, tt := range errors {
+		params, errs := LoadProgram(tt.input, "exec_test.hoot")
+
+		var err error
+		if len(errs) > 0 {
+			err = fmt.Errorf("%s", errs[0].Message)
+		} else {
+			_, err = ExecuteProgramContext(context.Background(), params)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+		}
+	}
+}
+
+// TestGeneratorsClosed checks that generators that are not run to the end
+// do not leave their goroutines running.
+func TestGeneratorsClosed(t *testing.T) {
+	fib := "let fib = () => {\n a, b = 0, 1\n while true {\n  yield a\n  a, b = b, a + b\n }\n}\n"
+
+	// Generators that are stopped early are closed as they are used, and
+	// ones left paused are stopped when the program returns
+	tests := []string{
+		fib + "for x in fib() {\n break\n}",
+		fib + "f = () => {\n for x in fib() {\n  return x\n }\n}\nf()",
+		fib + "x = [...fib().Take(3)]",
+		fib + "x = [...fib().Map((n) => n * 2).Take(3)]",
+		fib + "x = [...iter([1, 2]).Zip(fib())]",
+		fib + "g = fib()\ng::next()",
+	}
+
+	for _, input := range tests {
+		for _, withContext := range []bool{false, true} {
+			before := runtime.NumGoroutine()
+
+			params, _ := LoadProgram(input, "exec_test.hoot")
+			if withContext {
+				if _, err := ExecuteProgramContext(context.Background(), params); err != nil {
+					t.Errorf("%q: unexpected error %v", input, err)
+					continue
+				}
+			} else {
+				ExecuteProgram(params)
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			if n := runtime.NumGoroutine(); n > before {
+				t.Errorf("%q: left %d goroutines running", input, n-before)
+			}
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return (9223372036854775807 + 1)::str()", "9223372036854775808"},
+		{"return (-9223372036854775807 - 2)::str()", "-9223372036854775809"},
+		{"return (3 * 9223372036854775807)::str()", "27670116110564327421"},
+		{"return [2 ** 100, 2 ** 100 - 2 ** 100 + 5, 2 ** -1]::str()", "[1267650600228229401496703205376, 5, 0.5]"},
+		{"x = 9223372036854775807\nx++\ny = -9223372036854775807 - 1\nreturn [x, -y, y - 1 + 1]::str()", "[9223372036854775808, 9223372036854775808, -9223372036854775808]"},
+		{"return [0.1d + 0.2d, 19.99d * 3, 10.00d / 4, 1d / 3, 2.5d - 1]::str()", "[0.3, 59.97, 2.50, 0.3333333333333333, 1.5]"},
+		{"return [1.50d == 1.5d, 2.0d == 2, 1.5d < 2, 0.1d + 0.2d == 0.3d]::str()", "[true, true, true, true]"},
+		{"return [7 // 2, -7 // 2, 7.5 // 2, 7.5d // 2, (2 ** 70) // (2 ** 68)]::str()", "[3, -4, 3, 3, 4]"},
+		{"return [0xFF, 0o17, 0b1010, 1_000_000, 99999999999999999999 % 7]::str()", "[255, 15, 10, 1000000, 1]"},
+		{"return [12 & 10, 12 | 10, 12 ^ 10, 1 << 70, -16 >> 2, (1 << 70) >> 69]::str()", "[8, 14, 6, 1180591620717411303424, -4, 2]"},
+		{"return [true & false, true | false, true ^ true]::str()", "[false, true, false]"},
+		{"# halves, rounding down\nx = 7 // 2 # 3\nreturn x::str()", "3"},
+		{"x = 5\nx <<= 2\nx |= 1\nx //= 3\nx **= 2\nx %= 10\nreturn x::str()", "9"},
+		{"return [hash(2.0d) == hash(2), hash(2 ** 70) == hash(2.0 ** 70), hash(1.5d) == hash(1.50d)]::str()", "[true, true, true]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, input := range []string{"return 1 // 0", "return 1 % 0", "return 1.5d + 1.5", "return 1.5 & 1", "return 1 << -1", "return 1d / 0"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, input)); err == nil || !strings.Contains(err.Error(), "Unable to evaluate binary operator") {
+			t.Errorf("Expected %q to fail, got %v", input, err)
+		}
+	}
+}
+
 func TestCall(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -644,6 +890,115 @@ func TestHTTP(t *testing.T) {
 	}
 }*/
 
+func TestLists(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"l = [1, 2]\nl.Add([3, 4])\nreturn l::str()", "[1, 2, [3, 4]]"},
+		{"l = [1, 2, 3]\nl.Insert(0, 0)\nl.Insert(-1, 9)\nl.Insert(5, 4)\nreturn l::str()", "[0, 1, 2, 9, 3, 4]"},
+		{"l = [1, 2, 3]\nreturn [l.Pop(), l.Pop(0), l]::str()", "[3, 1, [2]]"},
+		{"l = [1, 2, 1]\nl.Remove(1)\nl.Extend(range(3, 5))\nreturn l::str()", "[2, 1, 3, 4]"},
+		{"l = [1, [2], 1]\nreturn [l.IndexOf([2]), l.IndexOf(5), l.Count(1)]::str()", "[1, -1, 2]"},
+		{"return [[1, 0].Any(), [1, 0].All(), [].All(), [1, 2].Any((x) => x > 1), [1, 2, 3].Find((x) => x > 1), [1].Find((x) => x > 1)]::str()", "[true, false, true, true, 2, null]"},
+		{"return [[3, 1, 2].Sort(), [3, 1, 2].Sort((a, b) => b - a), [3, 1, 2].Sort((a, b) => a > b)]::str()", "[[1, 2, 3], [3, 2, 1], [3, 2, 1]]"},
+		{"return ['bb', 'a', 'cc', 'b'].SortBy((s) => s.Len())::str()", "[a, b, bb, cc]"},
+		{"return [[1, 2, 1, 3, 2].Unique(), [[1], [1], 1].Unique()]::str()", "[[1, 2, 3], [[1], 1]]"},
+		{"return [1, 2, 3, 4].GroupBy((x) => x % 2)::str()", "[[1, [1, 3]], [0, [2, 4]]]"},
+		{"return [[1, 2, 3, 4, 5].Chunk(2), [].Chunk(3)]::str()", "[[[1, 2], [3, 4], [5]], []]"},
+		{"return [1, 2, 3].Zip(['a', 'b'])::str()", "[[1, a], [2, b]]"},
+		{"return [1, 2].Zip([3, 4], range(5, 9))::str()", "[[1, 3, 5], [2, 4, 6]]"},
+		{"return [[1, 2, 3].Sum(), [].Sum(), [0.5, 1].Sum(), [[1], [2]].Sum([])]::str()", "[6, 0, 1.5, [1, 2]]"},
+		{"return [[3, 1, 2].Min(), [3, 1, 2].Max(), ['bb', 'a', 'cc'].Max((s) => s.Len())]::str()", "[1, 3, bb]"},
+		{"l = [1, 2]\nc = l.Copy()\nc.Add(3)\nreturn [l, c]::str()", "[[1, 2], [1, 2, 3]]"},
+		{"return [[1, [2]] == [1, [2]], [1, 2] == [2, 1], [1] != [1, 2], [1, 2] + [3]]::str()", "[true, false, true, [1, 2, 3]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, input := range []string{"['a', 1].Sort()", "[1, 'a'].Max()", "[].Min()", "[].Pop()", "[1].Pop(1)", "[1].Insert(3, 0)", "[1].Remove(2)", "[1].Chunk(0)", "[1].Extend(2)", "[1].Add(1, 2)", "[1].Count(1, 2)", "[1].IndexOf(1, 2)", "[1].Remove(1, 2)", "[1].Extend([2], [3])", "[1].Zip()", "[1] + 2", "[[1], 2].Sum()", "[1, 2].Sort((a, b) => 'x')"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, input)); err == nil {
+			t.Errorf("Expected %s to fail", input)
+		}
+	}
+
+	// Failures of the functions given to list functions are passed on
+	failures := []struct {
+		input    string
+		expected string
+	}{
+		{"import 'math'\n[2, 1].Sort(math.Abs)", "Sort comparator failed, math.Abs takes 1 number"},
+		{"import 'math'\n[2, 1].SortBy(math.Gcd)", "SortBy function failed, math.Gcd takes at least 2 integers"},
+		{"import 'math'\n[2, 1].GroupBy(math.Gcd)", "GroupBy function failed, math.Gcd takes at least 2 integers"},
+	}
+
+	for _, tt := range failures {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, tt.input)); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected an error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f = (x) => x\nreturn [typeof(1), typeof(2 ** 80), typeof(1.5), typeof(1.5d), typeof('s'), typeof(true), typeof(null), typeof([1]), typeof({a: 1}), typeof(f), typeof(len), typeof('a'.Upper)]::str()", "[int, int, float, decimal, string, bool, null, list, object, function, native, native]"},
+		{"return [int(2.9), int(-2.9), int(' 42 '), int(true), int(1e20), int(-7.5d), int(2 ** 70)]::str()", "[2, -2, 42, 1, 100000000000000000000, -7, 1180591620717411303424]"},
+		{"return [float(3), float('2.5'), float(false), float(0.5d)]::str()", "[3, 2.5, 0, 0.5]"},
+		{"o = {}\no::str = () => 'custom'\nreturn [str(12) + '!', str([1, 'a']), str(null), str(o)]::str()", "[12!, [1, a], null, custom]"},
+		{"return [bool(0), bool(''), bool([1]), bool('x'), bool(null)]::str()", "[false, false, true, true, false]"},
+		{"l = [1, 2]\nc = list(l)\nc.Add(3)\nreturn [l, c, list('hé'), list(range(3))]::str()", "[[1, 2], [1, 2, 3], [h, é], [0, 1, 2]]"},
+		{"return [typeof(int('7')) == 'int', typeof(float(7)) == 'float']::str()", "[true, true]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, input := range []string{"int([1])", "int('1.5')", "int('x')", "int(1 / 0.0)", "float('x')", "float(null)", "list(1)", "o = {}\no::str = () => 1\nstr(o)"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, input)); err == nil {
+			t.Errorf("Expected %s to fail", input)
+		}
+	}
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return [math.Floor(2.5), math.Ceil(2.0), math.Ceil(2.1), math.Trunc(-2.7), math.Floor(7)]::str()", "[2, 2, 3, -2, 7]"},
+		{"return [math.Round(2.5), math.Round(3.5), math.Round(-2.5), math.Round(2.345d, 2), math.Round(1.25, 1)]::str()", "[2, 4, -2, 2.34, 1.2]"},
+		{"return [math.Floor(-2.5d), math.Ceil(2.01d), math.Trunc(-2.7d), math.Floor(1e20)]::str()", "[-3, 3, -2, 100000000000000000000]"},
+		{"return [math.Sqrt(16), math.Hypot(3, 4), math.Sin(0), math.Log(math.e), math.Atan2(0, 1), math.tau == 2 * math.pi]::str()", "[4, 5, 0, 1, 0, true]"},
+		{"return [math.IsNaN(math.nan), math.IsInf(-math.inf), math.IsFinite(1), math.IsInf(2 ** 2000)]::str()", "[true, true, true, false]"},
+		{"return [math.Abs(-5), math.Abs(-1.5d), math.Abs(-2.5), math.Gcd(12, 18), math.Gcd(12, -18, 4)]::str()", "[5, 1.5, 2.5, 6, 2]"},
+		{"return [math.Clamp(15, 0, 10), math.Clamp(-1, 0, 10), math.Clamp(5, 0, 10), math.Clamp('b', 'a', 'c')]::str()", "[10, 0, 5, b]"},
+		{"return [math.Min(3, 1, 2), math.Max([3, 1, 2]), math.Min('b', 'a')]::str()", "[1, 3, a]"},
+		{"r = math.rand.New(42)\ns = math.rand.New(42)\nreturn ([...range(5).Map((i) => r.Int(100))]::str() == [...range(5).Map((i) => s.Int(100))]::str())::str()", "true"},
+		{"math.rand.Seed(7)\na = [math.rand.Float(), math.rand.Choice(['a', 'b', 'c']), math.rand.Shuffle([1, 2, 3])]\nmath.rand.Seed(7)\nreturn (a::str() == [math.rand.Float(), math.rand.Choice(['a', 'b', 'c']), math.rand.Shuffle([1, 2, 3])]::str())::str()", "true"},
+		{"r = math.rand.New(1)\nok = true\nfor i in range(200) {\n x = r.Int(5, 8)\n f = r.Float(2, 3)\n ok = ok and x >= 5 and x < 8 and f >= 2 and f < 3\n}\nreturn ok::str()", "true"},
+		{"return [...math.rand.Shuffle([3, 1, 2])].Sort()::str()", "[1, 2, 3]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval("import 'math'\n" + tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, input := range []string{"math.Sqrt('x')", "math.Sqrt(1, 2)", "math.Gcd(1.5, 2)", "math.Clamp(1, 10, 0)", "math.rand.Int(0)", "math.rand.Choice([])", "math.Round(1.5, -1)"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, "import 'math'\n"+input)); err == nil {
+			t.Errorf("Expected %s to fail", input)
+		}
+	}
+}
+
 func TestRegex(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -653,10 +1008,71 @@ func TestRegex(t *testing.T) {
 		{"return 'aabbaxxb'.ReIndex('ax*b')::str()", "[1, 3]"},
 		{"return 'aabbaxxb'.ReMatch('^ax*b')::str()", "false"},
 		{"return 'aabbaxxb'.ReMatch('^a.*b')::str()", "true"},
+		{"return 'añxb'.ReIndex('x')::str()", "[2, 3]"},
+		{"re = regex.Compile('a(x*)b')\nreturn [re.Match('aab'), re.Match('ba'), re.Find('zaxxbab'), re.Find('z'), re.pattern]::str()", "[true, false, axxb, null, a(x*)b]"},
+		{"re = regex.Compile('[0-9]+')\nreturn [re.FindAll('a1b22c333'), re.FindAll('a1b22c333', 2), re.FindAll('abc')]::str()", "[[1, 22, 333], [1, 22], []]"},
+		{"m = regex.Compile('(?P<key>\\\\pL+)=(?P<value>\\\\d*)(;)?').FindSubmatch('x: é=1')\nreturn [m[0], m.key, m.value, m[2], m[3]]::str()", "[é=1, é, 1, 1, null]"},
+		{"return regex.Compile('x').FindSubmatch('abc')::str()", "null"},
+		{"re = regex.Compile('(\\\\w)(\\\\d)')\nreturn [re.Replace('a1 b2', '$2$1'), re.ReplaceFunc('a1 b2', (m) => m.Upper())]::str()", "[1a 2b, A1 B2]"},
+		{"re = regex.Compile(' *, *')\nreturn [re.Split('a , b,c'), re.Split('a,b,c', 2)]::str()", "[[a, b, c], [a, b,c]]"},
+		{"return regex.Compile(regex.Escape('a.b')).Match('axb')::str()", "false"},
 	}
 
 	for _, tt := range tests {
-		evaluated := eval(tt.input)
+		evaluated := eval("import 'regex'\n" + tt.input)
 		testString(t, evaluated, tt.expected)
 	}
+
+	for _, input := range []string{"regex.Compile('a(')", "regex.Compile('[z-a]')", "'abc'.ReMatch('*')", "regex.Compile('a').ReplaceFunc('aa', (m) => m.Missing())", "regex.Compile('a').FindAll('a', -1)"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, "import 'regex'\n"+input)); err == nil {
+			t.Errorf("Expected %s to fail", input)
+		}
+	}
+
+	_, msg := compileRegex("a(")
+	if msg != "Invalid regex 'a(', missing closing )" {
+		t.Errorf("Unexpected error for an invalid regex, %s", msg)
+	}
+
+	a, _ := compileRegex("a+")
+	b, _ := compileRegex("a+")
+	if a != b {
+		t.Errorf("Expected a pattern to be compiled once")
+	}
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"d = 90 * time.minute\nreturn [d, d / 2, time.hour * 1.5, d / time.hour, d % time.hour, -d, d > time.hour, d.Minutes(), d.Milliseconds()]::str()", "[1h30m0s, 45m0s, 1h30m0s, 1.5, 30m0s, -1h30m0s, true, 90, 5400000]"},
+		{"return [time.ParseDuration('1h2m3s') == time.hour + 2 * time.minute + 3 * time.second, (1500 * time.millisecond).Round(time.second)]::str()", "[true, 2s]"},
+		{"t = time.Date(2024, 2, 29, 13, 5, 0, '+05:30')\nreturn [t, t.Weekday(), t.Year(), t.Month(), t.Day(), t.YearDay(), t.Zone(), t.Offset(), t.UTC()]::str()", "[2024-02-29T13:05:00+05:30, Thursday, 2024, 2, 29, 60, +05:30, 5h30m0s, 2024-02-29T07:35:00Z]"},
+		{"t = time.Date(2024, 1, 31)\nreturn [t + time.hour, t - time.hour, time.Date(2024, 2, 1) - t, t.AddDate(0, 1, 0), t.Format(time.dateOnly), t.Format('Jan 2, 2006')]::str()", "[2024-01-31T01:00:00Z, 2024-01-30T23:00:00Z, 24h0m0s, 2024-03-02T00:00:00Z, 2024-01-31, Jan 31, 2024]"},
+		{"a = time.Date(2024, 1, 1, 12, 0, 0, 'UTC')\nb = a.In('-03:00')\nreturn [a == b, a < a + time.second, b.Hour(), a.Truncate(24 * time.hour).Hour()]::str()", "[true, true, 9, 0]"},
+		{"return [time.Parse(time.dateTime, '2024-03-01 10:00:00').Unix(), time.Parse(time.dateTime, '2024-03-01 10:00:00', '+01:00').UTC(), time.Parse(time.rfc3339, '2024-03-01T10:00:00-02:00').Hour()]::str()", "[1709287200, 2024-03-01T09:00:00Z, 10]"},
+		{"return [time.Unix(1700000000).UTC(), time.Unix(1.5).UnixMilli(), time.UnixMilli(2500).Unix()]::str()", "[2023-11-14T22:13:20Z, 1500, 2]"},
+		{"start = time.Now()\nm = time.Monotonic()\ntime.Sleep(2 * time.hour)\nreturn [start, time.Now(), time.Since(start), time.Until(start), time.Monotonic() - m]::str()", "[2024-05-01T09:00:00Z, 2024-05-01T11:00:00Z, 2h0m0s, -2h0m0s, 2h0m0s]"},
+	}
+
+	for _, tt := range tests {
+		e := NewTreeExecutor("")
+		e.SetClock(NewFakeClock(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)))
+
+		var evaluated *OwlObj
+		params := mustLoad(t, "import 'time'\n"+tt.input)
+		if err := e.Try(func() { evaluated = e.ExecProgram(params.Program, params.Globals) }); err != nil {
+			t.Errorf("%s failed: %v", tt.input, err)
+			continue
+		}
+
+		testString(t, evaluated, tt.expected)
+	}
+
+	for _, input := range []string{"time.Sleep(1)", "time.Date(2024, 1)", "time.Date(2024, 1, 1, 'Mars/Olympus')", "time.Parse(time.dateOnly, 'soon')", "time.ParseDuration('1 hour')", "time.second / 0", "time.second + 1", "time.Now() + time.Now()", "time.hour * 1e300"} {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, "import 'time'\n"+input)); err == nil {
+			t.Errorf("Expected %s to fail", input)
+		}
+	}
 }
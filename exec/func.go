@@ -3,25 +3,29 @@ package exec
 import (
 	"strings"
 
+	"github.com/AnthonyEdvalson/owl/lexer"
 	"github.com/AnthonyEdvalson/owl/parser"
 )
 
 type FuncData struct {
+	Name      string
+	Token     lexer.Token
 	Body      []parser.Statement
 	Exec      *TreeExecutor
 	Arg       parser.Assign
 	This      *OwlObj
+	Home      *OwlObj
 	Env       *Frame
 	Condition parser.Expression
 	Else      *FuncData
+	Generator bool
 }
 
 func NewFunc(exec *TreeExecutor, def *parser.FunctionDef, frame *Frame) *OwlObj {
-	f := NewOwlObj()
-	f.SetDeepAttr("str", NewCallBridge(funcStr))
+	f := &OwlObj{methods: funcMethods}
 
 	data := funcDefToData(def, exec, frame)
-	f.Bind = func(this *OwlObj) { data.This = this }
+	f.Bind = func(this *OwlObj) { data.This = this; data.Home = this }
 	f.Raw = data
 	f.BridgeCall = func(a *OwlObj) (*OwlObj, bool) { return funcCall(data, a) }
 
@@ -40,6 +44,7 @@ func funcDefToData(def *parser.FunctionDef, exec *TreeExecutor, frame *Frame) *F
 	}
 
 	data := &FuncData{
+		Token:     def.Token(),
 		Body:      def.Body,
 		Exec:      exec,
 		Arg:       def.Arg,
@@ -47,6 +52,7 @@ func funcDefToData(def *parser.FunctionDef, exec *TreeExecutor, frame *Frame) *F
 		Env:       &ctx,
 		Condition: def.Condition,
 		Else:      elseData,
+		Generator: def.Generator,
 	}
 	return data
 }
@@ -59,11 +65,18 @@ func funcCall(f *FuncData, arg *OwlObj) (*OwlObj, bool) {
 		t.pushFrame()
 		t.Assign(data.Arg, arg)
 		t.set("this", data.This)
+		if data.Home != nil && data.Home.Proto != nil {
+			t.set("super", newSuper(data.This, data.Home.Proto))
+		}
 		if data.Condition == nil || data.Exec.EvalExpression(data.Condition).IsTruthy() {
-			state := data.Exec.ExecBlock(data.Body)
-			t.popFrame()
-			t.popFrame()
-			return state.Return, true
+			if data.Generator {
+				g := newGenerator(t, data)
+				t.popFrame()
+				t.popFrame()
+				return g, true
+			}
+			t.checkContext(data.Token)
+			return t.callBody(data), true
 		}
 		t.popFrame()
 		t.popFrame()
@@ -72,6 +85,48 @@ func funcCall(f *FuncData, arg *OwlObj) (*OwlObj, bool) {
 	return NewString("Unable to find a matching overload"), false
 }
 
+// callBody runs the body of data, which has its frames pushed, and pops
+// them once it returns. The call is counted towards the policy's depth limit
+// and recorded for stack traces until then, even if the body fails.
+func (t *TreeExecutor) callBody(data *FuncData) *OwlObj {
+	if t.Policy != nil {
+		t.checkDepth(data.Token)
+		b := t.run.budget
+		defer func() { b.depth-- }()
+	}
+
+	t.calls = append(t.calls, data)
+	defer func() { t.calls = t.calls[:len(t.calls)-1] }()
+
+	if t.Profiler != nil {
+		t.Profiler.OnCall(t, data)
+	}
+	state := data.Exec.ExecBlock(data.Body)
+	if t.Profiler != nil {
+		t.Profiler.OnReturn(t, data)
+	}
+
+	t.popFrame()
+	t.popFrame()
+
+	return state.Return
+}
+
+// nameFunc gives an anonymous function the name it is first assigned to, so
+// that it can be identified in profiles and stack traces.
+func nameFunc(v *OwlObj, name string) {
+	if v == nil {
+		return
+	}
+
+	for data, ok := v.Raw.(*FuncData); ok && data != nil; data = data.Else {
+		if data.Name != "" {
+			return
+		}
+		data.Name = name
+	}
+}
+
 func funcStr(args []*OwlObj) (*OwlObj, bool) {
 	f := args[0].Raw.(*FuncData)
 
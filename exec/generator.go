@@ -0,0 +1,178 @@
+package exec
+
+import "github.com/AnthonyEdvalson/owl/parser"
+
+// Calling a function that contains yield creates a generator instead of
+// running the function. The body runs on its own goroutine and executor, so
+// that it can stop part way through at a yield, and the caller and the
+// generator take turns, never running at the same time:
+//
+//	count = (n) => {
+//	    i = 0
+//	    while i < n {
+//	        sent = yield i
+//	        i += sent ?? 1
+//	    }
+//	}
+//
+// A generator is an iterator, so it can be used in for loops, spread and
+// with the iterator adapters. Send resumes it with a value for the yield it
+// is paused at, and Close stops it. For loops, spread and Take close the
+// generators they stop iterating early, and a generator that is otherwise
+// never finished or closed keeps its goroutine until the program's context
+// is cancelled, which happens when the program returns. Resuming it after
+// that finds it finished.
+type generator struct {
+	exec     *TreeExecutor
+	data     *FuncData
+	in       chan *OwlObj
+	out      chan genResult
+	closing  chan struct{}
+	started  bool
+	finished bool
+}
+
+// genResult is sent from the generator's goroutine each time it stops, with
+// the value it yielded, done when the body finished, or the value it
+// panicked with.
+type genResult struct {
+	value *OwlObj
+	done  bool
+	err   interface{}
+}
+
+// genClosed is panicked at a paused yield to unwind a closed generator.
+type genClosed struct{}
+
+// newGenerator creates a generator that runs data's body with t's current
+// frames, which hold the function's arguments.
+func newGenerator(t *TreeExecutor, data *FuncData) *OwlObj {
+	e := &TreeExecutor{
+		Frames:      append([]Frame{}, t.Frames...),
+		Debugger:    t.Debugger,
+		Profiler:    t.Profiler,
+		Tests:       t.Tests,
+		Policy:      t.Policy,
+		currentPath: t.currentPath,
+		calls:       []*FuncData{data},
+		run:         t.run,
+	}
+
+	g := &generator{
+		exec:    e,
+		data:    data,
+		in:      make(chan *OwlObj),
+		out:     make(chan genResult, 1),
+		closing: make(chan struct{}),
+	}
+	e.gen = g
+
+	o := NewIterator(func() (*OwlObj, bool) { return g.resume(NewNull()) })
+	o.SetDeepAttr("str", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return NewString("<generator>"), true }))
+	o.SetAttr("Send", NewCallBridge(g.send))
+	o.SetDeepAttr("close", NewCallBridge(g.close))
+	o.SetAttr("Close", NewCallBridge(g.close))
+
+	return o
+}
+
+func (g *generator) run() {
+	defer func() {
+		r := recover()
+		if _, ok := r.(genClosed); ok || r == nil {
+			g.out <- genResult{done: true}
+		} else {
+			g.out <- genResult{err: r}
+		}
+	}()
+
+	g.exec.ExecBlock(g.data.Body)
+}
+
+// resume runs the generator until it yields or finishes, with v as the value
+// of the yield it is paused at. An error in the generator is raised again
+// in the caller.
+func (g *generator) resume(v *OwlObj) (*OwlObj, bool) {
+	if g.finished {
+		return NewDone(), true
+	}
+
+	var r genResult
+	if g.started {
+		// A generator stopped by its context has already sent its result
+		select {
+		case g.in <- v:
+			r = <-g.out
+		case r = <-g.out:
+		}
+	} else {
+		g.started = true
+		go g.run()
+		r = <-g.out
+	}
+
+	if r.err != nil {
+		g.finished = true
+		panic(r.err)
+	}
+
+	if r.done {
+		g.finished = true
+		return NewDone(), true
+	}
+
+	return r.value, true
+}
+
+func (g *generator) send(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Send expects a value"), false
+	}
+
+	if !g.started && !args[1].IsNullish() {
+		return NewString("Unable to send a value to a generator that has not started, call next first"), false
+	}
+
+	return g.resume(args[1])
+}
+
+func (g *generator) close(args []*OwlObj) (*OwlObj, bool) {
+	if g.started && !g.finished {
+		close(g.closing)
+		<-g.out
+	}
+
+	g.finished = true
+
+	return NewNull(), true
+}
+
+// yield gives v to the generator's caller, and waits to be resumed.
+func (g *generator) yield(v *OwlObj) *OwlObj {
+	// The context is read before handing control back, as the caller may
+	// replace it once it has the value
+	cancelled := g.exec.run.ctx.Done()
+	g.out <- genResult{value: v}
+
+	select {
+	case sent := <-g.in:
+		return sent
+	case <-g.closing:
+	case <-cancelled:
+	}
+
+	panic(genClosed{})
+}
+
+func (t *TreeExecutor) evalYield(y *parser.Yield) *OwlObj {
+	if t.gen == nil {
+		t.panic("yield can only be used inside a generator", y.Token())
+	}
+
+	v := NewNull()
+	if y.Value != nil {
+		v = t.EvalExpression(y.Value)
+	}
+
+	return t.gen.yield(v)
+}
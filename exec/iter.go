@@ -0,0 +1,269 @@
+package exec
+
+import "strconv"
+
+// NewIterator creates a lazy iterator, whose values come from calling next
+// until it returns NewDone(). Iterators can be used in for loops and spread,
+// and have adapters that create new iterators without evaluating anything:
+//
+//	range(0, null).Map((x) => x * x).Filter((x) => x % 2 == 0).Take(3)
+//
+// An iterator can only be iterated once.
+func NewIterator(next func() (*OwlObj, bool)) *OwlObj {
+	return newIterator(next, nil)
+}
+
+// newIterator creates an iterator like NewIterator, whose close function
+// calls close, if it is not nil. Adapters close the iterator they take
+// their values from when they are closed.
+func newIterator(next func() (*OwlObj, bool), close func()) *OwlObj {
+	it := NewOwlObj()
+
+	it.SetDeepAttr("next", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return next() }))
+	it.SetDeepAttr("iter", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return it, true }))
+	it.SetDeepAttr("str", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return NewString("<iterator>"), true }))
+
+	if close != nil {
+		it.SetDeepAttr("close", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+			close()
+			return NewNull(), true
+		}))
+	}
+
+	it.SetAttr("Map", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return iterMap(it, next, args) }))
+	it.SetAttr("Filter", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return iterFilter(it, next, args) }))
+	it.SetAttr("Take", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return iterTake(it, next, args) }))
+	it.SetAttr("Zip", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return iterZip(it, next, args) }))
+	it.SetAttr("Enumerate", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return iterEnumerate(it, next, args) }))
+
+	return it
+}
+
+// iterMap yields fn(v) for each value v.
+func iterMap(it *OwlObj, next func() (*OwlObj, bool), args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Map expects a function"), false
+	}
+
+	fn := args[1]
+
+	return newIterator(func() (*OwlObj, bool) {
+		v, ok := next()
+		if !ok || v == NewDone() {
+			return v, ok
+		}
+
+		return fn.Call(v)
+	}, func() { closeIterator(it) }), true
+}
+
+// iterFilter yields the values for which fn is truthy.
+func iterFilter(it *OwlObj, next func() (*OwlObj, bool), args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Filter expects a function"), false
+	}
+
+	fn := args[1]
+
+	return newIterator(func() (*OwlObj, bool) {
+		for {
+			v, ok := next()
+			if !ok || v == NewDone() {
+				return v, ok
+			}
+
+			keep, ok := fn.Call(v)
+			if !ok {
+				return keep, false
+			}
+
+			if keep.IsTruthy() {
+				return v, true
+			}
+		}
+	}, func() { closeIterator(it) }), true
+}
+
+// iterTake yields the first n values, and then closes the iterator they
+// came from, as it will not be used again.
+func iterTake(it *OwlObj, next func() (*OwlObj, bool), args []*OwlObj) (*OwlObj, bool) {
+	var n int64
+	ok := len(args) == 2
+	if ok {
+		n, ok = args[1].TrueInt()
+	}
+
+	if !ok || n < 0 {
+		return NewString("Take expects a count of at least 0"), false
+	}
+
+	closed := false
+	closeSource := func() {
+		if !closed {
+			closed = true
+			closeIterator(it)
+		}
+	}
+
+	return newIterator(func() (*OwlObj, bool) {
+		if n <= 0 {
+			closeSource()
+			return NewDone(), true
+		}
+
+		n--
+		return next()
+	}, closeSource), true
+}
+
+// iterZip yields lists of one value from each iterable, stopping at the
+// end of the shortest, and then closing the others.
+func iterZip(it *OwlObj, next func() (*OwlObj, bool), args []*OwlObj) (*OwlObj, bool) {
+	nexts := []func() (*OwlObj, bool){next}
+	sources := []*OwlObj{it}
+
+	for _, arg := range args[1:] {
+		other, iter, ok := iterator(arg)
+		if !ok {
+			return NewString("Unable to zip " + typeName(arg) + ", it is not iterable"), false
+		}
+
+		nexts = append(nexts, other)
+		sources = append(sources, iter)
+	}
+
+	done := false
+	closeSources := func() {
+		if done {
+			return
+		}
+
+		done = true
+		for _, source := range sources {
+			closeIterator(source)
+		}
+	}
+
+	return newIterator(func() (*OwlObj, bool) {
+		if done {
+			return NewDone(), true
+		}
+
+		values := make([]*OwlObj, len(nexts))
+
+		for i, n := range nexts {
+			v, ok := n()
+			if !ok {
+				return v, false
+			}
+
+			if v == NewDone() {
+				closeSources()
+				return v, true
+			}
+
+			values[i] = v
+		}
+
+		return NewList(values), true
+	}, closeSources), true
+}
+
+// iterEnumerate yields [i, v] for each value v, counting from 0 or the
+// given start.
+func iterEnumerate(it *OwlObj, next func() (*OwlObj, bool), args []*OwlObj) (*OwlObj, bool) {
+	var i int64
+	if len(args) == 2 {
+		var ok bool
+		if i, ok = args[1].TrueInt(); !ok {
+			return NewString("Enumerate expects an integer to start from"), false
+		}
+	} else if len(args) > 2 {
+		return NewString("Enumerate expects at most one argument"), false
+	}
+
+	return newIterator(func() (*OwlObj, bool) {
+		v, ok := next()
+		if !ok || v == NewDone() {
+			return v, ok
+		}
+
+		i++
+		return NewList([]*OwlObj{NewInt(i - 1), v}), true
+	}, func() { closeIterator(it) }), true
+}
+
+// builtinIter makes a lazy iterator over any iterable value, so that the
+// adapters can be used with it.
+func builtinIter(arg *OwlObj) (*OwlObj, bool) {
+	next, iter, ok := iterator(arg)
+	if !ok {
+		return NewString(typeName(arg) + " is not iterable"), false
+	}
+
+	return newIterator(next, func() { closeIterator(iter) }), true
+}
+
+// builtinRange implements range(stop), range(start, stop) and
+// range(start, stop, step). The values are computed as they are iterated,
+// and a null stop never ends.
+func builtinRange(args []*OwlObj) (*OwlObj, bool) {
+	args = args[1:]
+	if len(args) < 1 || len(args) > 3 {
+		return NewString("range expects a stop, or a start, stop and optionally a step"), false
+	}
+
+	bounds := []*int64{}
+	for i, arg := range args {
+		if arg.IsNullish() && i == 1 {
+			bounds = append(bounds, nil)
+			continue
+		}
+
+		v, ok := arg.TrueInt()
+		if !ok {
+			return NewString("range expects integers, got " + typeName(arg)), false
+		}
+		bounds = append(bounds, &v)
+	}
+
+	var start, step int64 = 0, 1
+	var stop *int64
+
+	switch len(bounds) {
+	case 1:
+		stop = bounds[0]
+	case 3:
+		step = *bounds[2]
+		fallthrough
+	case 2:
+		start, stop = *bounds[0], bounds[1]
+	}
+
+	if step == 0 {
+		return NewString("range step cannot be 0"), false
+	}
+
+	i := start
+
+	it := NewIterator(func() (*OwlObj, bool) {
+		if stop != nil && ((step > 0 && i >= *stop) || (step < 0 && i <= *stop)) {
+			return NewDone(), true
+		}
+
+		i += step
+		return NewInt(i - step), true
+	})
+
+	desc := "range(" + strconv.FormatInt(start, 10) + ", "
+	if stop == nil {
+		desc += "null"
+	} else {
+		desc += strconv.FormatInt(*stop, 10)
+	}
+	desc += ", " + strconv.FormatInt(step, 10) + ")"
+
+	it.SetDeepAttr("str", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return NewString(desc), true }))
+
+	return it, true
+}
@@ -5,8 +5,16 @@ import (
 	"os"
 )
 
-func read(args []*OwlObj) (*OwlObj, bool) {
+func read(t *TreeExecutor, args []*OwlObj) (*OwlObj, bool) {
 	d := args[1].TrueStr()
+	if msg, ok := t.checkPath(d); !ok {
+		return NewString(msg), false
+	}
+
+	if err := t.run.ctx.Err(); err != nil {
+		return NewString("Read cancelled: " + err.Error()), false
+	}
+
 	bytes, err := os.ReadFile(d)
 
 	if err != nil {
@@ -16,8 +24,16 @@ func read(args []*OwlObj) (*OwlObj, bool) {
 	return NewString(string(bytes)), true
 }
 
-func listDir(args []*OwlObj) (*OwlObj, bool) {
+func listDir(t *TreeExecutor, args []*OwlObj) (*OwlObj, bool) {
 	d := args[1].TrueStr()
+	if msg, ok := t.checkPath(d); !ok {
+		return NewString(msg), false
+	}
+
+	if err := t.run.ctx.Err(); err != nil {
+		return NewString("ListDir cancelled: " + err.Error()), false
+	}
+
 	files, err := ioutil.ReadDir(d)
 
 	if err != nil {
@@ -36,11 +52,11 @@ func listDir(args []*OwlObj) (*OwlObj, bool) {
 	return NewList(names), true
 }
 
-func FsLibExport() *OwlObj {
+func FsLibExport(t *TreeExecutor) *OwlObj {
 	o := NewOwlObj()
 
-	o.SetAttr("Read", NewCallBridge(read))
-	o.SetAttr("ListDir", NewCallBridge(listDir))
+	o.SetAttr("Read", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return read(t, args) }))
+	o.SetAttr("ListDir", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return listDir(t, args) }))
 
 	return o
 }
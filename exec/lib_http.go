@@ -2,6 +2,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"regexp"
@@ -10,7 +11,9 @@ import (
 
 var lock = sync.Mutex{}
 
-func listenAndServe(args []*OwlObj) (*OwlObj, bool) {
+// listenAndServe serves the routes of the app it is bound to until the
+// server fails, or the executor's context is cancelled.
+func listenAndServe(t *TreeExecutor, args []*OwlObj) (*OwlObj, bool) {
 	this := args[0]
 	port := args[1].TrueStr()
 
@@ -20,9 +23,18 @@ func listenAndServe(args []*OwlObj) (*OwlObj, bool) {
 		return NewString("Attribute 'routes' not found"), false
 	}
 
-	urls := routes.Attr
+	// Routes are compiled once, rather than for each request
+	matchers := make(map[*regexp.Regexp]*OwlObj, len(routes.Attr))
+	for pattern, handler := range routes.Attr {
+		re, msg := compileRegex(pattern)
+		if msg != "" {
+			return NewString(msg), false
+		}
+		matchers[re] = handler
+	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		lock.Lock()
 		defer lock.Unlock()
 
@@ -31,10 +43,8 @@ func listenAndServe(args []*OwlObj) (*OwlObj, bool) {
 
 		url := r.URL.String()
 
-		for matcher, handler := range urls {
-			matched, err := regexp.MatchString(matcher, url)
-
-			if !matched || err != nil {
+		for re, handler := range matchers {
+			if !re.MatchString(url) {
 				continue
 			}
 
@@ -44,15 +54,32 @@ func listenAndServe(args []*OwlObj) (*OwlObj, bool) {
 		}
 	})
 
-	err := http.ListenAndServe(port, nil)
+	server := &http.Server{Addr: port, Handler: mux}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	go func() {
+		select {
+		case <-t.run.ctx.Done():
+			server.Shutdown(context.Background())
+		case <-stopped:
+		}
+	}()
+
+	err := server.ListenAndServe()
+
+	if ctxErr := t.run.ctx.Err(); ctxErr != nil {
+		return NewString("Server cancelled: " + ctxErr.Error()), false
+	}
 
 	return NewString(err.Error()), true
 }
 
-func HttpLibExport() *OwlObj {
+func HttpLibExport(t *TreeExecutor) *OwlObj {
 	o := NewOwlObj()
 
-	o.SetAttr("ListenAndServe", NewCallBridge(listenAndServe))
+	o.SetAttr("ListenAndServe", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return listenAndServe(t, args) }))
 
 	return o
 }
@@ -0,0 +1,433 @@
+package exec
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
+)
+
+// mathFuncs are the functions of Go's math package that take and return one
+// float, by their name in the math module.
+var mathFuncs = map[string]func(float64) float64{
+	"Sqrt":  math.Sqrt,
+	"Cbrt":  math.Cbrt,
+	"Exp":   math.Exp,
+	"Exp2":  math.Exp2,
+	"Expm1": math.Expm1,
+	"Log":   math.Log,
+	"Log2":  math.Log2,
+	"Log10": math.Log10,
+	"Log1p": math.Log1p,
+	"Sin":   math.Sin,
+	"Cos":   math.Cos,
+	"Tan":   math.Tan,
+	"Asin":  math.Asin,
+	"Acos":  math.Acos,
+	"Atan":  math.Atan,
+	"Sinh":  math.Sinh,
+	"Cosh":  math.Cosh,
+	"Tanh":  math.Tanh,
+	"Asinh": math.Asinh,
+	"Acosh": math.Acosh,
+	"Atanh": math.Atanh,
+	"Erf":   math.Erf,
+	"Erfc":  math.Erfc,
+	"Gamma": math.Gamma,
+}
+
+// mathFuncs2 are the functions that take two floats.
+var mathFuncs2 = map[string]func(float64, float64) float64{
+	"Atan2":     math.Atan2,
+	"Hypot":     math.Hypot,
+	"Pow":       math.Pow,
+	"Copysign":  math.Copysign,
+	"Remainder": math.Remainder,
+}
+
+var mathConsts = map[string]float64{
+	"pi":    math.Pi,
+	"tau":   2 * math.Pi,
+	"e":     math.E,
+	"phi":   math.Phi,
+	"sqrt2": math.Sqrt2,
+	"ln2":   math.Ln2,
+	"ln10":  math.Ln10,
+	"inf":   math.Inf(1),
+	"nan":   math.NaN(),
+}
+
+func MathLibExport(t *TreeExecutor) *OwlObj {
+	o := NewOwlObj()
+
+	for name, v := range mathConsts {
+		o.SetAttr(name, NewFloat(v))
+	}
+
+	for name, f := range mathFuncs {
+		name, f := name, f
+		o.SetAttr(name, NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+			x, msg := floatArgs(name, args, 1)
+			if msg != "" {
+				return NewString(msg), false
+			}
+
+			return NewFloat(f(x[0])), true
+		}))
+	}
+
+	for name, f := range mathFuncs2 {
+		name, f := name, f
+		o.SetAttr(name, NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+			x, msg := floatArgs(name, args, 2)
+			if msg != "" {
+				return NewString(msg), false
+			}
+
+			return NewFloat(f(x[0], x[1])), true
+		}))
+	}
+
+	o.SetAttr("Floor", NewCallBridge(mathRounder("Floor", math.Floor, func(d decimal.Decimal) decimal.Decimal {
+		v, _ := d.IntDiv(decimal.FromInt(1))
+		return v
+	})))
+	o.SetAttr("Ceil", NewCallBridge(mathRounder("Ceil", math.Ceil, func(d decimal.Decimal) decimal.Decimal {
+		v, _ := d.Neg().IntDiv(decimal.FromInt(1))
+		return v.Neg()
+	})))
+	o.SetAttr("Trunc", NewCallBridge(mathRounder("Trunc", math.Trunc, func(d decimal.Decimal) decimal.Decimal {
+		if d.Sign() < 0 {
+			v, _ := d.Neg().IntDiv(decimal.FromInt(1))
+			return v.Neg()
+		}
+		v, _ := d.IntDiv(decimal.FromInt(1))
+		return v
+	})))
+	o.SetAttr("Round", NewCallBridge(mathRound))
+
+	o.SetAttr("IsNaN", NewCallBridge(mathCheck("IsNaN", func(f float64) bool { return math.IsNaN(f) }, false)))
+	o.SetAttr("IsInf", NewCallBridge(mathCheck("IsInf", func(f float64) bool { return math.IsInf(f, 0) }, false)))
+	o.SetAttr("IsFinite", NewCallBridge(mathCheck("IsFinite", func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }, true)))
+
+	o.SetAttr("Abs", NewCallBridge(mathAbs))
+	o.SetAttr("Gcd", NewCallBridge(mathGcd))
+	o.SetAttr("Clamp", NewCallBridge(mathClamp))
+	o.SetAttr("Min", NewCallBridge(mathExtreme("Min", "<")))
+	o.SetAttr("Max", NewCallBridge(mathExtreme("Max", ">")))
+
+	o.SetAttr("rand", randModule(rand.New(rand.NewSource(time.Now().UnixNano()))))
+
+	return o
+}
+
+// floatArgs converts the arguments of the math function name to floats. It
+// returns a message for the error if there are not n numbers.
+func floatArgs(name string, args []*OwlObj, n int) ([]float64, string) {
+	args = args[1:]
+	if len(args) != n {
+		return nil, "math." + name + " takes " + pluralize(n, "number") + ", got " + pluralize(len(args), "argument")
+	}
+
+	x := make([]float64, n)
+	for i, a := range args {
+		if getRawType(a) == UNKNOWN {
+			return nil, "math." + name + " expects a number, got " + typeName(a)
+		}
+		x[i] = toFloat(a)
+	}
+
+	return x, ""
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+
+	return strconv.Itoa(n) + " " + word + "s"
+}
+
+// floatToInt converts a whole float to an int. Infinity and NaN are returned
+// as they are.
+func floatToInt(f float64) *OwlObj {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return NewFloat(f)
+	}
+
+	if math.Abs(f) < 1<<63 {
+		return NewInt(int64(f))
+	}
+
+	n, _ := big.NewFloat(f).Int(nil)
+	return NewBigInt(n)
+}
+
+// mathRounder creates a function that rounds a number to an int, with f for
+// floats and d for decimals. Ints are returned unchanged.
+func mathRounder(name string, f func(float64) float64, d func(decimal.Decimal) decimal.Decimal) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) != 2 {
+			return NewString("math." + name + " takes 1 number, got " + pluralize(len(args)-1, "argument")), false
+		}
+
+		switch v := args[1].Raw.(type) {
+		case int64, *big.Int:
+			return args[1], true
+		case float64:
+			return floatToInt(f(v)), true
+		case decimal.Decimal:
+			n, _ := d(v).Int()
+			return NewBigInt(n), true
+		}
+
+		return NewString("math." + name + " expects a number, got " + typeName(args[1])), false
+	}
+}
+
+// mathRound rounds half to even, to an int, or to a number of places after
+// the point when it is given a second argument.
+func mathRound(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) == 2 {
+		return mathRounder("Round", math.RoundToEven, func(d decimal.Decimal) decimal.Decimal { return d.Round(0) })(args)
+	}
+
+	if len(args) != 3 {
+		return NewString("math.Round takes a number and an optional number of places, got " + pluralize(len(args)-1, "argument")), false
+	}
+
+	places, ok := args[2].TrueInt()
+	if !ok || places < 0 || places > math.MaxInt32 {
+		return NewString("math.Round expects a whole number of places, got " + args[2].TrueStr()), false
+	}
+
+	switch v := args[1].Raw.(type) {
+	case int64, *big.Int:
+		return args[1], true
+	case float64:
+		scale := math.Pow(10, float64(places))
+		return NewFloat(math.RoundToEven(v*scale) / scale), true
+	case decimal.Decimal:
+		return NewDecimal(v.Round(int32(places))), true
+	}
+
+	return NewString("math.Round expects a number, got " + typeName(args[1])), false
+}
+
+// mathCheck creates a function testing a float. Other numbers are always
+// finite, so the function returns others for them.
+func mathCheck(name string, f func(float64) bool, others bool) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) != 2 || getRawType(args[1]) == UNKNOWN {
+			return NewString("math." + name + " expects a number"), false
+		}
+
+		if v, ok := args[1].Raw.(float64); ok {
+			return NewBool(f(v)), true
+		}
+
+		return NewBool(others), true
+	}
+}
+
+func mathAbs(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("math.Abs takes 1 number, got " + pluralize(len(args)-1, "argument")), false
+	}
+
+	switch v := args[1].Raw.(type) {
+	case int64:
+		if v < 0 {
+			return numberNeg(args[1:])
+		}
+		return args[1], true
+	case *big.Int:
+		return NewBigInt(new(big.Int).Abs(v)), true
+	case decimal.Decimal:
+		if v.Sign() < 0 {
+			return NewDecimal(v.Neg()), true
+		}
+		return args[1], true
+	case float64:
+		return NewFloat(math.Abs(v)), true
+	}
+
+	return NewString("math.Abs expects a number, got " + typeName(args[1])), false
+}
+
+// mathGcd returns the greatest common divisor of its arguments, which must be
+// integers.
+func mathGcd(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) < 3 {
+		return NewString("math.Gcd takes at least 2 integers"), false
+	}
+
+	gcd := new(big.Int)
+	for _, a := range args[1:] {
+		if k := getRawType(a); k != INT && k != BIG {
+			return NewString("math.Gcd expects integers, got " + typeName(a) + " " + a.TrueStr()), false
+		}
+		gcd.GCD(nil, nil, gcd, toBig(a))
+	}
+
+	return NewBigInt(gcd), true
+}
+
+// mathClamp limits a value to the range lo to hi. It works with any values
+// that can be compared.
+func mathClamp(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 4 {
+		return NewString("math.Clamp takes a value, a minimum and a maximum"), false
+	}
+
+	v, lo, hi := args[1], args[2], args[3]
+
+	if bad, ok := BinaryOp(">", lo, hi); !ok || bad.IsTruthy() {
+		return NewString("math.Clamp minimum " + lo.TrueStr() + " is greater than its maximum " + hi.TrueStr()), false
+	}
+
+	for _, c := range []struct {
+		op    string
+		bound *OwlObj
+	}{{"<", lo}, {">", hi}} {
+		out, ok := BinaryOp(c.op, v, c.bound)
+		if !ok {
+			return NewString("math.Clamp is unable to compare " + typeName(v) + " and " + typeName(c.bound)), false
+		}
+		if out.IsTruthy() {
+			return c.bound, true
+		}
+	}
+
+	return v, true
+}
+
+// mathExtreme creates Min or Max, which take any number of values, or a
+// list, and return the one that compares op to all the others.
+func mathExtreme(name string, op string) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) < 2 {
+			return NewString("math." + name + " of nothing"), false
+		}
+
+		best := args[1]
+		for _, v := range args[2:] {
+			out, ok := BinaryOp(op, v, best)
+			if !ok {
+				return NewString("math." + name + " is unable to compare " + typeName(v) + " and " + typeName(best)), false
+			}
+			if out.IsTruthy() {
+				best = v
+			}
+		}
+
+		return best, true
+	}
+}
+
+// randModule creates math.rand, whose functions use r. New creates another
+// generator with its own seed, so that a sequence can be repeated.
+func randModule(r *rand.Rand) *OwlObj {
+	o := NewOwlObj()
+
+	o.SetAttr("Seed", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		seed, ok := seedArg("Seed", args)
+		if !ok {
+			return seed, false
+		}
+
+		r.Seed(seed.Raw.(int64))
+		return NewNull(), true
+	}))
+
+	o.SetAttr("New", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		seed, ok := seedArg("New", args)
+		if !ok {
+			return seed, false
+		}
+
+		return randModule(rand.New(rand.NewSource(seed.Raw.(int64)))), true
+	}))
+
+	o.SetAttr("Int", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return randInt(r, args) }))
+	o.SetAttr("Float", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) { return randFloat(r, args) }))
+
+	o.SetAttr("Choice", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) < 2 {
+			return NewString("math.rand.Choice of an empty list"), false
+		}
+
+		return args[1+r.Intn(len(args)-1)], true
+	}))
+
+	o.SetAttr("Shuffle", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		items := append([]*OwlObj{}, args[1:]...)
+		r.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+
+		return NewList(items), true
+	}))
+
+	return o
+}
+
+func seedArg(name string, args []*OwlObj) (*OwlObj, bool) {
+	if len(args) == 2 {
+		if _, ok := args[1].TrueInt(); ok {
+			return args[1], true
+		}
+	}
+
+	return NewString("math.rand." + name + " takes an integer seed"), false
+}
+
+// randInt returns an int from 0 up to but not including n with one argument,
+// and from lo up to but not including hi with two, like range.
+func randInt(r *rand.Rand, args []*OwlObj) (*OwlObj, bool) {
+	lo, hi := NewInt(0), (*OwlObj)(nil)
+
+	switch len(args) {
+	case 2:
+		hi = args[1]
+	case 3:
+		lo, hi = args[1], args[2]
+	default:
+		return NewString("math.rand.Int takes a maximum, or a minimum and a maximum"), false
+	}
+
+	for _, v := range []*OwlObj{lo, hi} {
+		if k := getRawType(v); k != INT && k != BIG {
+			return NewString("math.rand.Int expects integers, got " + typeName(v)), false
+		}
+	}
+
+	span := new(big.Int).Sub(toBig(hi), toBig(lo))
+	if span.Sign() <= 0 {
+		return NewString("math.rand.Int range is empty, " + hi.TrueStr() + " is not greater than " + lo.TrueStr()), false
+	}
+
+	if span.IsInt64() {
+		return numberAdd([]*OwlObj{nil, lo, NewInt(r.Int63n(span.Int64()))})
+	}
+
+	return NewBigInt(span.Add(new(big.Int).Rand(r, span), toBig(lo))), true
+}
+
+// randFloat returns a float from 0 up to 1, or from lo up to hi.
+func randFloat(r *rand.Rand, args []*OwlObj) (*OwlObj, bool) {
+	switch len(args) {
+	case 1:
+		return NewFloat(r.Float64()), true
+	case 3:
+		x, msg := floatArgs("rand.Float", args, 2)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		return NewFloat(x[0] + r.Float64()*(x[1]-x[0])), true
+	}
+
+	return NewString("math.rand.Float takes no arguments, or a minimum and a maximum"), false
+}
@@ -1,22 +1,29 @@
 package exec
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"runtime"
 	"strings"
 )
 
-func OsLibExport() *OwlObj {
+func OsLibExport(t *TreeExecutor) *OwlObj {
 	o := NewOwlObj()
 
-	o.SetAttr("Exec", NewCallBridge(execCommand))
+	o.SetAttr("Exec", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		if t.Policy != nil && !t.Policy.AllowExec {
+			return NewString("os.Exec is not allowed"), false
+		}
+
+		return execCommand(t.run.ctx, args)
+	}))
 	o.SetAttr("Platform", NewCallBridge(execPlatform))
 
 	return o
 }
 
-func execCommand(args []*OwlObj) (*OwlObj, bool) {
+func execCommand(ctx context.Context, args []*OwlObj) (*OwlObj, bool) {
 	if len(args) < 2 {
 		return NewString("Not enough arguments, need at least 1"), false
 	}
@@ -32,7 +39,11 @@ func execCommand(args []*OwlObj) (*OwlObj, bool) {
 		return NewString("Command contains spaces, separate into multiple arguments"), false
 	}
 
-	cmdOut, err := exec.Command(cmd, cmdArgs...).CombinedOutput()
+	cmdOut, err := exec.CommandContext(ctx, cmd, cmdArgs...).CombinedOutput()
+
+	if ctx.Err() != nil {
+		return NewString("Command cancelled: " + ctx.Err().Error()), false
+	}
 
 	if err != nil {
 		return NewString("Command failed to run: " + err.Error() + "\r\nOutput: " + fmt.Sprintf("%s", cmdOut)), false
@@ -0,0 +1,219 @@
+package exec
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"sync"
+)
+
+// REGEX_CACHE_SIZE is the number of compiled patterns kept by compileRegex,
+// after which the cache is emptied and starts again.
+const REGEX_CACHE_SIZE = 256
+
+var (
+	regexCacheMu sync.Mutex
+	regexCache   = map[string]*regexp.Regexp{}
+)
+
+// compileRegex compiles pattern, reusing the result of an earlier call with
+// the same pattern. It returns a message describing the error if the pattern
+// is invalid.
+func compileRegex(pattern string) (*regexp.Regexp, string) {
+	regexCacheMu.Lock()
+	defer regexCacheMu.Unlock()
+
+	if re, ok := regexCache[pattern]; ok {
+		return re, ""
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		msg := err.Error()
+		if e, ok := err.(*syntax.Error); ok {
+			msg = e.Code.String()
+		}
+
+		return nil, "Invalid regex '" + pattern + "', " + msg
+	}
+
+	if len(regexCache) >= REGEX_CACHE_SIZE {
+		regexCache = map[string]*regexp.Regexp{}
+	}
+	regexCache[pattern] = re
+
+	return re, ""
+}
+
+func RegexLibExport(t *TreeExecutor) *OwlObj {
+	o := NewOwlObj()
+
+	o.SetAttr("Compile", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) != 2 {
+			return NewString("regex.Compile takes 1 pattern, got " + pluralize(len(args)-1, "argument")), false
+		}
+
+		re, msg := compileRegex(args[1].TrueStr())
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		return newRegex(re), true
+	}))
+
+	o.SetAttr("Escape", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) != 2 {
+			return NewString("regex.Escape takes 1 string, got " + pluralize(len(args)-1, "argument")), false
+		}
+
+		return NewString(regexp.QuoteMeta(args[1].TrueStr())), true
+	}))
+
+	return o
+}
+
+// newRegex creates the object for a compiled pattern, with the pattern's
+// source as its pattern attribute.
+func newRegex(re *regexp.Regexp) *OwlObj {
+	o := NewOwlObj()
+
+	// method adds a function taking a string and n more arguments, or up to
+	// n when optional is set.
+	method := func(name string, n int, optional bool, f func(s string, args []*OwlObj) (*OwlObj, bool)) {
+		o.SetAttr(name, NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+			if got := len(args) - 2; got != n && !(optional && got >= 0 && got < n) {
+				return NewString(name + " takes a string and " + pluralize(n, "argument") + ", got " + pluralize(len(args)-1, "argument")), false
+			}
+
+			return f(args[1].TrueStr(), args[2:])
+		}))
+	}
+
+	o.SetAttr("pattern", NewString(re.String()))
+	o.SetDeepAttr("str", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		return NewString("regex " + strconv.Quote(re.String())), true
+	}))
+
+	method("Match", 0, false, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		return NewBool(re.MatchString(s)), true
+	})
+
+	method("Find", 0, false, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		loc := re.FindStringIndex(s)
+		if loc == nil {
+			return NewNull(), true
+		}
+
+		return NewString(s[loc[0]:loc[1]]), true
+	})
+
+	method("FindAll", 1, true, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		n, msg := limitArg("FindAll", args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		matches := re.FindAllString(s, n)
+		objs := make([]*OwlObj, len(matches))
+		for i, v := range matches {
+			objs[i] = NewString(v)
+		}
+
+		return NewList(objs), true
+	})
+
+	method("FindSubmatch", 0, false, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return NewNull(), true
+		}
+
+		return submatch(re, s, loc), true
+	})
+
+	method("Replace", 1, false, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		return NewString(re.ReplaceAllString(s, args[0].TrueStr())), true
+	})
+
+	method("ReplaceFunc", 1, false, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		fn := args[0]
+		var failed *OwlObj
+
+		out := re.ReplaceAllStringFunc(s, func(m string) string {
+			if failed != nil {
+				return m
+			}
+
+			r, ok := fn.Call(NewString(m))
+			if !ok {
+				failed = NewString("ReplaceFunc function failed on '" + m + "'")
+				if r != nil {
+					failed = NewString("ReplaceFunc function failed, " + r.TrueStr())
+				}
+				return m
+			}
+
+			return r.TrueStr()
+		})
+
+		if failed != nil {
+			return failed, false
+		}
+
+		return NewString(out), true
+	})
+
+	method("Split", 1, true, func(s string, args []*OwlObj) (*OwlObj, bool) {
+		n, msg := limitArg("Split", args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		parts := re.Split(s, n)
+		objs := make([]*OwlObj, len(parts))
+		for i, v := range parts {
+			objs[i] = NewString(v)
+		}
+
+		return NewList(objs), true
+	})
+
+	return o
+}
+
+// limitArg reads the optional limit on the number of results of name, which
+// is unlimited when not given.
+func limitArg(name string, args []*OwlObj) (int, string) {
+	if len(args) == 0 {
+		return -1, ""
+	}
+
+	n, ok := args[0].TrueInt()
+	if !ok || n < 0 {
+		return 0, name + " limit must be an int of at least 0, got " + args[0].TrueStr()
+	}
+
+	return int(n), ""
+}
+
+// submatch creates the object for a match found at loc in s. The whole match
+// is at index 0 and each group at its number, and named groups are also
+// attributes by their name. Groups that did not take part in the match are
+// null.
+func submatch(re *regexp.Regexp, s string, loc []int) *OwlObj {
+	o := NewOwlObj()
+
+	for i, name := range re.SubexpNames() {
+		v := NewNull()
+		if loc[2*i] >= 0 {
+			v = NewString(s[loc[2*i]:loc[2*i+1]])
+		}
+
+		o.SetAttr(strconv.Itoa(i), v)
+		if name != "" {
+			o.SetAttr(name, v)
+		}
+	}
+
+	return o
+}
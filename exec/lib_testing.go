@@ -0,0 +1,336 @@
+package exec
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/lexer"
+)
+
+// TestCase is a test registered with test.Test.
+type TestCase struct {
+	Name  string
+	Token lexer.Token
+	fn    *OwlObj
+}
+
+type TestResult struct {
+	Name     string
+	Token    lexer.Token
+	Passed   bool
+	Message  string
+	Duration time.Duration
+}
+
+// TestSuite collects the tests registered by a program through the test
+// module, so that they can be run once the program has been executed.
+type TestSuite struct {
+	Tests    []*TestCase
+	setup    []*OwlObj
+	teardown []*OwlObj
+	exec     *TreeExecutor
+}
+
+// AssertionError is returned by failed assertions. The executor reports it
+// without the usual function call context, since the message already says
+// what went wrong.
+type AssertionError struct {
+	Message string
+}
+
+func NewTestSuite(t *TreeExecutor) *TestSuite {
+	return &TestSuite{exec: t}
+}
+
+func newAssertionError(msg string) *OwlObj {
+	o := NewOwlObj()
+	o.Raw = &AssertionError{msg}
+	o.SetDeepAttr("str", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		return NewString(msg), true
+	}))
+
+	return o
+}
+
+func TestLibExport(t *TreeExecutor) *OwlObj {
+	s := t.Tests
+	if s == nil {
+		// Not run by the test runner, tests are registered but never run
+		s = NewTestSuite(t)
+	}
+
+	o := NewOwlObj()
+
+	o.SetAttr("Test", NewCallBridge(s.test))
+	o.SetAttr("Setup", NewCallBridge(s.addSetup))
+	o.SetAttr("Teardown", NewCallBridge(s.addTeardown))
+	o.SetAttr("Assert", NewCallBridge(testAssert))
+	o.SetAttr("AssertEq", NewCallBridge(testAssertEq))
+
+	return o
+}
+
+func (s *TestSuite) test(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 3 {
+		return NewString("Expected a name and a function"), false
+	}
+
+	fn := args[2]
+
+	var token lexer.Token
+	if data, ok := fn.Raw.(*FuncData); ok {
+		token = data.Token
+	}
+
+	s.Tests = append(s.Tests, &TestCase{args[1].TrueStr(), token, fn})
+
+	return NewNull(), true
+}
+
+func (s *TestSuite) addSetup(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Expected a function"), false
+	}
+
+	s.setup = append(s.setup, args[1])
+
+	return NewNull(), true
+}
+
+func (s *TestSuite) addTeardown(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Expected a function"), false
+	}
+
+	s.teardown = append(s.teardown, args[1])
+
+	return NewNull(), true
+}
+
+// Run runs every test whose name matches filter, or every test if filter is
+// nil. Setup functions are run before each test and teardown functions after
+// it, even if the test fails.
+func (s *TestSuite) Run(filter *regexp.Regexp) []TestResult {
+	results := []TestResult{}
+
+	for _, tc := range s.Tests {
+		if filter != nil && !filter.MatchString(tc.Name) {
+			continue
+		}
+
+		start := time.Now()
+		result := TestResult{Name: tc.Name, Token: tc.Token, Passed: true}
+
+		msg, ok := s.call(s.setup...)
+		if ok {
+			msg, ok = s.call(tc.fn)
+		}
+
+		if !ok {
+			result.Passed = false
+			result.Message = msg
+		}
+
+		if msg, ok := s.call(s.teardown...); !ok && result.Passed {
+			result.Passed = false
+			result.Message = msg
+		}
+
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// call calls each function in turn, stopping at the first one that fails.
+func (s *TestSuite) call(fns ...*OwlObj) (string, bool) {
+	msg := ""
+
+	err := s.exec.Try(func() {
+		for _, fn := range fns {
+			v, ok := fn.Call(nil)
+			if ok {
+				continue
+			}
+
+			if v == nil {
+				msg = "Unable to call " + fn.TrueStr()
+			} else {
+				msg = v.TrueStr()
+			}
+			return
+		}
+	})
+
+	if err != nil {
+		return err.Error(), false
+	}
+
+	return msg, msg == ""
+}
+
+func testAssert(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) < 2 || len(args) > 3 {
+		return NewString("Expected a condition and an optional message"), false
+	}
+
+	if args[1].IsTruthy() {
+		return NewNull(), true
+	}
+
+	msg := "Assertion failed"
+	if len(args) == 3 {
+		msg += ": " + args[2].TrueStr()
+	}
+
+	return newAssertionError(msg), false
+}
+
+func testAssertEq(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) < 3 || len(args) > 4 {
+		return NewString("Expected an actual value, an expected value and an optional message"), false
+	}
+
+	actual, expected := args[1], args[2]
+
+	diffs := []string{}
+	diff("", actual, expected, &diffs)
+
+	if len(diffs) == 0 {
+		return NewNull(), true
+	}
+
+	b := strings.Builder{}
+	b.WriteString("Expected ")
+	b.WriteString(testRepr(expected))
+	b.WriteString(", got ")
+	b.WriteString(testRepr(actual))
+
+	if len(args) == 4 {
+		b.WriteString(": ")
+		b.WriteString(args[3].TrueStr())
+	}
+
+	// Only show a diff when it says more than the line above
+	if len(diffs) > 1 || !strings.HasPrefix(diffs[0], "value:") {
+		for _, d := range diffs {
+			b.WriteString("\n    ")
+			b.WriteString(d)
+		}
+	}
+
+	return newAssertionError(b.String()), false
+}
+
+func isPlainObj(o *OwlObj) bool {
+	return o.Raw == nil && o.BridgeCall == nil && !o.IsNullish()
+}
+
+// diff appends a line to diffs for every difference between actual and
+// expected. Lists and objects are compared item by item, everything else is
+// compared with ==.
+func diff(path string, actual *OwlObj, expected *OwlObj, diffs *[]string) {
+	name := path
+	if name == "" {
+		name = "value"
+	}
+
+	a, aList := actual.TrueList()
+	e, eList := expected.TrueList()
+
+	if aList && eList {
+		for i := 0; i < len(a) || i < len(e); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+
+			if i >= len(a) {
+				*diffs = append(*diffs, p+": missing, expected "+testRepr(e[i]))
+			} else if i >= len(e) {
+				*diffs = append(*diffs, p+": unexpected "+testRepr(a[i]))
+			} else {
+				diff(p, a[i], e[i], diffs)
+			}
+		}
+		return
+	}
+
+	if isPlainObj(actual) && isPlainObj(expected) {
+		keys := []string{}
+		for k := range actual.Attr {
+			keys = append(keys, k)
+		}
+		for k := range expected.Attr {
+			if _, ok := actual.Attr[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p := path + "." + k
+			av, aOk := actual.Attr[k]
+			ev, eOk := expected.Attr[k]
+
+			if !aOk {
+				*diffs = append(*diffs, p+": missing, expected "+testRepr(ev))
+			} else if !eOk {
+				*diffs = append(*diffs, p+": unexpected "+testRepr(av))
+			} else {
+				diff(p, av, ev, diffs)
+			}
+		}
+		return
+	}
+
+	if !testEqual(actual, expected) {
+		*diffs = append(*diffs, name+": expected "+testRepr(expected)+", got "+testRepr(actual))
+	}
+}
+
+func testEqual(a *OwlObj, b *OwlObj) bool {
+	if a == b {
+		return true
+	}
+
+	v, ok := a.Eq(a, b)
+	if !ok {
+		v, ok = b.Eq(a, b)
+	}
+
+	return ok && v.IsTruthy()
+}
+
+// testRepr returns the text used for a value in assertion messages, strings
+// are quoted so that "1" and 1 can be told apart.
+func testRepr(o *OwlObj) string {
+	if s, ok := o.Raw.(string); ok {
+		return strconv.Quote(s)
+	}
+
+	if list, ok := o.TrueList(); ok {
+		parts := make([]string, len(list))
+		for i, v := range list {
+			parts[i] = testRepr(v)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+
+	if isPlainObj(o) && len(o.Attr) > 0 {
+		keys := []string{}
+		for k := range o.Attr {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + ": " + testRepr(o.Attr[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+
+	return o.TrueStr()
+}
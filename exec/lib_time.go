@@ -0,0 +1,646 @@
+package exec
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the time module. Programs use the system
+// clock, SetClock replaces it, so that tests can control the time a program
+// sees without waiting.
+type Clock interface {
+	Now() time.Time
+
+	// Sleep waits for d, returning early with the context's error if ctx
+	// is cancelled.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FakeClock is a Clock whose time only moves when Advance or Sleep is
+// called. Sleep returns immediately, after moving the clock forward.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.Advance(d)
+	return nil
+}
+
+// Advance moves the clock forward by d, or back if d is negative.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// SetClock sets the clock the time module reads, for the program and every
+// module it imports. It must be called before the program imports time.
+func (t *TreeExecutor) SetClock(c Clock) {
+	t.run.clock = c
+}
+
+func (t *TreeExecutor) clock() Clock {
+	if t.run.clock == nil {
+		return systemClock{}
+	}
+
+	return t.run.clock
+}
+
+func NewTime(v time.Time) *OwlObj {
+	return &OwlObj{Raw: v, methods: timeMethods}
+}
+
+func NewDuration(v time.Duration) *OwlObj {
+	return &OwlObj{Raw: v, methods: durationMethods}
+}
+
+// timeLayouts are common layouts for Format and Parse, by their name in the
+// time module.
+var timeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339Nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"dateTime":    "2006-01-02 15:04:05",
+	"dateOnly":    "2006-01-02",
+	"timeOnly":    "15:04:05",
+	"kitchen":     time.Kitchen,
+}
+
+var timeUnits = map[string]time.Duration{
+	"nanosecond":  time.Nanosecond,
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"minute":      time.Minute,
+	"hour":        time.Hour,
+}
+
+func TimeLibExport(t *TreeExecutor) *OwlObj {
+	o := NewOwlObj()
+	clock := t.clock()
+	start := clock.Now()
+
+	for name, v := range timeLayouts {
+		o.SetAttr(name, NewString(v))
+	}
+
+	for name, v := range timeUnits {
+		o.SetAttr(name, NewDuration(v))
+	}
+
+	o.SetAttr("Now", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		return NewTime(clock.Now()), true
+	}))
+
+	o.SetAttr("Sleep", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		d, msg := durationArg("time.Sleep", args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		if err := clock.Sleep(t.run.ctx, d); err != nil {
+			return NewString("time.Sleep cancelled: " + err.Error()), false
+		}
+
+		return NewNull(), true
+	}))
+
+	o.SetAttr("Since", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		v, msg := timeArg("time.Since", args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		return NewDuration(clock.Now().Sub(v)), true
+	}))
+
+	o.SetAttr("Until", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		v, msg := timeArg("time.Until", args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		return NewDuration(v.Sub(clock.Now())), true
+	}))
+
+	// Monotonic is the time since the module was imported, which only ever
+	// increases, even if the system clock is changed.
+	o.SetAttr("Monotonic", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		return NewDuration(clock.Now().Sub(start)), true
+	}))
+
+	o.SetAttr("Unix", NewCallBridge(timeUnix))
+	o.SetAttr("UnixMilli", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		ms, ok := intArgs(args)
+		if !ok || len(ms) != 1 {
+			return NewString("time.UnixMilli takes 1 int"), false
+		}
+
+		return NewTime(time.UnixMilli(ms[0])), true
+	}))
+
+	o.SetAttr("Date", NewCallBridge(timeDate))
+	o.SetAttr("Parse", NewCallBridge(timeParse))
+
+	o.SetAttr("ParseDuration", NewCallBridge(func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) != 2 {
+			return NewString("time.ParseDuration takes 1 string"), false
+		}
+
+		d, err := time.ParseDuration(args[1].TrueStr())
+		if err != nil {
+			return NewString("Unable to parse '" + args[1].TrueStr() + "' as a duration, such as 1h30m"), false
+		}
+
+		return NewDuration(d), true
+	}))
+
+	return o
+}
+
+// durationArg reads the single duration argument of the function name.
+func durationArg(name string, args []*OwlObj) (time.Duration, string) {
+	if len(args) != 2 {
+		return 0, name + " takes 1 duration, got " + pluralize(len(args)-1, "argument")
+	}
+
+	d, ok := args[1].Raw.(time.Duration)
+	if !ok {
+		return 0, name + " expects a duration, such as 2 * time.second, got " + typeName(args[1])
+	}
+
+	return d, ""
+}
+
+// timeArg reads the single time argument of the function name.
+func timeArg(name string, args []*OwlObj) (time.Time, string) {
+	if len(args) != 2 {
+		return time.Time{}, name + " takes 1 time, got " + pluralize(len(args)-1, "argument")
+	}
+
+	v, ok := args[1].Raw.(time.Time)
+	if !ok {
+		return time.Time{}, name + " expects a time, got " + typeName(args[1])
+	}
+
+	return v, ""
+}
+
+// intArgs reads the arguments after this as ints.
+func intArgs(args []*OwlObj) ([]int64, bool) {
+	n := make([]int64, len(args)-1)
+
+	for i, a := range args[1:] {
+		v, ok := a.TrueInt()
+		if !ok {
+			return nil, false
+		}
+		n[i] = v
+	}
+
+	return n, true
+}
+
+// loadZone finds the time zone name, which is "UTC", "Local", a name from
+// the time zone database such as "Europe/Paris", or a fixed offset such as
+// "+05:30".
+func loadZone(name string) (*time.Location, string) {
+	switch name {
+	case "UTC":
+		return time.UTC, ""
+	case "Local":
+		return time.Local, ""
+	}
+
+	if offset, err := time.Parse("-07:00", name); err == nil {
+		_, seconds := offset.Zone()
+		return time.FixedZone(name, seconds), ""
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return nil, "Unknown time zone '" + name + "'"
+	}
+
+	return loc, ""
+}
+
+// timeUnix creates a time from seconds since 1970, which can be a float, and
+// optionally nanoseconds.
+func timeUnix(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) == 2 {
+		if f, ok := args[1].Raw.(float64); ok {
+			sec, frac := math.Modf(f)
+			return NewTime(time.Unix(int64(sec), int64(math.Round(frac*1e9)))), true
+		}
+	}
+
+	n, ok := intArgs(args)
+	if !ok || len(n) < 1 || len(n) > 2 {
+		return NewString("time.Unix takes seconds and optionally nanoseconds"), false
+	}
+
+	n = append(n, 0)
+	return NewTime(time.Unix(n[0], n[1])), true
+}
+
+// timeDate creates a time from its year, month and day, then optionally its
+// hour, minute, second and nanosecond, and a time zone, which is UTC by
+// default.
+func timeDate(args []*OwlObj) (*OwlObj, bool) {
+	loc := time.UTC
+
+	if last := args[len(args)-1]; len(args) > 1 {
+		if name, ok := last.Raw.(string); ok {
+			var msg string
+			if loc, msg = loadZone(name); msg != "" {
+				return NewString(msg), false
+			}
+			args = args[:len(args)-1]
+		}
+	}
+
+	n, ok := intArgs(args)
+	if !ok || len(n) < 3 || len(n) > 7 {
+		return NewString("time.Date takes a year, month and day, optionally the hour, minute, second and nanosecond, then a time zone"), false
+	}
+
+	for len(n) < 7 {
+		n = append(n, 0)
+	}
+
+	return NewTime(time.Date(int(n[0]), time.Month(n[1]), int(n[2]), int(n[3]), int(n[4]), int(n[5]), int(n[6]), loc)), true
+}
+
+// timeParse parses a time with a layout, in UTC or the time zone given if the
+// text has none.
+func timeParse(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 3 && len(args) != 4 {
+		return NewString("time.Parse takes a layout, a string and optionally a time zone"), false
+	}
+
+	loc := time.UTC
+	if len(args) == 4 {
+		var msg string
+		if loc, msg = loadZone(args[3].TrueStr()); msg != "" {
+			return NewString(msg), false
+		}
+	}
+
+	layout, s := args[1].TrueStr(), args[2].TrueStr()
+	v, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return NewString("Unable to parse '" + s + "' with layout '" + layout + "'"), false
+	}
+
+	return NewTime(v), true
+}
+
+func timeFormat(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Format takes 1 layout, such as time.rfc3339"), false
+	}
+
+	return NewString(args[0].Raw.(time.Time).Format(args[1].TrueStr())), true
+}
+
+func timeIn(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("In takes 1 time zone"), false
+	}
+
+	loc, msg := loadZone(args[1].TrueStr())
+	if msg != "" {
+		return NewString(msg), false
+	}
+
+	return NewTime(args[0].Raw.(time.Time).In(loc)), true
+}
+
+func timeAddDate(args []*OwlObj) (*OwlObj, bool) {
+	n, ok := intArgs(args)
+	if !ok || len(n) != 3 {
+		return NewString("AddDate takes a number of years, months and days"), false
+	}
+
+	return NewTime(args[0].Raw.(time.Time).AddDate(int(n[0]), int(n[1]), int(n[2]))), true
+}
+
+// timeGetter creates a method returning a part of a time as an int.
+func timeGetter(f func(v time.Time) int64) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		return NewInt(f(args[0].Raw.(time.Time))), true
+	}
+}
+
+// timeRounder creates Round or Truncate for times.
+func timeRounder(name string, f func(v time.Time, d time.Duration) time.Time) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		d, msg := durationArg(name, args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		return NewTime(f(args[0].Raw.(time.Time), d)), true
+	}
+}
+
+// timeOperands returns the time and the other operand of a binary operator,
+// which can be either way around.
+func timeOperands(args []*OwlObj) (time.Time, *OwlObj, bool) {
+	if v, ok := args[1].Raw.(time.Time); ok {
+		return v, args[2], true
+	}
+
+	v, ok := args[2].Raw.(time.Time)
+	return v, args[1], ok
+}
+
+// timeAdd adds a duration to a time.
+func timeAdd(args []*OwlObj) (*OwlObj, bool) {
+	v, other, ok := timeOperands(args)
+	d, isDuration := other.Raw.(time.Duration)
+
+	if !ok || !isDuration {
+		return nil, false
+	}
+
+	return NewTime(v.Add(d)), true
+}
+
+// timeSub subtracts a duration from a time, or finds the duration between
+// two times.
+func timeSub(args []*OwlObj) (*OwlObj, bool) {
+	a, ok := args[1].Raw.(time.Time)
+	if !ok {
+		return nil, false
+	}
+
+	switch b := args[2].Raw.(type) {
+	case time.Duration:
+		return NewTime(a.Add(-b)), true
+	case time.Time:
+		return NewDuration(a.Sub(b)), true
+	}
+
+	return nil, false
+}
+
+// timeCompare creates a comparison operator for times, which compares the
+// instants they refer to, whatever their time zone.
+func timeCompare(f func(c int) bool) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		a, aOk := args[1].Raw.(time.Time)
+		b, bOk := args[2].Raw.(time.Time)
+
+		if !aOk || !bOk {
+			return nil, false
+		}
+
+		c := 0
+		if a.Before(b) {
+			c = -1
+		} else if a.After(b) {
+			c = 1
+		}
+
+		return NewBool(f(c)), true
+	}
+}
+
+func timeStr(args []*OwlObj) (*OwlObj, bool) {
+	return NewString(args[0].Raw.(time.Time).Format(time.RFC3339Nano)), true
+}
+
+func timeHash(args []*OwlObj) (*OwlObj, bool) {
+	return NewInt(args[0].Raw.(time.Time).UnixNano()), true
+}
+
+// durationGetter creates a method converting a duration to a number of
+// units.
+func durationGetter(f func(d time.Duration) *OwlObj) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		return f(args[0].Raw.(time.Duration)), true
+	}
+}
+
+// durationRounder creates Round or Truncate for durations.
+func durationRounder(name string, f func(d time.Duration, m time.Duration) time.Duration) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		m, msg := durationArg(name, args)
+		if msg != "" {
+			return NewString(msg), false
+		}
+
+		return NewDuration(f(args[0].Raw.(time.Duration), m)), true
+	}
+}
+
+// durations returns the operands of a binary operator if both are durations.
+func durations(args []*OwlObj) (time.Duration, time.Duration, bool) {
+	a, aOk := args[1].Raw.(time.Duration)
+	b, bOk := args[2].Raw.(time.Duration)
+
+	return a, b, aOk && bOk
+}
+
+func durationAdd(args []*OwlObj) (*OwlObj, bool) {
+	a, b, ok := durations(args)
+	if !ok {
+		return nil, false
+	}
+
+	if s := a + b; (s > a) == (b > 0) {
+		return NewDuration(s), true
+	}
+
+	return NewString("Duration overflow adding " + b.String() + " to " + a.String()), false
+}
+
+func durationSub(args []*OwlObj) (*OwlObj, bool) {
+	a, b, ok := durations(args)
+	if !ok {
+		return nil, false
+	}
+
+	if s := a - b; (s < a) == (b > 0) {
+		return NewDuration(s), true
+	}
+
+	return NewString("Duration overflow subtracting " + b.String() + " from " + a.String()), false
+}
+
+// scaleDuration multiplies d by a number, failing if the result does not
+// fit in a duration.
+func scaleDuration(d time.Duration, n *OwlObj) (*OwlObj, bool) {
+	if getRawType(n) == UNKNOWN {
+		return nil, false
+	}
+
+	if i, ok := n.TrueInt(); ok && (i == 0 || (int64(d)*i)/i == int64(d)) {
+		return NewDuration(d * time.Duration(i)), true
+	}
+
+	f := math.Round(float64(d) * toFloat(n))
+	if math.IsNaN(f) || math.Abs(f) >= 1<<63 {
+		return NewString("Duration overflow multiplying " + d.String() + " by " + n.TrueStr()), false
+	}
+
+	return NewDuration(time.Duration(f)), true
+}
+
+// durationMul multiplies a duration by a number, which can be either
+// operand.
+func durationMul(args []*OwlObj) (*OwlObj, bool) {
+	if d, ok := args[1].Raw.(time.Duration); ok {
+		return scaleDuration(d, args[2])
+	}
+
+	if d, ok := args[2].Raw.(time.Duration); ok {
+		return scaleDuration(d, args[1])
+	}
+
+	return nil, false
+}
+
+// durationDiv divides a duration by a number, giving a duration, or by
+// another duration, giving a float.
+func durationDiv(args []*OwlObj) (*OwlObj, bool) {
+	a, ok := args[1].Raw.(time.Duration)
+	if !ok {
+		return nil, false
+	}
+
+	if b, ok := args[2].Raw.(time.Duration); ok {
+		if b == 0 {
+			return NewString("Division of a duration by zero"), false
+		}
+		return NewFloat(float64(a) / float64(b)), true
+	}
+
+	if getRawType(args[2]) == UNKNOWN {
+		return nil, false
+	}
+
+	if toFloat(args[2]) == 0 {
+		return NewString("Division of a duration by zero"), false
+	}
+
+	return scaleDuration(a, NewFloat(1/toFloat(args[2])))
+}
+
+func durationMod(args []*OwlObj) (*OwlObj, bool) {
+	a, b, ok := durations(args)
+	if !ok {
+		return nil, false
+	}
+
+	if b == 0 {
+		return NewString("Division of a duration by zero"), false
+	}
+
+	return NewDuration(a % b), true
+}
+
+func durationNeg(args []*OwlObj) (*OwlObj, bool) {
+	return NewDuration(-args[0].Raw.(time.Duration)), true
+}
+
+// durationCompare creates a comparison operator for durations.
+func durationCompare(f func(a time.Duration, b time.Duration) bool) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		a, b, ok := durations(args)
+		if !ok {
+			return nil, false
+		}
+
+		return NewBool(f(a, b)), true
+	}
+}
+
+func durationStr(args []*OwlObj) (*OwlObj, bool) {
+	return NewString(args[0].Raw.(time.Duration).String()), true
+}
+
+func durationHash(args []*OwlObj) (*OwlObj, bool) {
+	return NewInt(int64(args[0].Raw.(time.Duration))), true
+}
+
+func durationBool(args []*OwlObj) (*OwlObj, bool) {
+	return NewBool(args[0].Raw.(time.Duration) != 0), true
+}
+
+func weekday(v time.Time) *OwlObj {
+	return NewString(v.Weekday().String())
+}
+
+func zoneName(v time.Time) *OwlObj {
+	name, _ := v.Zone()
+	return NewString(name)
+}
+
+func zoneOffset(v time.Time) *OwlObj {
+	_, offset := v.Zone()
+	return NewDuration(time.Duration(offset) * time.Second)
+}
+
+// unitCount creates a getter returning a duration as a whole number of unit.
+func unitCount(unit time.Duration) func(d time.Duration) *OwlObj {
+	return func(d time.Duration) *OwlObj {
+		return NewInt(int64(d / unit))
+	}
+}
+
+// unitFraction creates a getter returning a duration as a float number of
+// unit.
+func unitFraction(unit time.Duration) func(d time.Duration) *OwlObj {
+	return func(d time.Duration) *OwlObj {
+		return NewFloat(float64(d) / float64(unit))
+	}
+}
+
+// timeMethod wraps a function of a time as a method.
+func timeMethod(f func(v time.Time) *OwlObj) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		return f(args[0].Raw.(time.Time)), true
+	}
+}
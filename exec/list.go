@@ -7,29 +7,7 @@ import (
 )
 
 func NewList(values []*OwlObj) *OwlObj {
-	l := NewOwlObj()
-
-	l.SetDeepAttr("bool", NewCallBridge(listBool))
-	l.SetDeepAttr("index", NewCallBridge(listIndex))
-	l.SetDeepAttr("setIndex", NewCallBridge(listSetIndex))
-	l.SetDeepAttr("slice", NewCallBridge(listSlice))
-	l.SetDeepAttr("str", NewCallBridge(listStr))
-	l.SetDeepAttr("has", NewCallBridge(listHas))
-	l.SetDeepAttr("iter", NewCallBridge(listIter))
-	l.SetAttr("Reverse", NewCallBridge(listReverse))
-	l.SetAttr("Add", NewCallBridge(listAppend))
-	l.SetAttr("Sort", NewCallBridge(listSort))
-	l.SetAttr("Join", NewCallBridge(listJoin))
-	l.SetAttr("Len", NewCallBridge(listLen))
-
-	l.SetAttr("Map", NewCallBridge(listMap))
-	l.SetAttr("Filter", NewCallBridge(listFilter))
-	l.SetAttr("Reduce", NewCallBridge(listReduce))
-	l.SetAttr("FlatMap", NewCallBridge(listFlatMap))
-
-	l.Raw = values
-
-	return l
+	return &OwlObj{Raw: values, methods: listMethods}
 }
 
 func mapIndex(i int64, len int) int {
@@ -159,7 +137,7 @@ func listReverse(args []*OwlObj) (*OwlObj, bool) {
 
 func listAppend(args []*OwlObj) (*OwlObj, bool) {
 	if len(args) != 2 {
-		panic("listAppend expects 2 arguments, got " + strconv.Itoa(len(args)))
+		return NewString("Add takes 1 value, got " + strconv.Itoa(len(args)-1)), false
 	}
 
 	raw, ok := args[0].TrueList()
@@ -224,6 +202,9 @@ func listIter(args []*OwlObj) (*OwlObj, bool) {
 	return NewList(raw), true
 }
 
+// listSort sorts the list in place, keeping equal items in order. Items are
+// compared with <, or with the comparator given, which returns true or a
+// negative number when its first argument comes first.
 func listSort(args []*OwlObj) (*OwlObj, bool) {
 	raw, ok := args[0].TrueList()
 
@@ -231,16 +212,115 @@ func listSort(args []*OwlObj) (*OwlObj, bool) {
 		return nil, false
 	}
 
-	sort.Slice(raw[:],
-		func(i, j int) bool {
-			co, ok := raw[i].Lt(raw[i], raw[j])
-			return ok && co.IsTruthy()
-		},
-	)
+	less := lessThan
+	if len(args) > 1 {
+		cmp := args[1]
+		less = func(a *OwlObj, b *OwlObj) (bool, string) {
+			out, ok := cmp.Call(NewList([]*OwlObj{a, b}))
+			if !ok {
+				return false, callFailed("Sort comparator", out, describe(a)+" and "+describe(b))
+			}
+
+			if v, ok := out.TrueBool(); ok {
+				return v, ""
+			}
+
+			if sign, ok := BinaryOp("<", out, NewInt(0)); ok && getRawType(out) != UNKNOWN {
+				return sign.IsTruthy(), ""
+			}
+
+			return false, "Sort comparator must return a bool or a number, got " + typeName(out)
+		}
+	}
+
+	if msg := stableSort(raw, raw, less); msg != "" {
+		return NewString(msg), false
+	}
 
 	return NewList(raw), true
 }
 
+// listSortBy sorts the list in place by the key fn returns for each item,
+// keeping items with equal keys in order.
+func listSortBy(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 2 {
+		return NewString("SortBy takes a function returning the key of an item"), false
+	}
+
+	keys, msg := mapItems(raw, args[1], "SortBy")
+	if msg != "" {
+		return NewString(msg), false
+	}
+
+	if msg := stableSort(raw, keys, lessThan); msg != "" {
+		return NewString(msg), false
+	}
+
+	return NewList(raw), true
+}
+
+func lessThan(a *OwlObj, b *OwlObj) (bool, string) {
+	return compare("<", a, b)
+}
+
+// compare compares a and b with op, returning a message if they can not be
+// compared. Values of different types are only compared if both are numbers,
+// as strings would otherwise compare with anything by its string form.
+func compare(op string, a *OwlObj, b *OwlObj) (bool, string) {
+	mismatch := typeName(a) != typeName(b) && (getRawType(a) == UNKNOWN || getRawType(b) == UNKNOWN)
+
+	out, ok := BinaryOp(op, a, b)
+	if mismatch || !ok {
+		return false, "Unable to compare " + describe(a) + " with " + describe(b)
+	}
+
+	return out.IsTruthy(), ""
+}
+
+// describe names a value and its type for error messages.
+func describe(o *OwlObj) string {
+	return typeName(o) + " " + o.TrueStr()
+}
+
+// stableSort sorts items by keys, which is the same length and is sorted
+// along with it. It stops at the first pair of keys that can not be
+// compared, returning the message for it.
+func stableSort(items []*OwlObj, keys []*OwlObj, less func(a *OwlObj, b *OwlObj) (bool, string)) string {
+	msg := ""
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if msg != "" {
+			return false
+		}
+
+		lt, m := less(keys[order[i]], keys[order[j]])
+		msg = m
+
+		return lt
+	})
+
+	if msg != "" {
+		return msg
+	}
+
+	sortedItems := make([]*OwlObj, len(items))
+	sortedKeys := make([]*OwlObj, len(keys))
+	for i, j := range order {
+		sortedItems[i], sortedKeys[i] = items[j], keys[j]
+	}
+
+	copy(items, sortedItems)
+	copy(keys, sortedKeys)
+
+	return ""
+}
+
 func listJoin(args []*OwlObj) (*OwlObj, bool) {
 	raw, ok := args[0].TrueList()
 
@@ -354,3 +434,496 @@ func listFlatMap(args []*OwlObj) (*OwlObj, bool) {
 
 	return NewList(newList), true
 }
+
+// mapItems calls fn with each item, for the list functions that take a
+// function, named name in error messages.
+func mapItems(raw []*OwlObj, fn *OwlObj, name string) ([]*OwlObj, string) {
+	out := make([]*OwlObj, len(raw))
+
+	for i, v := range raw {
+		r, ok := fn.Call(v)
+		if !ok {
+			return nil, callFailed(name+" function", r, describe(v))
+		}
+		out[i] = r
+	}
+
+	return out, ""
+}
+
+// callFailed is the message for a function given to a list function, named
+// name, that failed when called with args. The function's own failure is
+// included when it gave one.
+func callFailed(name string, failure *OwlObj, args string) string {
+	if failure != nil {
+		return name + " failed, " + failure.TrueStr()
+	}
+
+	return name + " failed on " + args
+}
+
+// equal compares a and b with ==.
+func equal(a *OwlObj, b *OwlObj) bool {
+	out, ok := BinaryOp("==", a, b)
+	return ok && out.IsTruthy()
+}
+
+func listIndexOf(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 2 {
+		return NewString("IndexOf takes 1 value"), false
+	}
+
+	for i, v := range raw {
+		if equal(v, args[1]) {
+			return NewInt(int64(i)), true
+		}
+	}
+
+	return NewInt(-1), true
+}
+
+func listCount(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 2 {
+		return NewString("Count takes 1 value"), false
+	}
+
+	n := 0
+	for _, v := range raw {
+		if equal(v, args[1]) {
+			n++
+		}
+	}
+
+	return NewInt(int64(n)), true
+}
+
+// listInsert inserts a value before the index given, which can be the length
+// of the list to add it at the end.
+func listInsert(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 3 {
+		return NewString("Insert takes an index and a value"), false
+	}
+
+	index, ok := args[1].TrueInt()
+	if !ok {
+		return NewString("Insert index must be an int, got " + typeName(args[1])), false
+	}
+
+	i := mapIndex(index, len(raw))
+	if i < 0 || i > len(raw) {
+		return NewString("Insert index " + args[1].TrueStr() + " is out of range for a list of length " + strconv.Itoa(len(raw))), false
+	}
+
+	raw = append(raw, nil)
+	copy(raw[i+1:], raw[i:])
+	raw[i] = args[2]
+	args[0].Raw = raw
+
+	return args[0], true
+}
+
+// listPop removes and returns the last item, or the item at the index given.
+func listPop(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) > 2 {
+		return NewString("Pop takes an optional index"), false
+	}
+
+	if len(raw) == 0 {
+		return NewString("Pop from an empty list"), false
+	}
+
+	i := len(raw) - 1
+	if len(args) == 2 {
+		index, ok := args[1].TrueInt()
+		if !ok {
+			return NewString("Pop index must be an int, got " + typeName(args[1])), false
+		}
+
+		i = mapIndex(index, len(raw))
+		if i < 0 || i >= len(raw) {
+			return NewString("Pop index " + args[1].TrueStr() + " is out of range for a list of length " + strconv.Itoa(len(raw))), false
+		}
+	}
+
+	v := raw[i]
+	args[0].Raw = append(raw[:i], raw[i+1:]...)
+
+	return v, true
+}
+
+// listRemove removes the first item equal to the value given.
+func listRemove(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 2 {
+		return NewString("Remove takes 1 value"), false
+	}
+
+	for i, v := range raw {
+		if equal(v, args[1]) {
+			args[0].Raw = append(raw[:i], raw[i+1:]...)
+			return args[0], true
+		}
+	}
+
+	return NewString("Unable to remove " + describe(args[1]) + ", it is not in the list"), false
+}
+
+// listExtend adds every value of an iterable to the end of the list.
+func listExtend(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 2 {
+		return NewString("Extend takes 1 iterable"), false
+	}
+
+	values, ok := args[1].AsList()
+	if !ok {
+		return NewString("Unable to extend a list with " + typeName(args[1]) + ", it is not iterable"), false
+	}
+
+	args[0].Raw = append(raw, values...)
+
+	return args[0], true
+}
+
+func listCopy(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok {
+		return nil, false
+	}
+
+	return NewList(append([]*OwlObj{}, raw...)), true
+}
+
+// listTest returns a function checking whether each item passes the test fn
+// given to name, or is truthy without one.
+func listTest(args []*OwlObj, name string) (func(v *OwlObj) (bool, string), bool) {
+	if len(args) > 2 {
+		return nil, false
+	}
+
+	if len(args) == 1 {
+		return func(v *OwlObj) (bool, string) { return v.IsTruthy(), "" }, true
+	}
+
+	fn := args[1]
+	return func(v *OwlObj) (bool, string) {
+		out, msg := mapItems([]*OwlObj{v}, fn, name)
+		if msg != "" {
+			return false, msg
+		}
+		return out[0].IsTruthy(), ""
+	}, true
+}
+
+func listAny(args []*OwlObj) (*OwlObj, bool) {
+	raw, _ := args[0].TrueList()
+	test, ok := listTest(args, "Any")
+	if !ok {
+		return NewString("Any takes an optional function"), false
+	}
+
+	for _, v := range raw {
+		pass, msg := test(v)
+		if msg != "" {
+			return NewString(msg), false
+		}
+		if pass {
+			return NewBool(true), true
+		}
+	}
+
+	return NewBool(false), true
+}
+
+func listAll(args []*OwlObj) (*OwlObj, bool) {
+	raw, _ := args[0].TrueList()
+	test, ok := listTest(args, "All")
+	if !ok {
+		return NewString("All takes an optional function"), false
+	}
+
+	for _, v := range raw {
+		pass, msg := test(v)
+		if msg != "" {
+			return NewString(msg), false
+		}
+		if !pass {
+			return NewBool(false), true
+		}
+	}
+
+	return NewBool(true), true
+}
+
+// listFind returns the first item fn returns true for, or null.
+func listFind(args []*OwlObj) (*OwlObj, bool) {
+	raw, _ := args[0].TrueList()
+	if len(args) != 2 {
+		return NewString("Find takes a function"), false
+	}
+
+	test, _ := listTest(args, "Find")
+
+	for _, v := range raw {
+		pass, msg := test(v)
+		if msg != "" {
+			return NewString(msg), false
+		}
+		if pass {
+			return v, true
+		}
+	}
+
+	return NewNull(), true
+}
+
+// listUnique returns the items that are not equal to an earlier item.
+func listUnique(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok {
+		return nil, false
+	}
+
+	seen := map[int64][]*OwlObj{}
+	unhashable := []*OwlObj{}
+	unique := []*OwlObj{}
+
+	for _, v := range raw {
+		h, hashable := Hash(v)
+		candidates := unhashable
+		if hashable {
+			candidates = seen[h]
+		}
+
+		duplicate := false
+		for _, c := range candidates {
+			if equal(c, v) {
+				duplicate = true
+				break
+			}
+		}
+
+		if duplicate {
+			continue
+		}
+
+		if hashable {
+			seen[h] = append(seen[h], v)
+		} else {
+			unhashable = append(unhashable, v)
+		}
+		unique = append(unique, v)
+	}
+
+	return NewList(unique), true
+}
+
+// listGroupBy groups the items by the key fn returns for them, as a list of
+// [key, items] pairs in the order each key was first seen.
+func listGroupBy(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) != 2 {
+		return NewString("GroupBy takes a function returning the key of an item"), false
+	}
+
+	keys, msg := mapItems(raw, args[1], "GroupBy")
+	if msg != "" {
+		return NewString(msg), false
+	}
+
+	groups := []*OwlObj{}
+	for i, k := range keys {
+		var group *OwlObj
+		for _, g := range groups {
+			if equal(g.Raw.([]*OwlObj)[0], k) {
+				group = g
+				break
+			}
+		}
+
+		if group == nil {
+			group = NewList([]*OwlObj{k, NewList([]*OwlObj{})})
+			groups = append(groups, group)
+		}
+
+		items := group.Raw.([]*OwlObj)[1]
+		items.Raw = append(items.Raw.([]*OwlObj), raw[i])
+	}
+
+	return NewList(groups), true
+}
+
+// listChunk splits the list into lists of n items, the last of which can be
+// shorter.
+func listChunk(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	var n int64
+	if ok && len(args) == 2 {
+		n, ok = args[1].TrueInt()
+	}
+
+	if !ok || len(args) != 2 || n <= 0 {
+		return NewString("Chunk takes a positive size"), false
+	}
+
+	chunks := []*OwlObj{}
+	for i := 0; i < len(raw); i += int(n) {
+		end := i + int(n)
+		if end > len(raw) || end < i {
+			end = len(raw)
+		}
+		chunks = append(chunks, NewList(append([]*OwlObj{}, raw[i:end]...)))
+	}
+
+	return NewList(chunks), true
+}
+
+// listZip makes a list of each item and the items at the same position of
+// one or more other iterables, stopping at the end of the shortest.
+func listZip(args []*OwlObj) (*OwlObj, bool) {
+	raw, ok := args[0].TrueList()
+
+	if !ok || len(args) < 2 {
+		return NewString("Zip takes at least 1 iterable"), false
+	}
+
+	lists := [][]*OwlObj{raw}
+	n := len(raw)
+
+	for _, arg := range args[1:] {
+		other, ok := arg.AsList()
+		if !ok {
+			return NewString("Unable to zip " + typeName(arg) + ", it is not iterable"), false
+		}
+
+		lists = append(lists, other)
+		if len(other) < n {
+			n = len(other)
+		}
+	}
+
+	zipped := make([]*OwlObj, n)
+	for i := range zipped {
+		values := make([]*OwlObj, len(lists))
+		for j, list := range lists {
+			values[j] = list[i]
+		}
+		zipped[i] = NewList(values)
+	}
+
+	return NewList(zipped), true
+}
+
+// listSum adds the items with +, starting from the value given, or the first
+// item. The sum of an empty list is 0.
+func listSum(args []*OwlObj) (*OwlObj, bool) {
+	raw, _ := args[0].TrueList()
+
+	if len(args) > 2 {
+		return NewString("Sum takes an optional starting value"), false
+	}
+
+	if len(args) == 2 {
+		raw = append([]*OwlObj{args[1]}, raw...)
+	}
+
+	if len(raw) == 0 {
+		return NewInt(0), true
+	}
+
+	total := raw[0]
+	for _, v := range raw[1:] {
+		next, ok := BinaryOp("+", total, v)
+		if !ok {
+			return NewString("Unable to add " + describe(v) + " to " + describe(total)), false
+		}
+		total = next
+	}
+
+	return total, true
+}
+
+// listExtreme creates Min or Max, which return the first item that compares
+// op to every other, by the key fn returns for it if given.
+func listExtreme(name string, op string) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		raw, _ := args[0].TrueList()
+
+		if len(args) > 2 {
+			return NewString(name + " takes an optional function returning the key of an item"), false
+		}
+
+		if len(raw) == 0 {
+			return NewString(name + " of an empty list"), false
+		}
+
+		keys := raw
+		if len(args) == 2 {
+			var msg string
+			if keys, msg = mapItems(raw, args[1], name); msg != "" {
+				return NewString(msg), false
+			}
+		}
+
+		best := 0
+		for i := 1; i < len(raw); i++ {
+			better, msg := compare(op, keys[i], keys[best])
+			if msg != "" {
+				return NewString(msg), false
+			}
+			if better {
+				best = i
+			}
+		}
+
+		return raw[best], true
+	}
+}
+
+// listEq compares lists item by item.
+func listEq(args []*OwlObj) (*OwlObj, bool) {
+	a, aOk := args[1].TrueList()
+	b, bOk := args[2].TrueList()
+
+	if !aOk || !bOk {
+		return nil, false
+	}
+
+	if len(a) != len(b) {
+		return NewBool(false), true
+	}
+
+	for i := range a {
+		if !equal(a[i], b[i]) {
+			return NewBool(false), true
+		}
+	}
+
+	return NewBool(true), true
+}
+
+// listAdd concatenates two lists into a new list.
+func listAdd(args []*OwlObj) (*OwlObj, bool) {
+	a, aOk := args[1].TrueList()
+	b, bOk := args[2].TrueList()
+
+	if !aOk || !bOk {
+		return nil, false
+	}
+
+	return NewList(append(append(make([]*OwlObj, 0, len(a)+len(b)), a...), b...)), true
+}
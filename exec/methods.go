@@ -0,0 +1,310 @@
+package exec
+
+import "time"
+
+// methodTable holds the attributes shared by every value of a built-in
+// type, so that creating a value does not create its methods. Tables are
+// built once and never modified, attributes set on a value are stored on
+// the value itself and take precedence over its table.
+type methodTable struct {
+	attr map[string]*OwlObj
+	deep map[string]*OwlObj
+}
+
+type methods map[string]func(args []*OwlObj) (*OwlObj, bool)
+
+// extend creates a table with the attributes of m, plus attr and deep, and
+// without the deep attributes named in remove.
+func (m *methodTable) extend(attr methods, deep methods, remove ...string) *methodTable {
+	t := &methodTable{attr: map[string]*OwlObj{}, deep: map[string]*OwlObj{}}
+
+	if m != nil {
+		for k, v := range m.attr {
+			t.attr[k] = v
+		}
+		for k, v := range m.deep {
+			t.deep[k] = v
+		}
+	}
+
+	for k, f := range attr {
+		t.attr[k] = NewCallBridge(f)
+	}
+	for k, f := range deep {
+		t.deep[k] = NewCallBridge(f)
+	}
+	for _, k := range remove {
+		delete(t.deep, k)
+	}
+
+	return t
+}
+
+// whole marks the attributes named as taking a single value, which is not
+// spread into separate arguments when it is a list, see BridgeData.Whole.
+func (m *methodTable) whole(names ...string) {
+	for _, k := range names {
+		m.attr[k].Raw.(*BridgeData).Whole = true
+	}
+}
+
+// call calls the method v found in the table of this, without creating a
+// bound copy of it.
+func (m *methodTable) call(this *OwlObj, v *OwlObj, arg *OwlObj) (*OwlObj, bool) {
+	if data, ok := v.Raw.(*BridgeData); ok {
+		return data.BridgeCall(bridgeArgs(data, this, arg, -1))
+	}
+
+	return v.Call(arg)
+}
+
+const (
+	SMALL_INT_MIN = -128
+	SMALL_INT_MAX = 1023
+)
+
+var (
+	objectMethods   *methodTable
+	boolMethods     *methodTable
+	nullMethods     *methodTable
+	numberMethods   *methodTable
+	stringMethods   *methodTable
+	listMethods     *methodTable
+	funcMethods     *methodTable
+	timeMethods     *methodTable
+	durationMethods *methodTable
+	doneMethods     *methodTable
+
+	// Values that are created often are made once and shared. They are
+	// copied before a program sets an attribute on them.
+	boolValues  [2]*OwlObj
+	nullValue   *OwlObj
+	doneValue   *OwlObj
+	smallInts   [SMALL_INT_MAX - SMALL_INT_MIN + 1]*OwlObj
+	byteStrings [256]*OwlObj
+	emptyString *OwlObj
+)
+
+// The tables refer to functions that create values, which use the tables,
+// so they are built in init rather than as variable initializers.
+func init() {
+	objectMethods = (*methodTable)(nil).extend(nil, methods{
+		"str":      objStr,
+		"index":    objIndex,
+		"setIndex": objSetIndex,
+		"iter":     objIter,
+		"has":      objHas,
+		"coalesce": objCoalesce,
+		"and":      objAnd,
+		"or":       objOr,
+		"not":      objNot,
+	})
+
+	scalar := []string{"iter", "index", "setIndex", "has"}
+
+	boolMethods = objectMethods.extend(nil, methods{
+		"and": boolAnd,
+		"or":  boolOr,
+		"not": boolNot,
+		"eq":  boolEq,
+		"ne":  boolNe,
+		"str": boolStr,
+
+		"band": boolBitwise(func(a bool, b bool) bool { return a && b }),
+		"bor":  boolBitwise(func(a bool, b bool) bool { return a || b }),
+		"bxor": boolBitwise(func(a bool, b bool) bool { return a != b }),
+	}, scalar...)
+
+	boolValues[0] = &OwlObj{Raw: false, methods: boolMethods, shared: true}
+	boolValues[1] = &OwlObj{Raw: true, methods: boolMethods, shared: true}
+
+	nullMethods = objectMethods.extend(nil, methods{
+		"eq":  nullEq,
+		"ne":  nullNe,
+		"str": nullStr,
+	}, scalar...)
+	nullMethods.deep["null"] = boolValues[1]
+
+	nullValue = &OwlObj{methods: nullMethods, shared: true}
+
+	doneMethods = objectMethods.extend(nil, methods{"str": doneStr})
+	doneValue = &OwlObj{methods: doneMethods, shared: true}
+
+	numberMethods = objectMethods.extend(nil, methods{
+		"add":  numberAdd,
+		"sub":  numberSub,
+		"mul":  numberMul,
+		"div":  numberDiv,
+		"pow":  numberPow,
+		"mod":  numberMod,
+		"idiv": numberIntDiv,
+		"band": numberAnd,
+		"bor":  numberOr,
+		"bxor": numberXor,
+		"shl":  numberShl,
+		"shr":  numberShr,
+		"neg":  numberNeg,
+		"inc":  numberInc,
+		"dec":  numberDec,
+		"eq":   numberEq,
+		"ne":   numberNe,
+		"lt":   numberLt,
+		"le":   numberLe,
+		"gt":   numberGt,
+		"ge":   numberGe,
+		"str":  numberStr,
+	}, scalar...)
+
+	for i := range smallInts {
+		smallInts[i] = &OwlObj{Raw: int64(i + SMALL_INT_MIN), methods: numberMethods, shared: true}
+	}
+
+	stringMethods = objectMethods.extend(methods{
+		"Split":      stringSplit,
+		"Len":        stringLen,
+		"Replace":    stringReplace,
+		"ReReplace":  stringRegexReplace,
+		"ReMatch":    stringRegexMatch,
+		"ReIndex":    stringRegexIndexOf,
+		"Index":      stringIndexOf,
+		"Trim":       stringTrim,
+		"Upper":      stringUpper,
+		"Lower":      stringLower,
+		"Title":      stringTitle,
+		"StartsWith": stringStartsWith,
+		"EndsWith":   stringEndsWith,
+		"Contains":   stringContains,
+		"Repeat":     stringRepeat,
+		"PadLeft":    pad("PadLeft", true),
+		"PadRight":   pad("PadRight", false),
+		"Lines":      stringLines,
+		"Fields":     stringFields,
+		"Format":     stringFormat,
+		"ToInt":      stringToInt,
+		"ToFloat":    stringToFloat,
+		"Bytes":      stringBytes,
+	}, methods{
+		"mul":   stringMul,
+		"add":   stringAdd,
+		"eq":    stringEq,
+		"ne":    stringNe,
+		"gt":    stringGt,
+		"lt":    stringLt,
+		"ge":    stringGe,
+		"le":    stringLe,
+		"str":   stringStr,
+		"index": stringIndex,
+		"has":   stringHas,
+		"slice": stringSlice,
+		"iter":  stringIter,
+	}, "setIndex")
+
+	for i := range byteStrings {
+		byteStrings[i] = &OwlObj{Raw: string([]byte{byte(i)}), methods: stringMethods, shared: true}
+	}
+	emptyString = &OwlObj{Raw: "", methods: stringMethods, shared: true}
+
+	listMethods = objectMethods.extend(methods{
+		"Reverse": listReverse,
+		"Add":     listAppend,
+		"Sort":    listSort,
+		"Join":    listJoin,
+		"Len":     listLen,
+		"Map":     listMap,
+		"Filter":  listFilter,
+		"Reduce":  listReduce,
+		"FlatMap": listFlatMap,
+		"Insert":  listInsert,
+		"Pop":     listPop,
+		"Remove":  listRemove,
+		"Extend":  listExtend,
+		"IndexOf": listIndexOf,
+		"Count":   listCount,
+		"Any":     listAny,
+		"All":     listAll,
+		"Find":    listFind,
+		"SortBy":  listSortBy,
+		"Unique":  listUnique,
+		"GroupBy": listGroupBy,
+		"Chunk":   listChunk,
+		"Zip":     listZip,
+		"Sum":     listSum,
+		"Min":     listExtreme("Min", "<"),
+		"Max":     listExtreme("Max", ">"),
+		"Copy":    listCopy,
+	}, methods{
+		"eq":       listEq,
+		"add":      listAdd,
+		"bool":     listBool,
+		"index":    listIndex,
+		"setIndex": listSetIndex,
+		"slice":    listSlice,
+		"str":      listStr,
+		"has":      listHas,
+		"iter":     listIter,
+	})
+	listMethods.whole("Add", "Remove", "Extend", "IndexOf", "Count", "Zip")
+
+	funcMethods = objectMethods.extend(nil, methods{
+		"str": funcStr,
+	})
+
+	timeMethods = objectMethods.extend(methods{
+		"Year":       timeGetter(func(v time.Time) int64 { return int64(v.Year()) }),
+		"Month":      timeGetter(func(v time.Time) int64 { return int64(v.Month()) }),
+		"Day":        timeGetter(func(v time.Time) int64 { return int64(v.Day()) }),
+		"Hour":       timeGetter(func(v time.Time) int64 { return int64(v.Hour()) }),
+		"Minute":     timeGetter(func(v time.Time) int64 { return int64(v.Minute()) }),
+		"Second":     timeGetter(func(v time.Time) int64 { return int64(v.Second()) }),
+		"Nanosecond": timeGetter(func(v time.Time) int64 { return int64(v.Nanosecond()) }),
+		"YearDay":    timeGetter(func(v time.Time) int64 { return int64(v.YearDay()) }),
+		"Unix":       timeGetter(time.Time.Unix),
+		"UnixMilli":  timeGetter(time.Time.UnixMilli),
+		"UnixNano":   timeGetter(time.Time.UnixNano),
+		"Weekday":    timeMethod(weekday),
+		"Zone":       timeMethod(zoneName),
+		"Offset":     timeMethod(zoneOffset),
+		"UTC":        timeMethod(func(v time.Time) *OwlObj { return NewTime(v.UTC()) }),
+		"Format":     timeFormat,
+		"In":         timeIn,
+		"AddDate":    timeAddDate,
+		"Round":      timeRounder("Round", time.Time.Round),
+		"Truncate":   timeRounder("Truncate", time.Time.Truncate),
+	}, methods{
+		"add":  timeAdd,
+		"sub":  timeSub,
+		"eq":   timeCompare(func(c int) bool { return c == 0 }),
+		"lt":   timeCompare(func(c int) bool { return c < 0 }),
+		"le":   timeCompare(func(c int) bool { return c <= 0 }),
+		"gt":   timeCompare(func(c int) bool { return c > 0 }),
+		"ge":   timeCompare(func(c int) bool { return c >= 0 }),
+		"str":  timeStr,
+		"hash": timeHash,
+	}, scalar...)
+
+	durationMethods = objectMethods.extend(methods{
+		"Hours":        durationGetter(unitFraction(time.Hour)),
+		"Minutes":      durationGetter(unitFraction(time.Minute)),
+		"Seconds":      durationGetter(unitFraction(time.Second)),
+		"Milliseconds": durationGetter(unitCount(time.Millisecond)),
+		"Microseconds": durationGetter(unitCount(time.Microsecond)),
+		"Nanoseconds":  durationGetter(unitCount(time.Nanosecond)),
+		"Round":        durationRounder("Round", time.Duration.Round),
+		"Truncate":     durationRounder("Truncate", time.Duration.Truncate),
+	}, methods{
+		"add":  durationAdd,
+		"sub":  durationSub,
+		"mul":  durationMul,
+		"div":  durationDiv,
+		"mod":  durationMod,
+		"neg":  durationNeg,
+		"eq":   durationCompare(func(a time.Duration, b time.Duration) bool { return a == b }),
+		"lt":   durationCompare(func(a time.Duration, b time.Duration) bool { return a < b }),
+		"le":   durationCompare(func(a time.Duration, b time.Duration) bool { return a <= b }),
+		"gt":   durationCompare(func(a time.Duration, b time.Duration) bool { return a > b }),
+		"ge":   durationCompare(func(a time.Duration, b time.Duration) bool { return a >= b }),
+		"str":  durationStr,
+		"hash": durationHash,
+		"bool": durationBool,
+	}, scalar...)
+}
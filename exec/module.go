@@ -5,61 +5,328 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"github.com/AnthonyEdvalson/owl/manifest"
+)
+
+// golib holds the modules implemented in Go, by name. Each program builds
+// its own instance of a module, so that modules with state, such as test,
+// are not shared between programs.
+var (
+	golibMu sync.RWMutex
+	golib   = map[string]func(t *TreeExecutor) *OwlObj{}
 )
 
-// TODO use plugins to dynamically import go modules
-var golib = map[string]*OwlObj{
-	"lib_http": HttpLibExport(),
-	"fs":       FsLibExport(),
-	"os":       OsLibExport(),
+func init() {
+	golib["lib_http"] = HttpLibExport
+	golib["fs"] = FsLibExport
+	golib["math"] = MathLibExport
+	golib["os"] = OsLibExport
+	golib["regex"] = RegexLibExport
+	golib["test"] = TestLibExport
+	golib["time"] = TimeLibExport
 }
 
-func NewModule(name string, currentPath string) (*OwlObj, string) {
-	pathStr := name
+// RegisterModule makes a module implemented in Go importable by name, such
+// as import "name". ctor is called once by each program that imports the
+// module. It is intended to be called from the init function of a package
+// linked into a custom owl binary, and panics if the name is taken or is not
+// a valid module name.
+func RegisterModule(name string, ctor func() *OwlObj) {
+	if ctor == nil {
+		panic("exec: RegisterModule constructor is nil for " + name)
+	}
 
-	if pathStr[0] == '.' {
-		dir := filepath.Dir(currentPath)
-		pathStr = filepath.Clean(filepath.Join(dir, pathStr+".hoot"))
-	} else if pathStr[0] == '/' {
-		pathStr = filepath.Clean(pathStr + ".hoot")
-	} else {
-		lib, ok := golib[name]
+	RegisterExecModule(name, func(t *TreeExecutor) *OwlObj { return ctor() })
+}
 
-		if ok {
-			return lib, pathStr
-		}
-		ex, err := os.Executable()
+// RegisterExecModule registers a module like RegisterModule, for modules
+// that need the executor importing them, for example to respect its
+// context or policy.
+func RegisterExecModule(name string, ctor func(t *TreeExecutor) *OwlObj) {
+	if name == "" || isPathImport(name) || strings.Contains(name, "/") {
+		panic("exec: invalid module name '" + name + "'")
+	}
+
+	if ctor == nil {
+		panic("exec: RegisterExecModule constructor is nil for " + name)
+	}
+
+	golibMu.Lock()
+	defer golibMu.Unlock()
+
+	if _, ok := golib[name]; ok {
+		panic("exec: RegisterModule called twice for " + name)
+	}
+
+	golib[name] = ctor
+}
+
+func goModule(name string) (func(t *TreeExecutor) *OwlObj, bool) {
+	golibMu.RLock()
+	defer golibMu.RUnlock()
+
+	ctor, ok := golib[name]
+	return ctor, ok
+}
+
+// OWL_PATH is the environment variable listing extra directories to search
+// for modules, separated like PATH.
+const OWL_PATH = "OWL_PATH"
+
+// registry holds the modules a program has imported, keyed by the absolute
+// path of their file, or by name for Go modules. loading is the chain of
+// files currently being imported, used to detect cycles. project is the
+// manifest of the project the program's main file is in, if any.
+type registry struct {
+	modules map[string]*OwlObj
+	loading []string
+	root    string
+	project *manifest.Manifest
+	loaded  bool
+}
+
+func newRegistry(root string) *registry {
+	return &registry{modules: map[string]*OwlObj{}, root: root}
+}
+
+// dependency returns the file for an import of one of the project's
+// dependencies. "dep" imports the dependency's main.hoot, and "dep/util"
+// imports its util.hoot.
+func (r *registry) dependency(name string) (string, bool, error) {
+	if !r.loaded {
+		project, err := manifest.Find(filepath.Dir(r.root))
 		if err != nil {
-			panic(err)
+			return "", false, err
+		}
+
+		r.project = project
+		r.loaded = true
+	}
+
+	if r.project == nil {
+		return "", false, nil
+	}
+
+	dep, file := name, "main"
+	if i := strings.Index(name, "/"); i >= 0 {
+		dep, file = name[:i], name[i+1:]
+	}
+
+	dir, ok, err := r.project.ModuleDir(dep)
+	if !ok || err != nil {
+		return "", false, err
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(file)+".hoot"), true, nil
+}
+
+func isPathImport(name string) bool {
+	return name[0] == '.' || name[0] == '/'
+}
+
+// modulePath returns the file a relative or absolute import in the file at
+// from refers to.
+func modulePath(name string, from string) string {
+	if name[0] == '.' {
+		dir := filepath.Dir(from)
+		return filepath.Clean(filepath.Join(dir, name+".hoot"))
+	}
+
+	return filepath.Clean(name + ".hoot")
+}
+
+// searchPath returns the directories searched for library modules imported
+// by the file at path, in order. These are the lib directories in the
+// file's directory and each of its parents up to the project root, the
+// directory with the owl.json manifest, then the directories in OWL_PATH,
+// and the lib directory next to the Owl executable. Outside of a project,
+// only the lib directory next to the file is searched.
+func searchPath(path string) []string {
+	dirs := []string{}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err == nil {
+		libs := []string{}
+		for {
+			libs = append(libs, filepath.Join(dir, "lib"))
+
+			if _, err := os.Stat(filepath.Join(dir, manifest.MANIFEST_FILE)); err == nil {
+				break
+			}
+
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				libs = libs[:1]
+				break
+			}
+			dir = parent
+		}
+
+		dirs = append(dirs, libs...)
+	}
+
+	for _, d := range filepath.SplitList(os.Getenv(OWL_PATH)) {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+
+	if ex, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(ex), "lib"))
+	}
+
+	return dirs
+}
+
+// findModule returns the file an import in the file at from refers to, see
+// execImportStatement for the formats of names. Dependencies declared in the
+// project's manifest are found before modules in lib directories.
+func findModule(name string, from string, reg *registry) (string, error) {
+	if isPathImport(name) {
+		return modulePath(name, from), nil
+	}
+
+	path, ok, err := reg.dependency(name)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return path, nil
+	}
+
+	dirs := searchPath(from)
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name+".hoot")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unable to find module '%s', searched %s", name, strings.Join(dirs, ", "))
+}
+
+// ResolveModule returns the file that an import of name in the file at from
+// refers to, found the same way as by the import statement. isGo is set
+// instead for modules implemented in Go, which have no file.
+func ResolveModule(name string, from string) (path string, isGo bool, err error) {
+	if !isPathImport(name) {
+		if _, ok := goModule(name); ok {
+			return "", true, nil
+		}
+	}
+
+	path, err = findModule(name, from, newRegistry(from))
+	return path, false, err
+}
+
+// displayPath shortens path for error messages, relative to the working
+// directory when it is inside of it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return rel
+}
+
+// NewModule imports the module name into the program t is running, and
+// returns it along with the name it is imported as by default. Each module
+// is only loaded once per program, later imports of the same file get the
+// same module.
+func NewModule(name string, t *TreeExecutor) (*OwlObj, string, error) {
+	reg := t.run.modules
+
+	if !isPathImport(name) {
+		if lib, ok := goModule(name); ok {
+			key := "go:" + name
+			if _, ok := reg.modules[key]; !ok {
+				reg.modules[key] = lib(t)
+			}
+			return reg.modules[key], name, nil
 		}
-		exPath := filepath.Dir(ex)
-		pathStr = filepath.Join(exPath, "lib", pathStr+".hoot")
 	}
 
-	alias := strings.TrimSuffix(filepath.Base(pathStr), ".hoot")
+	pathStr, err := findModule(name, t.currentPath, reg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	alias := filepath.Base(filepath.FromSlash(name))
+
+	key, err := filepath.Abs(pathStr)
+	if err != nil {
+		key = pathStr
+	}
+
+	if o, ok := reg.modules[key]; ok {
+		return o, alias, nil
+	}
+
+	// The program that started the import chain is not imported itself, but
+	// importing it would still be a cycle
+	loading := reg.loading
+	if len(loading) == 0 {
+		if root, err := filepath.Abs(t.currentPath); err == nil {
+			loading = []string{root}
+		}
+	}
+
+	for i, p := range loading {
+		if p != key {
+			continue
+		}
+
+		chain := []string{}
+		for _, c := range append(loading[i:], key) {
+			chain = append(chain, displayPath(c))
+		}
+
+		return nil, "", fmt.Errorf("Import cycle: %s", strings.Join(chain, " -> "))
+	}
 
 	ok, params, parseErr := LoadProgramFromPath(pathStr)
 
 	if !ok && parseErr == nil {
-		panic("Failed to locate module: " + name)
+		return nil, "", fmt.Errorf("Failed to locate module: %s", name)
 	}
 
 	if !ok && len(parseErr) > 0 {
-		for _, e := range parseErr {
-			fmt.Println(e.Token.File + ":" + fmt.Sprint(e.Token.Line) + ":" + fmt.Sprint(e.Token.Column) + ": " + e.Message)
-		}
-
-		panic("Failed to load module: " + name)
+		return nil, "", fmt.Errorf("Failed to load module: %s\n%s", name, strings.TrimRight(FormatParserErrors(pathStr, parseErr), "\n"))
 	}
 
-	_, e := ExecuteProgram(params)
+	previous := reg.loading
+	reg.loading = append(loading, key)
+	defer func() { reg.loading = previous }()
+
+	e := t.child(params.Path)
+	e.ExecProgram(params.Program, params.Globals)
 
 	o := NewOwlObj()
 	o.Attr = e.Frames[0]
+
+	// Modules that export names only expose those names, the values are
+	// those they had when the module finished running
+	if len(e.exports) > 0 {
+		o.Attr = Frame{}
+		for _, name := range e.exports {
+			o.Attr[name] = e.Frames[0][name]
+		}
+	}
 	o.SetDeepAttr("name", NewString(alias))
 	o.SetDeepAttr("str", NewCallBridge(moduleStr))
 
-	return o, alias
+	reg.modules[key] = o
+
+	return o, alias, nil
 }
 
 func moduleStr(args []*OwlObj) (*OwlObj, bool) {
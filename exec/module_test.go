@@ -0,0 +1,253 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/internal/testutil"
+)
+
+func runFile(t *testing.T, path string) (*OwlObj, error) {
+	ok, params, errs := LoadProgramFromPath(path)
+	if !ok {
+		t.Fatalf("Failed to load %s: %v", path, errs)
+	}
+
+	e := NewTreeExecutor(params.Path)
+
+	var result *OwlObj
+	err := Try(func() { result = e.ExecProgram(params.Program, params.Globals) })
+
+	return result, err
+}
+
+func TestModuleCache(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"counter.hoot": "state = {count: 0}\nstate.count++",
+		"a.hoot":       "import \"./counter\"\nbump = () => counter.state.count++",
+		"main.hoot":    "import \"./counter\"\nimport \"./a\"\nimport \"./counter\" as c\na.bump()\nreturn [counter.state.count, c.state.count]",
+	})
+
+	v, err := runFile(t, filepath.Join(dir, "main.hoot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.TrueStr() != "[2, 2]" {
+		t.Errorf("Expected the module to run once and be shared, got %s", v.TrueStr())
+	}
+}
+
+func TestModuleCycle(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"a.hoot":    "import \"./b\"",
+		"b.hoot":    "import \"./c\"",
+		"c.hoot":    "import \"./a\"",
+		"main.hoot": "import \"./a\"",
+		"self.hoot": "import \"./self\"",
+	})
+
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	_, err := runFile(t, filepath.Join(dir, "main.hoot"))
+	if err == nil || !strings.Contains(err.Error(), "Import cycle: a.hoot -> b.hoot -> c.hoot -> a.hoot") {
+		t.Errorf("Expected an import cycle error, got %v", err)
+	}
+
+	_, err = runFile(t, filepath.Join(dir, "self.hoot"))
+	if err == nil || !strings.Contains(err.Error(), "Import cycle: self.hoot -> self.hoot") {
+		t.Errorf("Expected an import cycle error, got %v", err)
+	}
+}
+
+func TestModuleSearchPath(t *testing.T) {
+	dir := t.TempDir()
+	extra := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"lib/outside.hoot":         "x = \"outside\"",
+		"app/owl.json":             "{}",
+		"app/lib/shared.hoot":      "x = \"root\"",
+		"app/lib/project.hoot":     "x = \"project\"",
+		"app/src/lib/local.hoot":   "x = \"local\"",
+		"app/src/lib/shared.hoot":  "x = \"src\"",
+		"app/src/main.hoot":        "import \"local\"\nimport \"shared\"\nimport \"project\"\nimport \"extra\" as e\nreturn [local.x, shared.x, project.x, e.x]",
+		"app/src/missing.hoot":     "import \"nope\"",
+		"app/src/outside.hoot":     "import \"outside\"",
+		"loose/src/lib/local.hoot": "x = \"loose\"",
+		"loose/src/main.hoot":      "import \"local\"\nreturn local.x",
+		"loose/src/outside.hoot":   "import \"outside\"",
+	})
+	testutil.WriteFiles(t, extra, map[string]string{
+		"extra.hoot": "x = \"extra\"",
+	})
+
+	t.Setenv(OWL_PATH, extra)
+
+	v, err := runFile(t, filepath.Join(dir, "app", "src", "main.hoot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.TrueStr() != "[local, src, project, extra]" {
+		t.Errorf("Unexpected result %s", v.TrueStr())
+	}
+
+	v, err = runFile(t, filepath.Join(dir, "loose", "src", "main.hoot"))
+	if err != nil || v.TrueStr() != "loose" {
+		t.Errorf("Expected the module next to the file, got %v, %v", v, err)
+	}
+
+	_, err = runFile(t, filepath.Join(dir, "app", "src", "missing.hoot"))
+	if err == nil || !strings.Contains(err.Error(), "Unable to find module 'nope', searched "+filepath.Join(dir, "app", "src", "lib")+", "+filepath.Join(dir, "app", "lib")+", "+extra) {
+		t.Errorf("Expected a missing module error, got %v", err)
+	}
+
+	for _, file := range []string{"app/src/outside.hoot", "loose/src/outside.hoot"} {
+		_, err = runFile(t, filepath.Join(dir, filepath.FromSlash(file)))
+		if err == nil || !strings.Contains(err.Error(), "Unable to find module 'outside'") {
+			t.Errorf("%s: expected lib directories outside the project to be skipped, got %v", file, err)
+		}
+	}
+}
+
+func TestModuleExports(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"util.hoot":    "helper = (x) => x * 2\nexport Double = (x) => helper(x)\nexport Name, Other = \"util\", 1\nCount = 3\nexport Count",
+		"open.hoot":    "a = 1\nb = 2",
+		"main.hoot":    "import \"./util\"\nfrom \"./util\" import Double, Name as n\nfrom \"./open\" import b\nreturn [util.Double(2), Double(3), n, util.Count, b]",
+		"hidden.hoot":  "from \"./util\" import helper",
+		"private.hoot": "import \"./util\"\nutil.helper(1)",
+		"nested.hoot":  "f = () => {\n    export x = 1\n}\nf()",
+		"missing.hoot": "export y",
+	})
+
+	v, err := runFile(t, filepath.Join(dir, "main.hoot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.TrueStr() != "[4, 6, util, 3, 2]" {
+		t.Errorf("Unexpected result %s", v.TrueStr())
+	}
+
+	tests := []struct {
+		file string
+		err  string
+	}{
+		{"hidden.hoot", "Module './util' does not export 'helper'"},
+		{"private.hoot", "Unable to evaluate attribute 'util.helper'"},
+		{"nested.hoot", "export can only be used at the top level of a module"},
+		{"missing.hoot", "Unable to find variable 'y'"},
+	}
+
+	for _, tt := range tests {
+		_, err := runFile(t, filepath.Join(dir, tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.file, tt.err, err)
+		}
+	}
+}
+
+func TestModuleDependencies(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"app/owl.json":     `{"name": "app", "dependencies": {"greet": "../greet"}}`,
+		"app/main.hoot":    "import \"greet\"\nimport \"greet/names\"\nreturn greet.Hello(names.First)",
+		"greet/main.hoot":  "export Hello = (n) => \"Hello, \" + n",
+		"greet/names.hoot": "export First = \"owl\"",
+	})
+
+	app := filepath.Join(dir, "app")
+
+	v, err := runFile(t, filepath.Join(app, "main.hoot"))
+	if err != nil || v.TrueStr() != "Hello, owl" {
+		t.Fatalf("Expected the dependency to be imported, got %v, %v", v, err)
+	}
+
+	testutil.WriteFiles(t, app, map[string]string{"vendor/greet/main.hoot": "export Hello = (n) => \"Hi, \" + n", "vendor/greet/names.hoot": "export First = \"hoot\""})
+
+	v, err = runFile(t, filepath.Join(app, "main.hoot"))
+	if err != nil || v.TrueStr() != "Hi, hoot" {
+		t.Errorf("Expected the vendored dependency to be imported, got %v, %v", v, err)
+	}
+}
+
+type counter struct {
+	Start int
+	Label string `owl:"label"`
+	count int
+}
+
+func (c *counter) Add(n int) int {
+	c.count += n
+	return c.count
+}
+
+func TestRegisterModule(t *testing.T) {
+	RegisterModule("test_counter", func() *OwlObj {
+		return MustModuleFrom(&counter{Start: 5, Label: "c"})
+	})
+	RegisterModule("test_math", func() *OwlObj {
+		return MustModuleFrom(map[string]interface{}{
+			"Double": func(x float64) float64 { return x * 2 },
+			"Zero":   0,
+		})
+	})
+
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"other.hoot": "import \"test_counter\"\nexport n = test_counter.Add(2)",
+		"main.hoot":  "import \"test_counter\"\nimport \"./other\"\nfrom \"test_math\" import Double\nreturn [test_counter.Start, test_counter.label, other.n, test_counter.Add(3), Double(4)]",
+	})
+
+	v, err := runFile(t, filepath.Join(dir, "main.hoot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.TrueStr() != "[5, c, 2, 5, 8]" {
+		t.Errorf("Unexpected result %s", v.TrueStr())
+	}
+
+	// Each program gets its own instance
+	v, err = runFile(t, filepath.Join(dir, "main.hoot"))
+	if err != nil || v.TrueStr() != "[5, c, 2, 5, 8]" {
+		t.Errorf("Expected a new module instance, got %v, %v", v, err)
+	}
+
+	panics := []func(){
+		func() { RegisterModule("test_counter", func() *OwlObj { return NewOwlObj() }) },
+		func() { RegisterModule("fs", func() *OwlObj { return NewOwlObj() }) },
+		func() { RegisterModule("./local", func() *OwlObj { return NewOwlObj() }) },
+		func() { RegisterModule("a/b", func() *OwlObj { return NewOwlObj() }) },
+		func() { RegisterModule("test_nil", nil) },
+	}
+
+	for i, f := range panics {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registration %d to panic", i)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestModuleFrom(t *testing.T) {
+	tests := []interface{}{nil, 5, []int{1}, (*counter)(nil)}
+
+	for _, v := range tests {
+		if _, err := ModuleFrom(v); err == nil {
+			t.Errorf("Expected building a module from %#v to fail", v)
+		}
+	}
+}
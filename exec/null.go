@@ -1,25 +1,7 @@
 package exec
 
-var nullCache *OwlObj
-
 func NewNull() *OwlObj {
-	if nullCache != nil {
-		return nullCache
-	}
-
-	n := NewOwlObj()
-	n.SetDeepAttr("eq", NewCallBridge(nullEq))
-	n.SetDeepAttr("ne", NewCallBridge(nullNe))
-	n.SetDeepAttr("str", NewCallBridge(nullStr))
-	n.SetDeepAttr("null", NewBool(true))
-	n.DeleteDeepAttr("iter")
-	n.DeleteDeepAttr("index")
-	n.DeleteDeepAttr("setIndex")
-	n.DeleteDeepAttr("has")
-
-	nullCache = n
-
-	return n
+	return nullValue
 }
 
 func nullEq(args []*OwlObj) (*OwlObj, bool) {
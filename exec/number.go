@@ -2,10 +2,27 @@ package exec
 
 import (
 	"math"
+	"math/big"
 	"strconv"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
 )
 
 func NewInt(n int64) *OwlObj {
+	if n >= SMALL_INT_MIN && n <= SMALL_INT_MAX {
+		return smallInts[n-SMALL_INT_MIN]
+	}
+
+	return NewNumber(n)
+}
+
+// NewBigInt creates an integer too large for an int64. Integers that fit
+// are stored as an int64 instead, so that each integer has one form.
+func NewBigInt(n *big.Int) *OwlObj {
+	if n.IsInt64() {
+		return NewInt(n.Int64())
+	}
+
 	return NewNumber(n)
 }
 
@@ -13,16 +30,30 @@ func NewFloat(n float64) *OwlObj {
 	return NewNumber(n)
 }
 
+func NewDecimal(d decimal.Decimal) *OwlObj {
+	return NewNumber(d)
+}
+
+// Numbers are ordered by how they are promoted, an operation on two numbers
+// converts the lower one to the kind of the higher one. Decimals and floats
+// are not mixed, since converting either would lose the exactness of the
+// decimal or the range of the float.
 const (
 	INT     = 0
-	FLOAT   = 1
-	UNKNOWN = 2
+	BIG     = 1
+	DECIMAL = 2
+	FLOAT   = 3
+	UNKNOWN = 4
 )
 
 func getRawType(obj *OwlObj) byte {
 	switch obj.Raw.(type) {
 	case int64:
 		return INT
+	case *big.Int:
+		return BIG
+	case decimal.Decimal:
+		return DECIMAL
 	case float64:
 		return FLOAT
 	default:
@@ -30,48 +61,81 @@ func getRawType(obj *OwlObj) byte {
 	}
 }
 
+// numberKind is the kind that a and b are converted to before an operation
+// on both of them.
+func numberKind(a *OwlObj, b *OwlObj) byte {
+	aType := getRawType(a)
+	bType := getRawType(b)
+
+	if aType == UNKNOWN || bType == UNKNOWN {
+		return UNKNOWN
+	}
+
+	if (aType == DECIMAL && bType == FLOAT) || (aType == FLOAT && bType == DECIMAL) {
+		return UNKNOWN
+	}
+
+	if aType > bType {
+		return aType
+	}
+
+	return bType
+}
+
+func toBig(o *OwlObj) *big.Int {
+	if v, ok := o.Raw.(int64); ok {
+		return big.NewInt(v)
+	}
+
+	return o.Raw.(*big.Int)
+}
+
+func toDecimal(o *OwlObj) decimal.Decimal {
+	switch v := o.Raw.(type) {
+	case int64:
+		return decimal.FromInt(v)
+	case *big.Int:
+		return decimal.FromBig(v)
+	}
+
+	return o.Raw.(decimal.Decimal)
+}
+
+func toFloat(o *OwlObj) float64 {
+	switch v := o.Raw.(type) {
+	case int64:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case decimal.Decimal:
+		return v.Float64()
+	}
+
+	return o.Raw.(float64)
+}
+
 func NewNumber(v interface{}) *OwlObj {
-	n := NewOwlObj()
-
-	n.Raw = v
-	n.SetDeepAttr("add", NewCallBridge(numberAdd))
-	n.SetDeepAttr("sub", NewCallBridge(numberSub))
-	n.SetDeepAttr("mul", NewCallBridge(numberMul))
-	n.SetDeepAttr("div", NewCallBridge(numberDiv))
-	n.SetDeepAttr("pow", NewCallBridge(numberPow))
-	n.SetDeepAttr("mod", NewCallBridge(numberMod))
-	n.SetDeepAttr("neg", NewCallBridge(numberNeg))
-	n.SetDeepAttr("inc", NewCallBridge(numberInc))
-	n.SetDeepAttr("dec", NewCallBridge(numberDec))
-	n.SetDeepAttr("eq", NewCallBridge(numberEq))
-	n.SetDeepAttr("ne", NewCallBridge(numberNe))
-	n.SetDeepAttr("lt", NewCallBridge(numberLt))
-	n.SetDeepAttr("le", NewCallBridge(numberLe))
-	n.SetDeepAttr("gt", NewCallBridge(numberGt))
-	n.SetDeepAttr("ge", NewCallBridge(numberGe))
-	n.SetDeepAttr("str", NewCallBridge(numberStr))
-	n.DeleteDeepAttr("iter")
-	n.DeleteDeepAttr("index")
-	n.DeleteDeepAttr("setIndex")
-	n.DeleteDeepAttr("has")
-
-	return n
+	return &OwlObj{Raw: v, methods: numberMethods}
 }
 
 func numberAdd(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewInt(a.Raw.(int64) + b.Raw.(int64)), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewFloat(a.Raw.(float64) + b.Raw.(float64)), true
-	} else if aType == INT && bType == FLOAT {
-		return NewFloat(float64(a.Raw.(int64)) + b.Raw.(float64)), true
-	} else if aType == FLOAT && bType == INT {
-		return NewFloat(a.Raw.(float64) + float64(b.Raw.(int64))), true
+	switch numberKind(a, b) {
+	case INT:
+		x, y := a.Raw.(int64), b.Raw.(int64)
+		if s := x + y; (s > x) == (y > 0) {
+			return NewInt(s), true
+		}
+		return NewBigInt(new(big.Int).Add(toBig(a), toBig(b))), true
+	case BIG:
+		return NewBigInt(new(big.Int).Add(toBig(a), toBig(b))), true
+	case DECIMAL:
+		return NewDecimal(toDecimal(a).Add(toDecimal(b))), true
+	case FLOAT:
+		return NewFloat(toFloat(a) + toFloat(b)), true
 	}
 
 	return nil, false
@@ -80,17 +144,20 @@ func numberAdd(args []*OwlObj) (*OwlObj, bool) {
 func numberSub(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewInt(a.Raw.(int64) - b.Raw.(int64)), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewFloat(a.Raw.(float64) - b.Raw.(float64)), true
-	} else if aType == INT && bType == FLOAT {
-		return NewFloat(float64(a.Raw.(int64)) - b.Raw.(float64)), true
-	} else if aType == FLOAT && bType == INT {
-		return NewFloat(a.Raw.(float64) - float64(b.Raw.(int64))), true
+	switch numberKind(a, b) {
+	case INT:
+		x, y := a.Raw.(int64), b.Raw.(int64)
+		if d := x - y; (d < x) == (y > 0) {
+			return NewInt(d), true
+		}
+		return NewBigInt(new(big.Int).Sub(toBig(a), toBig(b))), true
+	case BIG:
+		return NewBigInt(new(big.Int).Sub(toBig(a), toBig(b))), true
+	case DECIMAL:
+		return NewDecimal(toDecimal(a).Sub(toDecimal(b))), true
+	case FLOAT:
+		return NewFloat(toFloat(a) - toFloat(b)), true
 	}
 
 	return nil, false
@@ -99,68 +166,145 @@ func numberSub(args []*OwlObj) (*OwlObj, bool) {
 func numberMul(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewInt(a.Raw.(int64) * b.Raw.(int64)), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewFloat(a.Raw.(float64) * b.Raw.(float64)), true
-	} else if aType == INT && bType == FLOAT {
-		return NewFloat(float64(a.Raw.(int64)) * b.Raw.(float64)), true
-	} else if aType == FLOAT && bType == INT {
-		return NewFloat(a.Raw.(float64) * float64(b.Raw.(int64))), true
+	switch numberKind(a, b) {
+	case INT:
+		x, y := a.Raw.(int64), b.Raw.(int64)
+		if x == 0 || y == 0 {
+			return NewInt(0), true
+		}
+		if p := x * y; p/y == x && !(x == math.MinInt64 && y == -1) {
+			return NewInt(p), true
+		}
+		return NewBigInt(new(big.Int).Mul(toBig(a), toBig(b))), true
+	case BIG:
+		return NewBigInt(new(big.Int).Mul(toBig(a), toBig(b))), true
+	case DECIMAL:
+		return NewDecimal(toDecimal(a).Mul(toDecimal(b))), true
+	case FLOAT:
+		return NewFloat(toFloat(a) * toFloat(b)), true
 	}
 
 	return nil, false
 }
 
+// numberDiv divides a by b. Dividing integers gives a float, use // for
+// integer division.
 func numberDiv(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
-
-	var aFloat, bFloat float64
 
-	if aType == INT {
-		aFloat = float64(a.Raw.(int64))
-	} else if aType == FLOAT {
-		aFloat = a.Raw.(float64)
-	} else {
-		return nil, false
+	switch numberKind(a, b) {
+	case INT, FLOAT:
+		return NewFloat(toFloat(a) / toFloat(b)), true
+	case BIG:
+		if toBig(b).Sign() == 0 {
+			return NewFloat(toFloat(a) / 0), true
+		}
+		f, _ := new(big.Rat).SetFrac(toBig(a), toBig(b)).Float64()
+		return NewFloat(f), true
+	case DECIMAL:
+		if v, err := toDecimal(a).Div(toDecimal(b)); err == nil {
+			return NewDecimal(v), true
+		}
 	}
 
-	if bType == INT {
-		bFloat = float64(b.Raw.(int64))
-	} else if bType == FLOAT {
-		bFloat = b.Raw.(float64)
-	} else {
-		return nil, false
+	return nil, false
+}
+
+// numberIntDiv divides a by b, rounding down. Dividing by zero fails.
+func numberIntDiv(args []*OwlObj) (*OwlObj, bool) {
+	a := args[1]
+	b := args[2]
+
+	switch numberKind(a, b) {
+	case INT:
+		x, y := a.Raw.(int64), b.Raw.(int64)
+		if y == 0 {
+			return nil, false
+		}
+		if x == math.MinInt64 && y == -1 {
+			return NewBigInt(new(big.Int).Neg(toBig(a))), true
+		}
+		q := x / y
+		if x%y != 0 && (x < 0) != (y < 0) {
+			q--
+		}
+		return NewInt(q), true
+	case BIG:
+		x, y := toBig(a), toBig(b)
+		if y.Sign() == 0 {
+			return nil, false
+		}
+		q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+		if r.Sign() != 0 && (r.Sign() < 0) != (y.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		}
+		return NewBigInt(q), true
+	case DECIMAL:
+		if v, err := toDecimal(a).IntDiv(toDecimal(b)); err == nil {
+			return NewDecimal(v), true
+		}
+	case FLOAT:
+		return NewFloat(math.Floor(toFloat(a) / toFloat(b))), true
 	}
 
-	return NewFloat(aFloat / bFloat), true
+	return nil, false
 }
 
+// numberPow raises a to the power b. Integers raised to non-negative integer
+// powers are exact, negative powers give a float, or a decimal for decimals.
 func numberPow(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewInt(int64(math.Pow(float64(a.Raw.(int64)), float64(b.Raw.(int64))))), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewFloat(math.Pow(a.Raw.(float64), b.Raw.(float64))), true
-	} else if aType == INT && bType == FLOAT {
-		return NewFloat(math.Pow(float64(a.Raw.(int64)), b.Raw.(float64))), true
-	} else if aType == FLOAT && bType == INT {
-		return NewFloat(math.Pow(a.Raw.(float64), float64(b.Raw.(int64)))), true
+	switch numberKind(a, b) {
+	case INT, BIG:
+		if toBig(b).Sign() < 0 {
+			return NewFloat(math.Pow(toFloat(a), toFloat(b))), true
+		}
+		if getRawType(b) == BIG {
+			return nil, false
+		}
+		return NewBigInt(new(big.Int).Exp(toBig(a), toBig(b), nil)), true
+	case DECIMAL:
+		n, ok := toDecimal(b).Int()
+		if !ok || !n.IsInt64() {
+			return nil, false
+		}
+		return decimalPow(toDecimal(a), n.Int64())
+	case FLOAT:
+		return NewFloat(math.Pow(toFloat(a), toFloat(b))), true
 	}
 
 	return nil, false
 }
 
+func decimalPow(d decimal.Decimal, n int64) (*OwlObj, bool) {
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+
+	v := decimal.FromInt(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			v = v.Mul(d)
+		}
+		d = d.Mul(d)
+	}
+
+	if negative {
+		inv, err := decimal.FromInt(1).Div(v)
+		if err != nil {
+			return nil, false
+		}
+		v = inv
+	}
+
+	return NewDecimal(v), true
+}
+
 func betterModInt(a int64, b int64) int64 {
 	v := a % b
 
@@ -184,76 +328,166 @@ func betterModFloat(a float64, b float64) float64 {
 func numberMod(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		v := betterModInt(a.Raw.(int64), b.Raw.(int64))
-		return NewInt(v), true
-	} else if aType == FLOAT && bType == FLOAT {
-		v := betterModFloat(a.Raw.(float64), b.Raw.(float64))
-		return NewFloat(v), true
-	} else if aType == INT && bType == FLOAT {
-		v := betterModFloat(float64(a.Raw.(int64)), b.Raw.(float64))
-		return NewFloat(v), true
-	} else if aType == FLOAT && bType == INT {
-		v := betterModFloat(a.Raw.(float64), float64(b.Raw.(int64)))
-		return NewFloat(v), true
+	switch numberKind(a, b) {
+	case INT:
+		if b.Raw.(int64) == 0 {
+			return nil, false
+		}
+		return NewInt(betterModInt(a.Raw.(int64), b.Raw.(int64))), true
+	case BIG:
+		y := toBig(b)
+		if y.Sign() == 0 {
+			return nil, false
+		}
+		v := new(big.Int).Rem(toBig(a), y)
+		if v.Sign() < 0 {
+			v.Add(v, y)
+		}
+		return NewBigInt(v), true
+	case DECIMAL:
+		if v, err := toDecimal(a).Mod(toDecimal(b)); err == nil {
+			return NewDecimal(v), true
+		}
+	case FLOAT:
+		return NewFloat(betterModFloat(toFloat(a), toFloat(b))), true
 	}
 
 	return nil, false
 }
 
-func numberNeg(args []*OwlObj) (*OwlObj, bool) {
-	aType := getRawType(args[0])
+// integers returns a and b as big ints, for the bitwise operators, which
+// only apply to integers.
+func integers(a *OwlObj, b *OwlObj) (*big.Int, *big.Int, bool) {
+	if k := numberKind(a, b); k != INT && k != BIG {
+		return nil, nil, false
+	}
 
-	if aType == INT {
-		return NewInt(-args[0].Raw.(int64)), true
-	} else if aType == FLOAT {
-		return NewFloat(-args[0].Raw.(float64)), true
+	return toBig(a), toBig(b), true
+}
+
+func numberAnd(args []*OwlObj) (*OwlObj, bool) {
+	if numberKind(args[1], args[2]) == INT {
+		return NewInt(args[1].Raw.(int64) & args[2].Raw.(int64)), true
 	}
 
-	return nil, false
+	x, y, ok := integers(args[1], args[2])
+	if !ok {
+		return nil, false
+	}
+
+	return NewBigInt(new(big.Int).And(x, y)), true
 }
 
-func numberInc(args []*OwlObj) (*OwlObj, bool) {
-	aType := getRawType(args[0])
+func numberOr(args []*OwlObj) (*OwlObj, bool) {
+	if numberKind(args[1], args[2]) == INT {
+		return NewInt(args[1].Raw.(int64) | args[2].Raw.(int64)), true
+	}
 
-	if aType == INT {
-		return NewInt(args[0].Raw.(int64) + 1), true
-	} else if aType == FLOAT {
-		return NewFloat(args[0].Raw.(float64) + 1), true
+	x, y, ok := integers(args[1], args[2])
+	if !ok {
+		return nil, false
 	}
 
-	return nil, false
+	return NewBigInt(new(big.Int).Or(x, y)), true
 }
 
-func numberDec(args []*OwlObj) (*OwlObj, bool) {
-	aType := getRawType(args[0])
+func numberXor(args []*OwlObj) (*OwlObj, bool) {
+	if numberKind(args[1], args[2]) == INT {
+		return NewInt(args[1].Raw.(int64) ^ args[2].Raw.(int64)), true
+	}
 
-	if aType == INT {
-		return NewInt(args[0].Raw.(int64) - 1), true
-	} else if aType == FLOAT {
-		return NewFloat(args[0].Raw.(float64) - 1), true
+	x, y, ok := integers(args[1], args[2])
+	if !ok {
+		return nil, false
+	}
+
+	return NewBigInt(new(big.Int).Xor(x, y)), true
+}
+
+// shift returns a as a big int, and b as a shift count, which can not be
+// negative.
+func shift(a *OwlObj, b *OwlObj) (*big.Int, uint, bool) {
+	x, y, ok := integers(a, b)
+	if !ok || y.Sign() < 0 || !y.IsInt64() || y.Int64() > math.MaxInt32 {
+		return nil, 0, false
+	}
+
+	return x, uint(y.Int64()), true
+}
+
+func numberShl(args []*OwlObj) (*OwlObj, bool) {
+	x, n, ok := shift(args[1], args[2])
+	if !ok {
+		return nil, false
+	}
+
+	return NewBigInt(new(big.Int).Lsh(x, n)), true
+}
+
+func numberShr(args []*OwlObj) (*OwlObj, bool) {
+	x, n, ok := shift(args[1], args[2])
+	if !ok {
+		return nil, false
+	}
+
+	return NewBigInt(new(big.Int).Rsh(x, n)), true
+}
+
+func numberNeg(args []*OwlObj) (*OwlObj, bool) {
+	switch v := args[0].Raw.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return NewBigInt(new(big.Int).Neg(toBig(args[0]))), true
+		}
+		return NewInt(-v), true
+	case *big.Int:
+		return NewBigInt(new(big.Int).Neg(v)), true
+	case decimal.Decimal:
+		return NewDecimal(v.Neg()), true
+	case float64:
+		return NewFloat(-v), true
 	}
 
 	return nil, false
 }
 
+func numberInc(args []*OwlObj) (*OwlObj, bool) {
+	return numberAdd([]*OwlObj{nil, args[0], NewInt(1)})
+}
+
+func numberDec(args []*OwlObj) (*OwlObj, bool) {
+	return numberSub([]*OwlObj{nil, args[0], NewInt(1)})
+}
+
+// numberCmp returns -1, 0 or 1 when a is less than, equal to or greater
+// than b. Floats are not compared here, since NaN is not ordered.
+func numberCmp(a *OwlObj, b *OwlObj, kind byte) int {
+	switch kind {
+	case INT:
+		x, y := a.Raw.(int64), b.Raw.(int64)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case BIG:
+		return toBig(a).Cmp(toBig(b))
+	default:
+		return toDecimal(a).Cmp(toDecimal(b))
+	}
+}
+
 func numberEq(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewBool(a.Raw.(int64) == b.Raw.(int64)), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewBool(a.Raw.(float64) == b.Raw.(float64)), true
-	} else if aType == INT && bType == FLOAT {
-		return NewBool(float64(a.Raw.(int64)) == b.Raw.(float64)), true
-	} else if aType == FLOAT && bType == INT {
-		return NewBool(a.Raw.(float64) == float64(b.Raw.(int64))), true
+	switch kind := numberKind(a, b); kind {
+	case INT, BIG, DECIMAL:
+		return NewBool(numberCmp(a, b, kind) == 0), true
+	case FLOAT:
+		return NewBool(toFloat(a) == toFloat(b)), true
 	}
 
 	return nil, false
@@ -272,17 +506,12 @@ func numberNe(args []*OwlObj) (*OwlObj, bool) {
 func numberLt(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewBool(a.Raw.(int64) < b.Raw.(int64)), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewBool(a.Raw.(float64) < b.Raw.(float64)), true
-	} else if aType == INT && bType == FLOAT {
-		return NewBool(float64(a.Raw.(int64)) < b.Raw.(float64)), true
-	} else if aType == FLOAT && bType == INT {
-		return NewBool(a.Raw.(float64) < float64(b.Raw.(int64))), true
+	switch kind := numberKind(a, b); kind {
+	case INT, BIG, DECIMAL:
+		return NewBool(numberCmp(a, b, kind) < 0), true
+	case FLOAT:
+		return NewBool(toFloat(a) < toFloat(b)), true
 	}
 
 	return nil, false
@@ -291,17 +520,12 @@ func numberLt(args []*OwlObj) (*OwlObj, bool) {
 func numberLe(args []*OwlObj) (*OwlObj, bool) {
 	a := args[1]
 	b := args[2]
-	aType := getRawType(a)
-	bType := getRawType(b)
 
-	if aType == INT && bType == INT {
-		return NewBool(a.Raw.(int64) <= b.Raw.(int64)), true
-	} else if aType == FLOAT && bType == FLOAT {
-		return NewBool(a.Raw.(float64) <= b.Raw.(float64)), true
-	} else if aType == INT && bType == FLOAT {
-		return NewBool(float64(a.Raw.(int64)) <= b.Raw.(float64)), true
-	} else if aType == FLOAT && bType == INT {
-		return NewBool(a.Raw.(float64) <= float64(b.Raw.(int64))), true
+	switch kind := numberKind(a, b); kind {
+	case INT, BIG, DECIMAL:
+		return NewBool(numberCmp(a, b, kind) <= 0), true
+	case FLOAT:
+		return NewBool(toFloat(a) <= toFloat(b)), true
 	}
 
 	return nil, false
@@ -328,12 +552,15 @@ func numberGe(args []*OwlObj) (*OwlObj, bool) {
 }
 
 func numberStr(args []*OwlObj) (*OwlObj, bool) {
-	aType := getRawType(args[0])
-
-	if aType == INT {
-		return NewString(strconv.FormatInt(args[0].Raw.(int64), 10)), true
-	} else if aType == FLOAT {
-		return NewString(strconv.FormatFloat(args[0].Raw.(float64), 'f', -1, 64)), true
+	switch v := args[0].Raw.(type) {
+	case int64:
+		return NewString(strconv.FormatInt(v, 10)), true
+	case *big.Int:
+		return NewString(v.String()), true
+	case decimal.Decimal:
+		return NewString(v.String()), true
+	case float64:
+		return NewString(strconv.FormatFloat(v, 'f', -1, 64)), true
 	}
 
 	return nil, false
@@ -0,0 +1,98 @@
+package exec
+
+// ObjectLibExport builds the Object builtin, which creates objects that share
+// attributes through prototypes:
+//
+//	Animal = {speak: () => this.name + " makes a sound"}
+//	Dog = Object.Extend(Animal, {speak: () => super.speak() + ", woof"})
+//	rex = Object.Extend(Dog, {name: "Rex"})
+//	Object.IsA(rex, Animal) == true
+func ObjectLibExport() *OwlObj {
+	o := NewOwlObj()
+
+	o.SetAttr("Extend", NewCallBridge(objectExtend))
+	o.SetAttr("Proto", NewCallBridge(objectProto))
+	o.SetAttr("IsA", NewCallBridge(objectIsA))
+	o.SetAttr("Set", NewCallBridge(objectSet))
+
+	return o
+}
+
+// objectExtend creates an object whose prototype is args[1], with the
+// attributes of args[2] if given. Operators defined on the prototype with
+// :: are used before the defaults every object has.
+func objectExtend(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) < 2 || len(args) > 3 {
+		return NewString("Object.Extend expects a prototype and optionally attributes"), false
+	}
+
+	proto := args[1]
+
+	var o *OwlObj
+	if proto.IsNullish() {
+		o = NewOwlObj()
+	} else if isPlainObj(proto) {
+		o = &OwlObj{Attr: map[string]*OwlObj{}, Proto: proto, methods: objectMethods}
+	} else {
+		return NewString("Unable to extend " + typeName(proto) + ", prototypes must be objects"), false
+	}
+
+	if len(args) == 3 {
+		if !isPlainObj(args[2]) {
+			return NewString("Unable to set attributes from " + typeName(args[2]) + ", expected an object"), false
+		}
+
+		for k, v := range args[2].Attr {
+			o.SetAttr(k, v)
+		}
+	}
+
+	return o, true
+}
+
+func objectProto(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Object.Proto expects an object"), false
+	}
+
+	if args[1].Proto == nil {
+		return NewNull(), true
+	}
+
+	return args[1].Proto, true
+}
+
+func objectIsA(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 3 {
+		return NewString("Object.IsA expects an object and a prototype"), false
+	}
+
+	return NewBool(args[1].IsA(args[2])), true
+}
+
+// objectSet sets an attribute without calling the object's setattr hook, so
+// that the hook can store values.
+func objectSet(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 4 {
+		return NewString("Object.Set expects an object, a name and a value"), false
+	}
+
+	if args[1].shared {
+		return NewString("Object.Set is unable to set " + args[2].TrueStr() + " on " + typeName(args[1])), false
+	}
+
+	args[1].SetAttr(args[2].TrueStr(), args[3])
+
+	return args[3], true
+}
+
+// newSuper creates the value of super in a method, which looks attributes
+// up starting from proto, but binds them to this.
+func newSuper(this *OwlObj, proto *OwlObj) *OwlObj {
+	return &OwlObj{
+		Attr:     map[string]*OwlObj{},
+		DeepAttr: map[string]*OwlObj{},
+		Proto:    proto,
+		receiver: this,
+	}
+}
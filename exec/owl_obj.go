@@ -1,8 +1,11 @@
 package exec
 
 import (
+	"math/big"
 	"sort"
 	"strings"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
 )
 
 type OwlObj struct {
@@ -11,23 +14,26 @@ type OwlObj struct {
 	BridgeCall func(arg *OwlObj) (*OwlObj, bool)
 	Raw        interface{}
 	Bind       func(*OwlObj)
+
+	// Proto is the object attributes are looked up on when o does not have
+	// them itself. Functions found on a prototype are bound to o.
+	Proto *OwlObj
+
+	// receiver is what functions found through Proto are bound to, if not
+	// the object itself. It is used by super.
+	receiver *OwlObj
+
+	// methods holds the attributes o shares with the other values of its
+	// type, which are used when neither o nor its prototypes have them.
+	methods *methodTable
+
+	// shared is set on values that are reused, such as small numbers,
+	// which are copied before attributes are set on them.
+	shared bool
 }
 
 func NewOwlObj() *OwlObj {
-	obj := OwlObj{}
-	obj.Attr = make(map[string]*OwlObj)
-	obj.DeepAttr = make(map[string]*OwlObj)
-	obj.SetDeepAttr("str", NewCallBridge(objStr))
-	obj.SetDeepAttr("index", NewCallBridge(objIndex))
-	obj.SetDeepAttr("setIndex", NewCallBridge(objSetIndex))
-	obj.SetDeepAttr("iter", NewCallBridge(objIter))
-	obj.SetDeepAttr("has", NewCallBridge(objHas))
-	obj.SetDeepAttr("coalesce", NewCallBridge(objCoalesce))
-	obj.SetDeepAttr("and", NewCallBridge(objAnd))
-	obj.SetDeepAttr("or", NewCallBridge(objOr))
-	obj.SetDeepAttr("not", NewCallBridge(objNot))
-
-	return &obj
+	return &OwlObj{Attr: map[string]*OwlObj{}, methods: objectMethods}
 }
 
 func objStr(args []*OwlObj) (*OwlObj, bool) {
@@ -105,6 +111,10 @@ func objSetIndex(args []*OwlObj) (*OwlObj, bool) {
 	this := args[0]
 	index := args[1].TrueStr()
 
+	if this.shared {
+		return NewString("Unable to set " + index + " on " + typeName(this)), false
+	}
+
 	this.SetAttr(index, args[2])
 
 	return nil, true
@@ -125,20 +135,122 @@ func objIter(args []*OwlObj) (*OwlObj, bool) {
 }
 
 func (o *OwlObj) GetAttr(name string) (*OwlObj, bool) {
-	v, ok := o.Attr[name]
-	return v, ok
+	if v, ok := o.Attr[name]; ok {
+		return v, ok
+	}
+
+	if o.Proto != nil {
+		if v, ok := o.lookup(name, false); ok {
+			return v, ok
+		}
+	}
+
+	if o.methods != nil {
+		if v, ok := o.methods.attr[name]; ok {
+			return v.boundTo(o, nil), true
+		}
+	}
+
+	return nil, false
 }
 
 func (o *OwlObj) GetDeepAttr(name string) (*OwlObj, bool) {
-	v, ok := o.DeepAttr[name]
-	return v, ok
+	if v, ok := o.DeepAttr[name]; ok {
+		return v, ok
+	}
+
+	if o.Proto != nil {
+		if v, ok := o.lookup(name, true); ok {
+			return v, ok
+		}
+	}
+
+	if o.methods != nil {
+		if v, ok := o.methods.deep[name]; ok {
+			return v.boundTo(o, nil), true
+		}
+	}
+
+	return nil, false
+}
+
+// lookup finds an attribute on o's prototype chain, and binds it to o.
+func (o *OwlObj) lookup(name string, deep bool) (*OwlObj, bool) {
+	receiver := o
+	if o.receiver != nil {
+		receiver = o.receiver
+	}
+
+	for p := o.Proto; p != nil; p = p.Proto {
+		attrs := p.Attr
+		if deep {
+			attrs = p.DeepAttr
+		}
+
+		if v, ok := attrs[name]; ok {
+			return v.boundTo(receiver, p), true
+		}
+	}
+
+	return nil, false
 }
 
+// boundTo returns a copy of the function o bound to this, so that a method
+// shared through a prototype runs with the object it was looked up on.
+// home is the object the method was found on. Values that are not functions
+// are returned as is.
+func (o *OwlObj) boundTo(this *OwlObj, home *OwlObj) *OwlObj {
+	switch data := o.Raw.(type) {
+	case *FuncData:
+		d := *data
+		d.This = this
+		d.Home = home
+
+		b := &OwlObj{Attr: o.Attr, DeepAttr: o.DeepAttr, Raw: &d}
+		b.BridgeCall = func(a *OwlObj) (*OwlObj, bool) { return funcCall(&d, a) }
+		b.Bind = func(this *OwlObj) { d.This = this; d.Home = this }
+
+		return b
+	case *BridgeData:
+		d := *data
+		d.This = this
+
+		b := &OwlObj{Attr: o.Attr, DeepAttr: o.DeepAttr, Raw: &d}
+		b.BridgeCall = func(a *OwlObj) (*OwlObj, bool) { return bridgeCall(b, a) }
+		b.Bind = func(this *OwlObj) { d.This = this }
+
+		return b
+	default:
+		return o
+	}
+}
+
+// IsA reports whether proto is on o's prototype chain.
+func (o *OwlObj) IsA(proto *OwlObj) bool {
+	for p := o.Proto; p != nil; p = p.Proto {
+		if p == proto {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetAttr sets the attribute name on o. Shared values can not be changed, so
+// callers must replace them with a copy first, as assignments do.
 func (o *OwlObj) SetAttr(name string, value *OwlObj) {
+	if o.shared {
+		panic("exec: unable to set " + name + " on a shared value")
+	}
+
 	if value.Bind != nil {
 		value.Bind(o)
 	}
 
+	if o.Attr == nil {
+		o.Attr = map[string]*OwlObj{}
+	}
+
 	o.Attr[name] = value
 }
 
@@ -148,30 +260,53 @@ func (o *OwlObj) SetAttrs(values map[string]*OwlObj) {
 	}
 }
 
+// SetDeepAttr sets the deep attribute name on o. Like SetAttr, it must not be
+// called on a shared value.
 func (o *OwlObj) SetDeepAttr(name string, value *OwlObj) {
+	if o.shared {
+		panic("exec: unable to set " + name + " on a shared value")
+	}
+
 	if value.Bind != nil {
 		value.Bind(o)
 	}
 
+	if o.DeepAttr == nil {
+		o.DeepAttr = map[string]*OwlObj{}
+	}
+
 	o.DeepAttr[name] = value
 }
 
 func (o *OwlObj) DeleteDeepAttr(name string) {
-	if o.DeepAttr[name].Bind != nil {
-		o.DeepAttr[name].Bind(nil)
+	if v, ok := o.DeepAttr[name]; ok && v.Bind != nil {
+		v.Bind(nil)
 	}
 
 	delete(o.DeepAttr, name)
 }
 
 func (o *OwlObj) DeleteAttr(name string) {
-	if o.Attr[name].Bind != nil {
-		o.Attr[name].Bind(nil)
+	if v, ok := o.Attr[name]; ok && v.Bind != nil {
+		v.Bind(nil)
 	}
 
 	delete(o.Attr, name)
 }
 
+// unshared returns a copy of o if o is shared, so that attributes can be
+// set on it without affecting other uses of the value.
+func (o *OwlObj) unshared() *OwlObj {
+	if !o.shared {
+		return o
+	}
+
+	c := *o
+	c.shared = false
+
+	return &c
+}
+
 func (o *OwlObj) IsTruthy() bool {
 	// TODO cast to bool and retun Raw.(bool)
 	switch t := o.Raw.(type) {
@@ -181,6 +316,10 @@ func (o *OwlObj) IsTruthy() bool {
 		return t != 0
 	case float64:
 		return t != 0.0
+	case *big.Int:
+		return t.Sign() != 0
+	case decimal.Decimal:
+		return t.Sign() != 0
 	case string:
 		return t != ""
 	case []*OwlObj:
@@ -202,6 +341,16 @@ func (o *OwlObj) IsNullish() bool {
 }
 
 func (o *OwlObj) DeepCall(name string, arg *OwlObj) (*OwlObj, bool) {
+	// Methods from the type's table are called directly, rather than
+	// through a copy bound to o
+	if _, own := o.DeepAttr[name]; !own && o.Proto == nil && o.methods != nil {
+		if v, ok := o.methods.deep[name]; ok {
+			return o.methods.call(o, v, arg)
+		}
+
+		return nil, false
+	}
+
 	v, ok := o.GetDeepAttr(name)
 
 	if !ok {
@@ -359,15 +508,15 @@ func (o *OwlObj) TrueList() ([]*OwlObj, bool) {
 }
 
 func (o *OwlObj) AsList() ([]*OwlObj, bool) {
-	// Gets a list of objects from either TrueList() or Iter()
+	// Gets a list of objects from either TrueList() or by iterating it
 	list, ok := o.TrueList()
 
 	if !ok {
-		var iter *OwlObj
-		iter, ok = o.Iter()
-		if ok {
-			list, ok = iter.TrueList()
-		}
+		list = []*OwlObj{}
+		ok = Iterate(o, func(v *OwlObj) bool {
+			list = append(list, v)
+			return true
+		})
 	}
 
 	return list, ok
@@ -0,0 +1,117 @@
+package exec
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/lexer"
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+// sink keeps benchmark results alive, so that creating them is not
+// optimized away.
+var sink *OwlObj
+
+func benchProgram(b *testing.B, src string) {
+	p := parser.NewParser(lexer.NewLexer(src).Tokenize("bench.hoot"))
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		b.Fatal(p.Errors)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		NewTreeExecutor(".").ExecProgram(program, Frame{})
+	}
+}
+
+func BenchmarkNewInt(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = NewInt(int64(i) + 1000)
+	}
+}
+
+func BenchmarkNewString(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = NewString("hello")
+	}
+}
+
+// BenchmarkAdd measures one addition, including creating its result.
+func BenchmarkAdd(b *testing.B) {
+	x, y := NewInt(1000), NewInt(2000)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink, _ = BinaryOp("+", x, y)
+	}
+}
+
+// BenchmarkLoop runs 1000 iterations of a loop doing a comparison, an
+// addition and an assignment.
+func BenchmarkLoop(b *testing.B) {
+	benchProgram(b, "i = 0\nwhile i < 1000 {\n i += 1\n}")
+}
+
+// BenchmarkListElements builds a list of 1000 numbers.
+func BenchmarkListElements(b *testing.B) {
+	benchProgram(b, "l = []\nfor x in range(1000) {\n l.Add(x * 2)\n}")
+}
+
+func TestSharedValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a = 4\nb = 4\na::neg = () => this + 1\nreturn [-a, -b, -4]::str()", "[5, -4, -4]"},
+		{"f = () => {\n s = 'ab'\n s::str = () => 'changed'\n return s::str()\n}\nreturn [f(), 'ab']::str()", "[changed, ab]"},
+		{"a = [1]\nb = [1]\na::str = () => 'mine'\nreturn [a::str(), b::str()]::str()", "[mine, [1]]"},
+		{"split = 'a,b'.Split\nreturn split(',')::str()", "[a, b]"},
+		{"f = () => {\n n = 5000\n before = n::str()\n n::str = () => 'tagged'\n return before + ' ' + n::str()\n}\nreturn f() + ', ' + f()", "5000 tagged, 5000 tagged"},
+		{"o = {n: 1, m: {k: 2}}\no.n.tag = 'a'\no.m.k.tag = 'b'\nreturn [o.n.tag, o.m.k.tag, o.n + 1]::str()", "[a, b, 2]"},
+		{"x = 5\ny = x\nx.tag = 1\nreturn [x.tag, x + 1, y ?? 0]::str()", "[1, 6, 5]"},
+		{"l = [0, 1]\nm = l[0]\nl[0].x = 1\nl[i = 1].y = 2\nreturn [l[0].x, l[1].y, l, m, i]::str()", "[1, 2, [0, 1], 0, 1]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(tt.input)
+		testString(t, evaluated, tt.expected)
+	}
+
+	if NewInt(7) != NewInt(7) || NewString("a") != NewString("a") || NewInt(100000) == NewInt(100000) {
+		t.Errorf("Expected only small values to be reused")
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"f = () => 3\nf().x = 1", "owl_obj_test.hoot:2:4: Unable to set an attribute on int 'f()', assign it to a variable first"},
+		{"s = 'ab'\ns[0].x = 1", "owl_obj_test.hoot:2:5: Unable to set an attribute on string 's[0]'"},
+		{"s = 'a'\ns['k'] = 1", "Unable to set index"},
+		{"Done['k'] = 1", "Unable to set k on"},
+		{"Object.Set(true, 'x', 1)", "Object.Set is unable to set x on bool"},
+	}
+
+	for _, tt := range errors {
+		if _, err := ExecuteProgramContext(context.Background(), mustLoad(t, tt.input)); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected an error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func mustLoad(t *testing.T, src string) *OwlParams {
+	params, errs := LoadProgram(src, "owl_obj_test.hoot")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	return params
+}
@@ -0,0 +1,340 @@
+package exec
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
+)
+
+// Objects customize how operators and builtins treat them by defining deep
+// attributes with ::. The full protocol is:
+//
+//	add sub mul div pow mod  a + b, a - b, a * b, a / b, a ** b, a % b
+//	idiv                     a // b
+//	band bor bxor shl shr    a & b, a | b, a ^ b, a << b, a >> b
+//	eq ne lt le gt ge        a == b, a != b, a < b, a <= b, a > b, a >= b
+//	radd rsub rmul ...       reflected versions of the arithmetic operators
+//	neg not inc dec          -a, !a, a++, a--
+//	and or coalesce          a and b, a or b, a ?? b
+//	has                      a has b
+//	index setIndex slice     a[i], a[i] = v, a[i:j]
+//	call                     a(...)
+//	str len hash             printing, len(a), hash(a)
+//	iter next close          for x in a, close stops an iterator early
+//	getattr setattr          a.x when a has no x, a.x = v
+//
+// Binary operators are looked up on the left operand, and are called with
+// the operands in the order they were written. If that fails, arithmetic
+// tries the reflected version on the right operand, still in written order,
+// so 5 - v calls v::rsub(5, v). Comparisons instead try the mirrored
+// comparison on the right operand with the operands swapped, so 5 < v calls
+// v::gt(v, 5). An implementation fails to let the other side try.
+var binaryOps = map[string]string{
+	"+":  "add",
+	"-":  "sub",
+	"*":  "mul",
+	"/":  "div",
+	"**": "pow",
+	"%":  "mod",
+	"//": "idiv",
+	"&":  "band",
+	"|":  "bor",
+	"^":  "bxor",
+	"<<": "shl",
+	">>": "shr",
+	"==": "eq",
+	"!=": "ne",
+	"<":  "lt",
+	"<=": "le",
+	">":  "gt",
+	">=": "ge",
+}
+
+// mirrored maps comparisons to the equivalent comparison with the operands
+// swapped, a < b is the same as b > a.
+var mirrored = map[string]string{
+	"eq": "eq",
+	"ne": "ne",
+	"lt": "gt",
+	"le": "ge",
+	"gt": "lt",
+	"ge": "le",
+}
+
+// BinaryOp applies the binary operator op, such as "+" or "<", to left and
+// right.
+func BinaryOp(op string, left *OwlObj, right *OwlObj) (*OwlObj, bool) {
+	name := binaryOps[op]
+	pair := NewList([]*OwlObj{left, right})
+
+	if val, ok := left.DeepCall(name, pair); ok {
+		return val, true
+	}
+
+	if mirror, ok := mirrored[name]; ok {
+		if val, ok := right.DeepCall(mirror, NewList([]*OwlObj{right, left})); ok {
+			return val, true
+		}
+	} else {
+		if val, ok := right.DeepCall("r"+name, pair); ok {
+			return val, true
+		}
+
+		// Addition and multiplication are commutative for most types, so
+		// the right operand's own implementation is tried as well
+		if name == "add" || name == "mul" {
+			if val, ok := right.DeepCall(name, pair); ok {
+				return val, true
+			}
+		}
+	}
+
+	// Without an implementation, an object is only equal to itself
+	switch name {
+	case "eq":
+		return NewBool(left == right), true
+	case "ne":
+		if val, ok := BinaryOp("==", left, right); ok {
+			return NewBool(!val.IsTruthy()), true
+		}
+	}
+
+	return nil, false
+}
+
+// NewDone returns the value an iterator's next function returns when it has
+// no more values, which Owl code refers to as Done. Like null, it is a
+// single shared value.
+func NewDone() *OwlObj {
+	return doneValue
+}
+
+func doneStr(args []*OwlObj) (*OwlObj, bool) {
+	return NewString("Done"), true
+}
+
+// Iterator returns a function that returns each value of o in turn, and
+// then NewDone(). Lists are iterated directly. Other objects are iterated
+// with their iter function, which can return a list, or an iterator whose
+// next function returns one value at a time and then NewDone(). Iterator
+// fails if o cannot be iterated, and the function fails if next does.
+func Iterator(o *OwlObj) (func() (*OwlObj, bool), bool) {
+	next, _, ok := iterator(o)
+	return next, ok
+}
+
+// iterator is Iterator, but also returns the iterator object the values
+// come from, so that it can be closed. It is nil when o is iterated as a
+// list.
+func iterator(o *OwlObj) (func() (*OwlObj, bool), *OwlObj, bool) {
+	if list, ok := o.TrueList(); ok {
+		return listIterator(list), nil, true
+	}
+
+	iter := o
+	if _, isIter := o.GetDeepAttr("next"); !isIter {
+		var ok bool
+		if iter, ok = o.Iter(); !ok || iter == nil {
+			return nil, nil, false
+		}
+
+		if list, ok := iter.TrueList(); ok {
+			return listIterator(list), nil, true
+		}
+	}
+
+	return func() (*OwlObj, bool) {
+		v, ok := iter.DeepCall("next", nil)
+		if !ok || v == nil {
+			return v, false
+		}
+
+		return v, true
+	}, iter, true
+}
+
+func listIterator(list []*OwlObj) func() (*OwlObj, bool) {
+	i := 0
+
+	return func() (*OwlObj, bool) {
+		if i >= len(list) {
+			return NewDone(), true
+		}
+
+		i++
+		return list[i-1], true
+	}
+}
+
+// closeIterator calls iter's close function, if it has one, to tell it no
+// more values will be taken. Generators stop their goroutine when closed.
+func closeIterator(iter *OwlObj) {
+	if iter == nil {
+		return
+	}
+
+	if _, ok := iter.GetDeepAttr("close"); ok {
+		iter.DeepCall("close", nil)
+	}
+}
+
+// Iterate calls fn with each value of o, until fn returns false. It fails if
+// o cannot be iterated, or if its next function fails. An iterator that is
+// not iterated to the end, because fn returns false or panics, is closed.
+func Iterate(o *OwlObj, fn func(v *OwlObj) bool) bool {
+	next, iter, ok := iterator(o)
+	if !ok {
+		return false
+	}
+
+	finished := false
+	defer func() {
+		if !finished {
+			closeIterator(iter)
+		}
+	}()
+
+	for {
+		v, ok := next()
+		if !ok {
+			return false
+		}
+
+		if v == NewDone() {
+			finished = true
+			return true
+		}
+
+		if !fn(v) {
+			return true
+		}
+	}
+}
+
+// Len returns the length of o, from its len function if it has one.
+func Len(o *OwlObj) (int64, bool) {
+	if v, ok := o.DeepCall("len", nil); ok && v != nil {
+		return v.TrueInt()
+	}
+
+	switch raw := o.Raw.(type) {
+	case []*OwlObj:
+		return int64(len(raw)), true
+	case string:
+		return int64(runeLen(raw)), true
+	}
+
+	if isPlainObj(o) {
+		return int64(len(o.Attr)), true
+	}
+
+	return 0, false
+}
+
+// Hash returns a hash of o, from its hash function if it has one. Numbers,
+// strings, bools and null are hashed by value, so values that are equal have
+// the same hash. Other values can only be hashed if they define hash.
+func Hash(o *OwlObj) (int64, bool) {
+	if v, ok := o.DeepCall("hash", nil); ok && v != nil {
+		return v.TrueInt()
+	}
+
+	h := fnv.New64a()
+
+	switch raw := o.Raw.(type) {
+	case int64:
+		h.Write([]byte{'n'})
+		writeUint(h, uint64(raw))
+	case *big.Int:
+		writeBig(h, raw)
+	case float64:
+		if raw == math.Trunc(raw) && math.Abs(raw) >= 1<<63 && !math.IsInf(raw, 0) {
+			n, _ := big.NewFloat(raw).Int(nil)
+			writeBig(h, n)
+			break
+		}
+
+		h.Write([]byte{'n'})
+		if raw == math.Trunc(raw) && math.Abs(raw) < 1<<63 {
+			writeUint(h, uint64(int64(raw)))
+		} else {
+			writeUint(h, math.Float64bits(raw))
+		}
+	case decimal.Decimal:
+		// Whole decimals are equal to ints, so they hash the same way
+		if n, ok := raw.Int(); ok {
+			if n.IsInt64() {
+				h.Write([]byte{'n'})
+				writeUint(h, uint64(n.Int64()))
+			} else {
+				writeBig(h, n)
+			}
+		} else {
+			h.Write([]byte{'d'})
+			h.Write([]byte(raw.Key()))
+		}
+	case string:
+		h.Write([]byte{'s'})
+		h.Write([]byte(raw))
+	case bool:
+		if raw {
+			h.Write([]byte{'t'})
+		} else {
+			h.Write([]byte{'f'})
+		}
+	default:
+		if !o.IsNullish() {
+			return 0, false
+		}
+		h.Write([]byte{'0'})
+	}
+
+	return int64(h.Sum64()), true
+}
+
+func writeBig(h interface{ Write([]byte) (int, error) }, n *big.Int) {
+	h.Write([]byte{'b', byte(n.Sign() + 1)})
+	h.Write(n.Bytes())
+}
+
+func writeUint(h interface{ Write([]byte) (int, error) }, v uint64) {
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = byte(v >> (8 * i))
+	}
+	h.Write(b)
+}
+
+// newUnaryBuiltin creates a builtin function of one argument. Unlike a call
+// bridge, the argument is not flattened, so that len([1, 2]) gets the list.
+func newUnaryBuiltin(name string, f func(arg *OwlObj) (*OwlObj, bool)) *OwlObj {
+	b := NewOwlObj()
+	b.BridgeCall = func(arg *OwlObj) (*OwlObj, bool) {
+		if arg == nil {
+			return NewString(name + " expects an argument"), false
+		}
+		return f(arg)
+	}
+
+	return b
+}
+
+func builtinLen(arg *OwlObj) (*OwlObj, bool) {
+	n, ok := Len(arg)
+	if !ok {
+		return NewString(typeName(arg) + " has no length"), false
+	}
+
+	return NewInt(n), true
+}
+
+func builtinHash(arg *OwlObj) (*OwlObj, bool) {
+	n, ok := Hash(arg)
+	if !ok {
+		return NewString(typeName(arg) + " is not hashable, define ::hash to hash it"), false
+	}
+
+	return NewInt(n), true
+}
@@ -0,0 +1,258 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/lexer"
+)
+
+// Policy restricts what a program can do, for running untrusted code. It is
+// set on an executor before running a program, and applies to every module
+// the program imports.
+//
+// A program with a policy can only import the modules named in Modules,
+// which may be Go modules such as "fs" or standard library modules such as
+// "json". Files, whether imported or read with fs, must be inside one of
+// Roots. os.Exec is disabled unless AllowExec is set.
+//
+// Limits that are zero are not enforced. MaxDuration is also set as a
+// deadline on the executor's context, so that bridges that block, such as
+// time.Sleep, os.Exec and HTTP requests, are interrupted when it passes.
+// MaxMemory is measured as the growth
+// of the Go heap since the program started, so it is approximate, and
+// includes anything else the host allocates at the same time.
+type Policy struct {
+	Modules   []string
+	Roots     []string
+	AllowExec bool
+
+	MaxInstructions int64
+	MaxDuration     time.Duration
+	MaxDepth        int
+	MaxMemory       uint64
+}
+
+const (
+	LIMIT_INSTRUCTIONS = "instruction"
+	LIMIT_DURATION     = "time"
+	LIMIT_DEPTH        = "recursion depth"
+	LIMIT_MEMORY       = "memory"
+)
+
+// LimitError is panicked when a program exceeds one of its policy's limits.
+// Token is the statement or function being run at the time.
+type LimitError struct {
+	Limit string
+	Token lexer.Token
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s limit exceeded", e.Token.File, e.Token.Line, e.Token.Column, e.Limit)
+}
+
+// The clock and heap are only checked every so many instructions, since
+// reading them is much slower than running a statement.
+const checkInterval = 256
+
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// budget tracks how much of its limits a program has used. When the policy
+// has a time limit, parent is the context the program was given, and cancel
+// releases the context derived from it with the deadline.
+type budget struct {
+	policy       *Policy
+	instructions int64
+	depth        int
+	deadline     time.Time
+	heap         uint64
+	sample       []metrics.Sample
+	parent       context.Context
+	cancel       context.CancelFunc
+}
+
+func newBudget(p *Policy) *budget {
+	b := &budget{policy: p}
+
+	if p.MaxDuration > 0 {
+		b.deadline = time.Now().Add(p.MaxDuration)
+	}
+
+	if p.MaxMemory > 0 {
+		b.sample = []metrics.Sample{{Name: heapMetric}}
+		b.heap = b.readHeap()
+	}
+
+	return b
+}
+
+func (b *budget) readHeap() uint64 {
+	metrics.Read(b.sample)
+
+	if b.sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+
+	return b.sample[0].Value.Uint64()
+}
+
+// budget returns t's budget, creating it when the program first uses it.
+func (t *TreeExecutor) budget() *budget {
+	if t.run.budget == nil {
+		t.run.budget = newBudget(t.Policy)
+		t.setDeadline(t.run.ctx)
+	}
+
+	return t.run.budget
+}
+
+// setDeadline runs t under ctx, with the budget's deadline if it has one.
+func (t *TreeExecutor) setDeadline(ctx context.Context) {
+	b := t.run.budget
+	if b == nil || b.deadline.IsZero() {
+		t.run.ctx = ctx
+		return
+	}
+
+	if b.cancel != nil {
+		b.cancel()
+	}
+
+	b.parent = ctx
+	t.run.ctx, b.cancel = context.WithDeadline(ctx, b.deadline)
+}
+
+// timedOut reports whether the program's context is done because its time
+// limit passed, rather than because the context it was given is done.
+func (b *budget) timedOut() bool {
+	return b.cancel != nil && b.parent.Err() == nil && time.Now().After(b.deadline)
+}
+
+// step counts an instruction, panicking if any limit has been exceeded.
+func (t *TreeExecutor) step(token lexer.Token) {
+	b := t.budget()
+	p := b.policy
+	b.instructions++
+
+	if p.MaxInstructions > 0 && b.instructions > p.MaxInstructions {
+		panic(&LimitError{LIMIT_INSTRUCTIONS, token})
+	}
+
+	if b.instructions%checkInterval != 0 {
+		return
+	}
+
+	if p.MaxDuration > 0 && time.Now().After(b.deadline) {
+		panic(&LimitError{LIMIT_DURATION, token})
+	}
+
+	if p.MaxMemory > 0 {
+		heap := b.readHeap()
+		if heap > b.heap && heap-b.heap > p.MaxMemory {
+			panic(&LimitError{LIMIT_MEMORY, token})
+		}
+	}
+}
+
+// checkDepth counts a function call, panicking if it would exceed the
+// policy's recursion limit. The depth is shared with imported modules, so
+// recursion through several modules is caught as well.
+func (t *TreeExecutor) checkDepth(token lexer.Token) {
+	b := t.budget()
+
+	if t.Policy.MaxDepth > 0 && b.depth >= t.Policy.MaxDepth {
+		panic(&LimitError{LIMIT_DEPTH, token})
+	}
+
+	b.depth++
+}
+
+// ResetLimits starts the policy's limits over, for executors that run
+// several programs, such as a REPL.
+func (t *TreeExecutor) ResetLimits() {
+	if b := t.run.budget; b != nil && b.cancel != nil {
+		b.cancel()
+		t.run.ctx = b.parent
+	}
+
+	t.run.budget = nil
+}
+
+func (p *Policy) allowsModule(name string) bool {
+	for _, m := range p.Modules {
+		if m == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvePath returns the absolute path of path, following symlinks so that
+// a link inside a root cannot be used to reach files outside of it.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+
+	// The file may not exist yet, resolve its directory instead
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(dir, filepath.Base(abs))
+	}
+
+	return abs
+}
+
+func (p *Policy) allowsPath(path string) bool {
+	path = resolvePath(path)
+
+	for _, root := range p.Roots {
+		rel, err := filepath.Rel(resolvePath(root), path)
+		if err != nil {
+			continue
+		}
+
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPath returns an error message if the program is not allowed to access
+// path.
+func (t *TreeExecutor) checkPath(path string) (string, bool) {
+	if t.Policy == nil || t.Policy.allowsPath(path) {
+		return "", true
+	}
+
+	return "Access to '" + path + "' is not allowed", false
+}
+
+// checkImport returns an error message if the program is not allowed to
+// import the module name.
+func (t *TreeExecutor) checkImport(name string) (string, bool) {
+	if t.Policy == nil {
+		return "", true
+	}
+
+	if isPathImport(name) {
+		if !t.Policy.allowsPath(modulePath(name, t.currentPath)) {
+			return "Import of '" + name + "' is not allowed, it is outside of the allowed directories", false
+		}
+	} else if !t.Policy.allowsModule(name) {
+		return "Import of module '" + name + "' is not allowed", false
+	}
+
+	return "", true
+}
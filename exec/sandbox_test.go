@@ -0,0 +1,106 @@
+package exec
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runSandboxed(t *testing.T, dir string, policy *Policy, src string) (*OwlObj, error) {
+	params, errs := LoadProgram(src, filepath.Join(dir, "main.hoot"))
+	if len(errs) > 0 {
+		t.Fatalf("Failed to parse program: %v", errs)
+	}
+
+	e := NewTreeExecutor(params.Path)
+	e.Policy = policy
+
+	var result *OwlObj
+	err := Try(func() { result = e.ExecProgram(params.Program, params.Globals) })
+
+	return result, err
+}
+
+func TestSandboxImports(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "inside.hoot"), []byte("x = 1"), 0644)
+	os.WriteFile(filepath.Join(dir, "data.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(outside, "outside.hoot"), []byte("x = 1"), 0644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+
+	policy := &Policy{Modules: []string{"fs", "os"}, Roots: []string{dir}}
+
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`import "./inside"`, ""},
+		{`import "fs"` + "\nreturn fs.Read(\"" + filepath.Join(dir, "data.txt") + "\")", ""},
+		{`import "test"`, "Import of module 'test' is not allowed"},
+		{`import "` + filepath.Join(outside, "outside") + `"`, "is outside of the allowed directories"},
+		{`import "./../` + filepath.Base(outside) + `/outside"`, "is outside of the allowed directories"},
+		{`import "fs"` + "\nfs.Read(\"" + filepath.Join(outside, "secret.txt") + "\")", "is not allowed"},
+		{`import "fs"` + "\nfs.Read(\"" + dir + "/../" + filepath.Base(outside) + "/secret.txt\")", "is not allowed"},
+		{`import "os"` + "\nos.Exec(\"echo\", \"hi\")", "os.Exec is not allowed"},
+	}
+
+	for _, tt := range tests {
+		v, err := runSandboxed(t, dir, policy, tt.input)
+
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.input, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: expected error containing %q, got %v (%v)", tt.input, tt.err, err, v)
+		}
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		policy *Policy
+		input  string
+		limit  string
+	}{
+		{&Policy{MaxInstructions: 1000}, "while (true) {\n}", LIMIT_INSTRUCTIONS},
+		{&Policy{MaxInstructions: 1000}, "i = 0\nwhile (i < 10) {\n    i++\n}", ""},
+		{&Policy{MaxDuration: 20 * time.Millisecond}, "while (true) {\n}", LIMIT_DURATION},
+		{&Policy{MaxDuration: 20 * time.Millisecond, Modules: []string{"time"}}, "import \"time\"\ntime.Sleep(5 * time.second)", LIMIT_DURATION},
+		{&Policy{MaxInstructions: 1000}, "x = list(range(0, null))", LIMIT_INSTRUCTIONS},
+		{&Policy{MaxDepth: 50}, "f = (n) => f(n + 1)\nf(0)", LIMIT_DEPTH},
+		{&Policy{MaxDepth: 50}, "f = (n) => n < 40 ? f(n + 1) : n\nreturn f(0)", ""},
+		{&Policy{MaxMemory: 16 << 20}, "s = \"abcdefghijklmnop\"\nfor i in range(12) {\n    s = s + s\n}\nl = []\nwhile (true) {\n    l.Add(s + l.Len())\n}", LIMIT_MEMORY},
+	}
+
+	for _, tt := range tests {
+		_, err := runSandboxed(t, dir, tt.policy, tt.input)
+
+		if tt.limit == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.input, err)
+			}
+			continue
+		}
+
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("%q: expected a LimitError, got %v", tt.input, err)
+			continue
+		}
+
+		if limitErr.Limit != tt.limit {
+			t.Errorf("%q: expected the %s limit to be exceeded, got %s", tt.input, tt.limit, limitErr.Limit)
+		}
+	}
+}
@@ -1,37 +1,23 @@
 package exec
 
 import (
-	"regexp"
+	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func NewString(v string) *OwlObj {
-	s := NewOwlObj()
-
-	s.SetDeepAttr("add", NewCallBridge(stringAdd))
-	s.SetDeepAttr("eq", NewCallBridge(stringEq))
-	s.SetDeepAttr("ne", NewCallBridge(stringNe))
-	s.SetDeepAttr("gt", NewCallBridge(stringGt))
-	s.SetDeepAttr("lt", NewCallBridge(stringLt))
-	s.SetDeepAttr("ge", NewCallBridge(stringGe))
-	s.SetDeepAttr("le", NewCallBridge(stringLe))
-	s.SetDeepAttr("str", NewCallBridge(stringStr))
-	s.SetDeepAttr("index", NewCallBridge(stringIndex))
-	s.SetDeepAttr("has", NewCallBridge(stringHas))
-	s.SetDeepAttr("slice", NewCallBridge(stringSlice))
-	s.SetDeepAttr("iter", NewCallBridge(stringIter))
-
-	s.SetAttr("Split", NewCallBridge(stringSplit))
-	s.SetAttr("Len", NewCallBridge(stringLen))
-	s.SetAttr("Replace", NewCallBridge(stringReplace))
-	s.SetAttr("ReReplace", NewCallBridge(stringRegexReplace))
-	s.SetAttr("ReMatch", NewCallBridge(stringRegexMatch))
-	s.SetAttr("ReIndex", NewCallBridge(stringRegexIndexOf))
-	s.SetAttr("Index", NewCallBridge(stringIndexOf))
-	s.SetAttr("Trim", NewCallBridge(stringTrim))
-	s.Raw = v
-
-	return s
+	switch len(v) {
+	case 0:
+		return emptyString
+	case 1:
+		return byteStrings[v[0]]
+	}
+
+	return &OwlObj{Raw: v, methods: stringMethods}
 }
 
 func stringAdd(args []*OwlObj) (*OwlObj, bool) {
@@ -66,15 +52,48 @@ func stringStr(args []*OwlObj) (*OwlObj, bool) {
 	return args[0], true
 }
 
+// Strings are indexed, sliced and measured by character rather than by byte,
+// so "héllo"[1] is "é". runes returns the characters of s, which for ASCII
+// strings are its bytes, so they can be indexed directly.
+func runes(s string) ([]rune, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return []rune(s), false
+		}
+	}
+
+	return nil, true
+}
+
+// runeLen returns the number of characters in s.
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
 func stringIndex(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
-	i, ok := args[1].TrueInt()
+	index, ok := args[1].TrueInt()
 
 	if !ok {
-		return nil, false
+		return NewString("String index must be an int, got " + typeName(args[1])), false
 	}
 
-	return NewString(string(s[int(i)])), ok
+	r, ascii := runes(s)
+	n := len(r)
+	if ascii {
+		n = len(s)
+	}
+
+	i := mapIndex(index, n)
+	if i < 0 || i >= n {
+		return NewString("String index " + args[1].TrueStr() + " is out of range for a string of length " + strconv.Itoa(n)), false
+	}
+
+	if ascii {
+		return NewString(s[i : i+1]), true
+	}
+
+	return NewString(string(r[i])), true
 }
 
 func stringHas(args []*OwlObj) (*OwlObj, bool) {
@@ -112,57 +131,55 @@ func stringSplit(args []*OwlObj) (*OwlObj, bool) {
 
 func stringLen(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
-	return NewInt(int64(len(s))), true
+	return NewInt(int64(runeLen(s))), true
 }
 
 func stringSlice(args []*OwlObj) (*OwlObj, bool) {
-	list := args[0].TrueStr()
-	start := args[1]
-	end := args[2]
-
-	var startIndex64, endIndex64 int64
-	var startIndex, endIndex int = 0, len(list)
-	var startOk, endOk bool
-
-	if start != nil {
-		startIndex64, startOk = start.TrueInt()
-		if startOk {
-			startIndex = mapIndex(startIndex64, len(list))
-		}
+	s := args[0].TrueStr()
+	r, ascii := runes(s)
+	n := len(r)
+	if ascii {
+		n = len(s)
 	}
 
-	if end != nil {
-		endIndex64, endOk = end.TrueInt()
-		if endOk {
-			endIndex = mapIndex(endIndex64, len(list))
+	bounds := [2]int{0, n}
+	for i, v := range args[1:3] {
+		if v == nil {
+			continue
 		}
-	}
 
-	if startIndex < 0 {
-		startIndex = startIndex + len(list)
-	}
+		index, ok := v.TrueInt()
+		if !ok {
+			return NewString("String slice index must be an int, got " + typeName(v)), false
+		}
 
-	if endIndex > len(list) {
-		endIndex = len(list)
+		bounds[i] = mapIndex(index, n)
+		if bounds[i] < 0 {
+			bounds[i] = 0
+		}
+		if bounds[i] > n {
+			bounds[i] = n
+		}
 	}
 
-	if startIndex > endIndex {
-		return NewString("test"), true
+	start, end := bounds[0], bounds[1]
+	if start > end {
+		return NewString(""), true
 	}
 
-	if startIndex >= len(list) || endIndex > len(list) {
-		return nil, false
+	if ascii {
+		return NewString(s[start:end]), true
 	}
 
-	return NewString(list[startIndex:endIndex]), true
+	return NewString(string(r[start:end])), true
 }
 
 func stringIter(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
 
-	objs := make([]*OwlObj, len(s))
-	for i, v := range s {
-		objs[i] = NewString(string(v))
+	objs := make([]*OwlObj, 0, len(s))
+	for _, v := range s {
+		objs = append(objs, NewString(string(v)))
 	}
 
 	return NewList(objs), true
@@ -180,7 +197,12 @@ func stringIndexOf(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
 	sub := args[1].TrueStr()
 
-	return NewInt(int64(strings.Index(s, sub))), true
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return NewInt(-1), true
+	}
+
+	return NewInt(int64(runeLen(s[:i]))), true
 }
 
 func stringRegexReplace(args []*OwlObj) (*OwlObj, bool) {
@@ -188,7 +210,10 @@ func stringRegexReplace(args []*OwlObj) (*OwlObj, bool) {
 	re := args[1].TrueStr()
 	repl := args[2].TrueStr()
 
-	exp := regexp.MustCompile(re)
+	exp, msg := compileRegex(re)
+	if msg != "" {
+		return NewString(msg), false
+	}
 
 	return NewString(exp.ReplaceAllString(s, repl)), true
 }
@@ -197,7 +222,10 @@ func stringRegexMatch(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
 	re := args[1].TrueStr()
 
-	exp := regexp.MustCompile(re)
+	exp, msg := compileRegex(re)
+	if msg != "" {
+		return NewString(msg), false
+	}
 
 	matches := exp.MatchString(s)
 
@@ -208,22 +236,314 @@ func stringRegexIndexOf(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
 	re := args[1].TrueStr()
 
-	exp := regexp.MustCompile(re)
+	exp, msg := compileRegex(re)
+	if msg != "" {
+		return NewString(msg), false
+	}
 
 	r := exp.FindIndex([]byte(s))
 	var start int64 = -1
 	var end int64 = -1
 	if r != nil {
-		start = int64(r[0])
-		end = int64(r[1])
+		start = int64(runeLen(s[:r[0]]))
+		end = start + int64(runeLen(s[r[0]:r[1]]))
 	}
 
 	return NewList([]*OwlObj{NewInt(start), NewInt(end)}), true
 }
 
+// stringTrim removes the characters given from both ends of the string, or
+// whitespace without them.
 func stringTrim(args []*OwlObj) (*OwlObj, bool) {
 	s := args[0].TrueStr()
-	cut := args[1].TrueStr()
 
-	return NewString(strings.Trim(s, cut)), true
+	if len(args) == 1 {
+		return NewString(strings.TrimSpace(s)), true
+	}
+
+	return NewString(strings.Trim(s, args[1].TrueStr())), true
+}
+
+func stringUpper(args []*OwlObj) (*OwlObj, bool) {
+	return NewString(strings.ToUpper(args[0].TrueStr())), true
+}
+
+func stringLower(args []*OwlObj) (*OwlObj, bool) {
+	return NewString(strings.ToLower(args[0].TrueStr())), true
+}
+
+// stringTitle capitalises the first letter of each word, and lowers the rest.
+func stringTitle(args []*OwlObj) (*OwlObj, bool) {
+	b := strings.Builder{}
+	start := true
+
+	for _, c := range args[0].TrueStr() {
+		if start {
+			b.WriteRune(unicode.ToTitle(c))
+		} else {
+			b.WriteRune(unicode.ToLower(c))
+		}
+		start = unicode.IsSpace(c)
+	}
+
+	return NewString(b.String()), true
+}
+
+func stringStartsWith(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("StartsWith takes 1 string"), false
+	}
+
+	return NewBool(strings.HasPrefix(args[0].TrueStr(), args[1].TrueStr())), true
+}
+
+func stringEndsWith(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("EndsWith takes 1 string"), false
+	}
+
+	return NewBool(strings.HasSuffix(args[0].TrueStr(), args[1].TrueStr())), true
+}
+
+func stringContains(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Contains takes 1 string"), false
+	}
+
+	return stringHas(args)
+}
+
+// repeat repeats s n times, which can not be negative.
+func repeat(s string, n *OwlObj) (*OwlObj, bool) {
+	count, ok := n.TrueInt()
+	if !ok {
+		return NewString("Unable to repeat a string " + typeName(n) + " times"), false
+	}
+
+	if count < 0 {
+		return NewString("Unable to repeat a string a negative number of times"), false
+	}
+
+	if count > 0 && int64(len(s))*count/count != int64(len(s)) {
+		return NewString("Unable to repeat a string " + n.TrueStr() + " times"), false
+	}
+
+	return NewString(strings.Repeat(s, int(count))), true
+}
+
+func stringRepeat(args []*OwlObj) (*OwlObj, bool) {
+	if len(args) != 2 {
+		return NewString("Repeat takes 1 int"), false
+	}
+
+	return repeat(args[0].TrueStr(), args[1])
+}
+
+// stringMul repeats a string, which can be either operand of *.
+func stringMul(args []*OwlObj) (*OwlObj, bool) {
+	s, n := args[1], args[2]
+	if _, ok := s.Raw.(string); !ok {
+		s, n = n, s
+	}
+
+	if _, ok := n.Raw.(int64); !ok {
+		return nil, false
+	}
+
+	return repeat(s.TrueStr(), n)
+}
+
+// pad creates PadLeft and PadRight, which pad a string to a width with
+// spaces, or the character given.
+func pad(name string, left bool) func(args []*OwlObj) (*OwlObj, bool) {
+	return func(args []*OwlObj) (*OwlObj, bool) {
+		if len(args) < 2 || len(args) > 3 {
+			return NewString(name + " takes a width and an optional character"), false
+		}
+
+		s := args[0].TrueStr()
+		width, ok := args[1].TrueInt()
+		if !ok {
+			return NewString(name + " width must be an int, got " + typeName(args[1])), false
+		}
+
+		fill := " "
+		if len(args) == 3 {
+			fill = args[2].TrueStr()
+			if runeLen(fill) != 1 {
+				return NewString(name + " pads with a single character, got '" + fill + "'"), false
+			}
+		}
+
+		n := int(width) - runeLen(s)
+		if n <= 0 {
+			return args[0], true
+		}
+
+		if left {
+			return NewString(strings.Repeat(fill, n) + s), true
+		}
+
+		return NewString(s + strings.Repeat(fill, n)), true
+	}
+}
+
+// stringLines splits the string at line breaks, which can be \n or \r\n. A
+// line break at the end does not start another line.
+func stringLines(args []*OwlObj) (*OwlObj, bool) {
+	s := args[0].TrueStr()
+	lines := []*OwlObj{}
+
+	for s != "" {
+		line := s
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			line, s = s[:i], s[i+1:]
+		} else {
+			s = ""
+		}
+
+		lines = append(lines, NewString(strings.TrimSuffix(line, "\r")))
+	}
+
+	return NewList(lines), true
+}
+
+// stringFields splits the string around runs of whitespace.
+func stringFields(args []*OwlObj) (*OwlObj, bool) {
+	fields := strings.Fields(args[0].TrueStr())
+
+	objs := make([]*OwlObj, len(fields))
+	for i, v := range fields {
+		objs[i] = NewString(v)
+	}
+
+	return NewList(objs), true
+}
+
+func stringBytes(args []*OwlObj) (*OwlObj, bool) {
+	s := args[0].TrueStr()
+
+	objs := make([]*OwlObj, len(s))
+	for i := 0; i < len(s); i++ {
+		objs[i] = NewInt(int64(s[i]))
+	}
+
+	return NewList(objs), true
+}
+
+// stringToInt parses the string as an int, in base 10 or the base given.
+// Base 0 reads the base from a 0x, 0o or 0b prefix, and allows underscores.
+func stringToInt(args []*OwlObj) (*OwlObj, bool) {
+	s := strings.TrimSpace(args[0].TrueStr())
+	base := int64(10)
+
+	if len(args) > 2 {
+		return NewString("ToInt takes an optional base"), false
+	}
+
+	if len(args) == 2 {
+		b, ok := args[1].TrueInt()
+		if !ok || b < 0 || b == 1 || b > 36 {
+			return NewString("ToInt base must be 0, or from 2 to 36, got " + args[1].TrueStr()), false
+		}
+		base = b
+	}
+
+	n, ok := new(big.Int).SetString(s, int(base))
+	if !ok {
+		return NewString("Unable to parse '" + s + "' as an int"), false
+	}
+
+	return NewBigInt(n), true
+}
+
+func stringToFloat(args []*OwlObj) (*OwlObj, bool) {
+	s := strings.TrimSpace(args[0].TrueStr())
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return NewString("Unable to parse '" + s + "' as a float"), false
+	}
+
+	return NewFloat(f), true
+}
+
+// stringFormat formats values printf style. %d, %x, %X, %o, %b and %c take
+// ints, %e, %f and %g any number, and %s, %q and %v any value, in its string
+// form.
+func stringFormat(args []*OwlObj) (*OwlObj, bool) {
+	format := args[0].TrueStr()
+	values := args[1:]
+	b := strings.Builder{}
+	n := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.", format[j]) >= 0 {
+			j++
+		}
+
+		if j == len(format) {
+			return NewString("Format string ends in an incomplete verb '" + format[i:] + "'"), false
+		}
+
+		spec := format[i : j+1]
+		i = j
+
+		if format[j] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+
+		if n == len(values) {
+			return NewString("Format has no value for " + spec + ", got " + pluralize(len(values), "value")), false
+		}
+
+		v, msg := formatValue(format[j], values[n])
+		if msg != "" {
+			return NewString("Unable to format " + describe(values[n]) + " with " + spec + ", " + msg), false
+		}
+
+		b.WriteString(fmt.Sprintf(spec, v))
+		n++
+	}
+
+	if n < len(values) {
+		return NewString("Format has " + pluralize(len(values), "value") + " for " + pluralize(n, "verb")), false
+	}
+
+	return NewString(b.String()), true
+}
+
+// formatValue converts o to the Go value that verb formats.
+func formatValue(verb byte, o *OwlObj) (interface{}, string) {
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b', 'c':
+		switch raw := o.Raw.(type) {
+		case int64:
+			if verb == 'c' {
+				return rune(raw), ""
+			}
+			return raw, ""
+		case *big.Int:
+			if verb != 'c' {
+				return raw, ""
+			}
+		}
+		return nil, "it expects an int"
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		if getRawType(o) == UNKNOWN {
+			return nil, "it expects a number"
+		}
+		return toFloat(o), ""
+	case 's', 'q', 'v':
+		return o.TrueStr(), ""
+	}
+
+	return nil, "it is not a known verb"
 }
@@ -0,0 +1,132 @@
+package exec
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/AnthonyEdvalson/owl/decimal"
+)
+
+// typeOf names the type of o for typeof. It is typeName, except that
+// functions implemented in Go are native rather than function.
+func typeOf(o *OwlObj) string {
+	if _, ok := o.Raw.(*FuncData); !ok && o.BridgeCall != nil {
+		return "native"
+	}
+
+	return typeName(o)
+}
+
+// HasAttr reports whether every value of the built-in type typ, named as by
+// typeof, has the attribute name, such as a method of strings. Objects and
+// functions have no shared attributes, theirs are their own.
+func HasAttr(typ string, name string) bool {
+	var m *methodTable
+
+	switch typ {
+	case "bool":
+		m = boolMethods
+	case "null":
+		m = nullMethods
+	case "int", "float", "decimal":
+		m = numberMethods
+	case "string":
+		m = stringMethods
+	case "list":
+		m = listMethods
+	case "time":
+		m = timeMethods
+	case "duration":
+		m = durationMethods
+	default:
+		return false
+	}
+
+	_, ok := m.attr[name]
+	return ok
+}
+
+func builtinTypeof(arg *OwlObj) (*OwlObj, bool) {
+	return NewString(typeOf(arg)), true
+}
+
+// cannotConvert is the message for a value that has no conversion to typ.
+func cannotConvert(o *OwlObj, typ string) string {
+	return "Unable to convert " + typeOf(o) + " " + o.TrueStr() + " to " + typ
+}
+
+// builtinInt converts numbers to ints, rounding towards zero, and parses
+// strings in base 10.
+func builtinInt(arg *OwlObj) (*OwlObj, bool) {
+	switch raw := arg.Raw.(type) {
+	case int64, *big.Int:
+		return arg, true
+	case float64:
+		if math.IsNaN(raw) || math.IsInf(raw, 0) {
+			return NewString(cannotConvert(arg, "int")), false
+		}
+		return floatToInt(math.Trunc(raw)), true
+	case decimal.Decimal:
+		n, _ := raw.Int()
+		return NewBigInt(n), true
+	case string:
+		return stringToInt([]*OwlObj{arg})
+	case bool:
+		if raw {
+			return NewInt(1), true
+		}
+		return NewInt(0), true
+	}
+
+	return NewString(cannotConvert(arg, "int")), false
+}
+
+// builtinFloat converts numbers to floats, and parses strings.
+func builtinFloat(arg *OwlObj) (*OwlObj, bool) {
+	if getRawType(arg) != UNKNOWN {
+		return NewFloat(toFloat(arg)), true
+	}
+
+	switch raw := arg.Raw.(type) {
+	case string:
+		return stringToFloat([]*OwlObj{arg})
+	case bool:
+		if raw {
+			return NewFloat(1), true
+		}
+		return NewFloat(0), true
+	}
+
+	return NewString(cannotConvert(arg, "float")), false
+}
+
+// builtinStr converts any value to the string it prints as.
+func builtinStr(arg *OwlObj) (*OwlObj, bool) {
+	if _, ok := arg.Raw.(string); ok {
+		return arg, true
+	}
+
+	if s, ok := arg.Str(); ok && s != nil {
+		if _, isString := s.Raw.(string); isString {
+			return s, true
+		}
+	}
+
+	return NewString("Unable to convert " + typeOf(arg) + " to string, its ::str does not return a string"), false
+}
+
+// builtinBool converts any value to whether it is truthy.
+func builtinBool(arg *OwlObj) (*OwlObj, bool) {
+	return NewBool(arg.IsTruthy()), true
+}
+
+// builtinList creates a new list of the values of an iterable. It is stopped
+// at the call to list when t is cancelled or out of budget.
+func (t *TreeExecutor) builtinList(arg *OwlObj) (*OwlObj, bool) {
+	values, ok := t.asList(arg, t.run.call)
+	if !ok {
+		return NewString(cannotConvert(arg, "list") + ", it is not iterable"), false
+	}
+
+	return NewList(append([]*OwlObj{}, values...)), true
+}
@@ -0,0 +1,34 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WriteFiles writes each file in files, keyed by its path relative to dir,
+// creating directories as needed.
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TempFiles writes files into a new temporary directory, and returns it.
+func TempFiles(t testing.TB, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	WriteFiles(t, dir, files)
+
+	return dir
+}
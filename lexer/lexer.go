@@ -30,7 +30,7 @@ type TokenMatcher struct {
 }
 
 var reMap = []TokenMatcher{
-	{"COMMENT", regexp.MustCompile(`//.*`)},
+	{"COMMENT", regexp.MustCompile(`#.*`)},
 	{"NEWLINE", regexp.MustCompile(`\r?\n`)},
 
 	{"IF", regexp.MustCompile(`if`)},
@@ -39,19 +39,25 @@ var reMap = []TokenMatcher{
 	{"IN", regexp.MustCompile(`in`)},
 	{"HAS", regexp.MustCompile(`has`)},
 	{"RETURN", regexp.MustCompile(`return`)},
+	{"YIELD", regexp.MustCompile(`yield`)},
 	{"LET", regexp.MustCompile(`let`)},
 	{"WHILE", regexp.MustCompile(`while`)},
 	{"CONTINUE", regexp.MustCompile(`continue`)},
 	{"BREAK", regexp.MustCompile(`break`)},
 	{"IMPORT", regexp.MustCompile(`import`)},
+	{"AS", regexp.MustCompile(`as`)},
+	{"FROM", regexp.MustCompile(`from`)},
+	{"EXPORT", regexp.MustCompile(`export`)},
 	{"PRINT", regexp.MustCompile(`print`)},
 	{"NULL", regexp.MustCompile(`null`)},
 	{"WHEN", regexp.MustCompile(`when`)},
 
 	{"ARROW", regexp.MustCompile(`=>`)},
+	{"RARROW", regexp.MustCompile(`->`)},
 
+	{"SHIFT", regexp.MustCompile(`<<|>>`)},
 	{"COMPARE", regexp.MustCompile(`==|!=|<=|>=|<|>`)},
-	{"ASSIGN", regexp.MustCompile(`([+-/*&|]|)=`)},
+	{"ASSIGN", regexp.MustCompile(`(\+|-|\*\*|\*|//|/|%|&|\||\^|<<|>>|)=`)},
 
 	{"AND", regexp.MustCompile(`and`)},
 	{"OR", regexp.MustCompile(`or`)},
@@ -72,6 +78,7 @@ var reMap = []TokenMatcher{
 	{"INCDEC", regexp.MustCompile(`\+\+|--`)},
 	{"MINUS", regexp.MustCompile(`-`)},
 	{"PLUS", regexp.MustCompile(`\+`)},
+	{"DOUBLESLASH", regexp.MustCompile(`//`)},
 	{"SLASH", regexp.MustCompile(`/`)},
 	{"DOUBLESTAR", regexp.MustCompile(`\*\*`)},
 	{"STAR", regexp.MustCompile(`\*`)},
@@ -81,12 +88,14 @@ var reMap = []TokenMatcher{
 	{"DOUBLECOLON", regexp.MustCompile(`\:\:`)},
 	{"COLON", regexp.MustCompile(`\:`)},
 	{"PIPE", regexp.MustCompile(`\|`)},
+	{"AMP", regexp.MustCompile(`&`)},
+	{"CARET", regexp.MustCompile(`\^`)},
 	{"TRIPLEDOT", regexp.MustCompile(`\.\.\.`)},
 	{"DOT", regexp.MustCompile(`\.`)},
 
 	{"STRING", regexp.MustCompile(`"([^\\"\n]|\\.)*"|'([^\\'\n]|\\.)*'`)},
 	{"BOOL", regexp.MustCompile(`true|false`)},
-	{"NUMBER", regexp.MustCompile(`[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?`)},
+	{"NUMBER", regexp.MustCompile(`0[xX][0-9a-fA-F_]+|0[oO][0-7_]+|0[bB][01_]+|([0-9][0-9_]*)?\.?[0-9][0-9_]*([eE][-+]?[0-9]+)?d?`)},
 	{"NAME", regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`)},
 	{"EOF", regexp.MustCompile(`$`)},
 }
@@ -145,6 +154,13 @@ func (l *Lexer) Tokenize(fileName string) []Token {
 	for {
 		var tok Token
 		tok, line, column = l.NextToken(line, column, fileName)
+
+		// Comments run from # to the end of the line, and are dropped here
+		// so that the parser never sees them
+		if tok.Type == "COMMENT" {
+			continue
+		}
+
 		tokens = append(tokens, tok)
 
 		if tok.Type == "EOF" {
@@ -354,6 +354,104 @@ func TestBreak(t *testing.T) {
 	compareShortTokens(t, expected, tokens)
 }
 
+func TestYield(t *testing.T) {
+	tokens := tokenize("yield yielded")
+	expected := []ShortToken{
+		{"YIELD", "yield"},
+		{"NAME", "yielded"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
+
+func TestNumbers(t *testing.T) {
+	tokens := tokenize("0xFF_FF 0o17 0b1010 1_000.5 19.99d 2e10 .5")
+	expected := []ShortToken{
+		{"NUMBER", "0xFF_FF"},
+		{"NUMBER", "0o17"},
+		{"NUMBER", "0b1010"},
+		{"NUMBER", "1_000.5"},
+		{"NUMBER", "19.99d"},
+		{"NUMBER", "2e10"},
+		{"NUMBER", ".5"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
+
+func TestComments(t *testing.T) {
+	tokens := tokenize("x = 7 // 2 # halve\n# whole line\ny = '#'")
+	expected := []ShortToken{
+		{"NAME", "x"},
+		{"ASSIGN", "="},
+		{"NUMBER", "7"},
+		{"DOUBLESLASH", "//"},
+		{"NUMBER", "2"},
+		{"NEWLINE", "\n"},
+		{"NEWLINE", "\n"},
+		{"NAME", "y"},
+		{"ASSIGN", "="},
+		{"STRING", "'#'"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
+
+func TestBitwise(t *testing.T) {
+	tokens := tokenize("a & b | c ^ d << 1 >> 2 // 3 <= 4")
+	expected := []ShortToken{
+		{"NAME", "a"},
+		{"AMP", "&"},
+		{"NAME", "b"},
+		{"PIPE", "|"},
+		{"NAME", "c"},
+		{"CARET", "^"},
+		{"NAME", "d"},
+		{"SHIFT", "<<"},
+		{"NUMBER", "1"},
+		{"SHIFT", ">>"},
+		{"NUMBER", "2"},
+		{"DOUBLESLASH", "//"},
+		{"NUMBER", "3"},
+		{"COMPARE", "<="},
+		{"NUMBER", "4"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+
+	tokens = tokenize("a <<= 1\na //= 2\na **= 3\na %= 4\na,=.=")
+	expected = []ShortToken{
+		{"NAME", "a"},
+		{"ASSIGN", "<<="},
+		{"NUMBER", "1"},
+		{"NEWLINE", "\n"},
+		{"NAME", "a"},
+		{"ASSIGN", "//="},
+		{"NUMBER", "2"},
+		{"NEWLINE", "\n"},
+		{"NAME", "a"},
+		{"ASSIGN", "**="},
+		{"NUMBER", "3"},
+		{"NEWLINE", "\n"},
+		{"NAME", "a"},
+		{"ASSIGN", "%="},
+		{"NUMBER", "4"},
+		{"NEWLINE", "\n"},
+		{"NAME", "a"},
+		{"COMMA", ","},
+		{"ASSIGN", "="},
+		{"DOT", "."},
+		{"ASSIGN", "="},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
+
 func TestImport(t *testing.T) {
 	tokens := tokenize("import 'x'")
 	expected := []ShortToken{
@@ -365,6 +463,37 @@ func TestImport(t *testing.T) {
 	compareShortTokens(t, expected, tokens)
 }
 
+func TestImportAlias(t *testing.T) {
+	tokens := tokenize("import 'x' as y")
+	expected := []ShortToken{
+		{"IMPORT", "import"},
+		{"STRING", "'x'"},
+		{"AS", "as"},
+		{"NAME", "y"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
+
+func TestFromImport(t *testing.T) {
+	tokens := tokenize("from 'x' import a, b\nexport fromage")
+	expected := []ShortToken{
+		{"FROM", "from"},
+		{"STRING", "'x'"},
+		{"IMPORT", "import"},
+		{"NAME", "a"},
+		{"COMMA", ","},
+		{"NAME", "b"},
+		{"NEWLINE", "\n"},
+		{"EXPORT", "export"},
+		{"NAME", "fromage"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
+
 func TestIllegal(t *testing.T) {
 	tokens := tokenize("x @ y + 3")
 	expected := []Token{
@@ -504,3 +633,24 @@ func TestWhen(t *testing.T) {
 
 	compareShortTokens(t, expected, tokens)
 }
+
+func TestTypeAnnotation(t *testing.T) {
+	tokens := tokenize("(a: int) -> int => a-->b")
+	expected := []ShortToken{
+		{"LPAREN", "("},
+		{"NAME", "a"},
+		{"COLON", ":"},
+		{"NAME", "int"},
+		{"RPAREN", ")"},
+		{"RARROW", "->"},
+		{"NAME", "int"},
+		{"ARROW", "=>"},
+		{"NAME", "a"},
+		{"INCDEC", "--"},
+		{"COMPARE", ">"},
+		{"NAME", "b"},
+		{"EOF", ""},
+	}
+
+	compareShortTokens(t, expected, tokens)
+}
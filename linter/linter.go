@@ -0,0 +1,174 @@
+// Package linter finds common mistakes in Owl programs by looking at their
+// syntax trees, without running them. Each kind of mistake is found by a
+// rule, which can be enabled or disabled on its own.
+package linter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/lexer"
+	"github.com/AnthonyEdvalson/owl/manifest"
+)
+
+const SUFFIX = ".hoot"
+
+// Rule is a kind of mistake the linter looks for.
+type Rule struct {
+	Name        string
+	Description string
+}
+
+// Rules are the rules of the linter. Files that cannot be parsed are always
+// reported, with the rule name "syntax".
+var Rules = []Rule{
+	{"undefined", "names that are never defined"},
+	{"unused-variable", "variables that are assigned but never used"},
+	{"unused-import", "imported modules and names that are never used"},
+	{"shadow", "variables that hide a variable of an enclosing function, or a builtin"},
+	{"unreachable", "code after return, break or continue"},
+	{"assign-in-condition", "assignments used as conditions"},
+	{"duplicate-key", "keys written more than once in an object"},
+	{"unreachable-case", "overload cases that can never match"},
+}
+
+// Problem is a mistake found by a rule, at Token in the file at Path.
+type Problem struct {
+	Path    string
+	Token   lexer.Token
+	Rule    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", p.Path, p.Token.Line, p.Token.Column, p.Message, p.Rule)
+}
+
+// Select returns the names of the rules to run. Every rule runs when enable
+// is empty, otherwise only the rules in it do, and the rules in disable never
+// run. Unknown rule names are an error.
+func Select(enable []string, disable []string) ([]string, error) {
+	known := map[string]bool{}
+	for _, r := range Rules {
+		known[r.Name] = true
+	}
+
+	for _, name := range append(append([]string{}, enable...), disable...) {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown rule '%s'", name)
+		}
+	}
+
+	selected := []string{}
+	for _, r := range Rules {
+		if (len(enable) == 0 || contains(enable, r.Name)) && !contains(disable, r.Name) {
+			selected = append(selected, r.Name)
+		}
+	}
+
+	return selected, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Discover returns every Owl file in dir and its subdirectories, sorted by
+// path. Vendored dependencies are skipped.
+func Discover(dir string) ([]string, error) {
+	paths := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && path != dir && d.Name() == manifest.VENDOR_DIR {
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() && strings.HasSuffix(d.Name(), SUFFIX) {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	sort.Strings(paths)
+
+	return paths, err
+}
+
+// LintFile lints the file at path with the named rules.
+func LintFile(path string, rules []string) ([]Problem, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Lint(string(bytes), path, rules), nil
+}
+
+// Lint lints the source of the file at path with the named rules. The
+// problems are sorted by position.
+func Lint(src string, path string, rules []string) []Problem {
+	params, errs := exec.LoadProgram(src, path)
+	if errs != nil {
+		problems := []Problem{}
+		for _, e := range errs {
+			problems = append(problems, Problem{Path: path, Token: e.Token, Rule: "syntax", Message: e.Message})
+		}
+		return problems
+	}
+
+	l := &linter{path: path, rules: map[string]bool{}}
+	for _, r := range rules {
+		l.rules[r] = true
+	}
+	l.program(params.Program)
+
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		a, b := l.problems[i].Token, l.problems[j].Token
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return l.problems
+}
+
+type jsonProblem struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// WriteJSON writes the problems as a JSON array, with an object for each
+// problem.
+func WriteJSON(w io.Writer, problems []Problem) error {
+	out := make([]jsonProblem, len(problems))
+	for i, p := range problems {
+		out[i] = jsonProblem{p.Path, p.Token.Line, p.Token.Column, p.Rule, p.Message}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
@@ -0,0 +1,146 @@
+package linter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/internal/testutil"
+)
+
+// lint lints src with every rule, and returns the problems found without
+// their path.
+func lint(src string) []string {
+	rules, _ := Select(nil, nil)
+
+	found := []string{}
+	for _, p := range Lint(src, "main.hoot", rules) {
+		found = append(found, fmt.Sprintf("%d:%d: %s (%s)", p.Token.Line, p.Token.Column, p.Message, p.Rule))
+	}
+
+	return found
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"x = escapeMap", []string{"1:5: Unable to find variable 'escapeMap' (undefined)"}},
+		{"f = () => {\n  a = 1\n  return 2\n}", []string{"2:3: 'a' is assigned but never used (unused-variable)"}},
+		{"export f = () => 1\ng = 2", []string{"2:1: 'g' is assigned but never used (unused-variable)"}},
+		{"import 'time'\nimport './util' as u\nfrom './lib' import a, b\nprint(a)", []string{
+			"1:1: Module 'time' is imported but never used (unused-import)",
+			"2:1: Module 'u' is imported but never used (unused-import)",
+			"3:1: 'b' is imported but never used (unused-import)",
+		}},
+		{"count = 0\ninc = (n) => {\n  count = count + n\n}", []string{"3:3: 'count' shadows the variable on line 1 (shadow)"}},
+		{"list = [1]\nf = (str) => str", []string{"1:1: 'list' shadows a builtin (shadow)"}},
+		{"f = (n) => {\n  return n\n  print(n)\n}", []string{"3:3: Unreachable code after return (unreachable)"}},
+		{"for i in [1] {\n  break\n  print(i)\n}", []string{"3:3: Unreachable code after break (unreachable)"}},
+		{"x = 1\nif x = 2 {}\ny = (x = 3) ? 1 : 2", []string{
+			"2:6: Assignment 'x = 2' is used as a condition, use '==' to compare (assign-in-condition)",
+			"3:8: Assignment 'x = 3' is used as a condition, use '==' to compare (assign-in-condition)",
+		}},
+		{"o = {a: 1, 'b': 2,\n  a: 3}", []string{"2:3: Key 'a' is already set on line 1, only the last value is used (duplicate-key)"}},
+		{"f = (n) => 1 | (0) => 2", []string{"1:20: Overload case can never match, the case on line 1 matches every argument (unreachable-case)"}},
+		{"f = (0) => 1 | (1) => 2 | (0) => 3", []string{"1:31: Overload case can never match, the case on line 1 matches the same arguments (unreachable-case)"}},
+	}
+
+	for _, tt := range tests {
+		found := lint(tt.src)
+
+		if strings.Join(found, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.src, found)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []string{
+		// Functions can use variables assigned after them
+		"f = () => later + len([])\nlater = 1\nprint(f())",
+		// Parameters, names starting with _ and loop variables at the top
+		// level of a module that exports nothing are not unused
+		"f = (a, b) => {\n  _ = 1\n  return a\n}\nfor i in [1] {}",
+		"f = () => this.x + super.y",
+		"x = [1]\nx[0] = 2\nx[0] += 1\ny = 0\ny++\nprint(y)",
+		"g = 2\nexport f = (0) => 1 | (n) => n\nexport g",
+		"g = 1",
+		// Only variables assigned earlier are shadowed
+		"add = (l, r) => l + r\nr = add(1, 2)\nprint(r)",
+	}
+
+	for _, src := range tests {
+		if found := lint(src); len(found) != 0 {
+			t.Errorf("Expected no problems for %q, got %q", src, found)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	rules, err := Select([]string{"undefined", "shadow"}, []string{"shadow"})
+	if err != nil || strings.Join(rules, ",") != "undefined" {
+		t.Errorf("Expected only undefined, got %q, %v", rules, err)
+	}
+
+	if _, err := Select(nil, []string{"nope"}); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+
+	found := Lint("list = missing", "main.hoot", []string{"shadow"})
+	if len(found) != 1 || found[0].Rule != "shadow" {
+		t.Errorf("Expected only the shadow rule to run, got %v", found)
+	}
+
+	found = Lint("x = (1 +", "main.hoot", nil)
+	if len(found) == 0 || found[0].Rule != "syntax" {
+		t.Errorf("Expected a syntax problem, got %v", found)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{
+		"main.hoot":           "x = 1",
+		"lib/util.hoot":       "x = 1",
+		"lib/notes.txt":       "x = 1",
+		"vendor/dep/dep.hoot": "x = 1",
+	})
+
+	paths, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(dir, "lib", "util.hoot"), filepath.Join(dir, "main.hoot")}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %q, got %q", expected, paths)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	rules, _ := Select(nil, nil)
+	problems := Lint("x = y", "main.hoot", rules)
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, problems); err != nil {
+		t.Fatal(err)
+	}
+
+	var out []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != 1 || out[0]["path"] != "main.hoot" || out[0]["line"] != 1.0 || out[0]["column"] != 5.0 || out[0]["rule"] != "undefined" {
+		t.Errorf("Unexpected JSON %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil || strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("Expected an empty array, got %s", buf.String())
+	}
+}
@@ -0,0 +1,442 @@
+package linter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/lexer"
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+var builtins = map[string]bool{}
+
+func init() {
+	for _, name := range exec.Builtins() {
+		builtins[name] = true
+	}
+}
+
+// The kinds of binding, parameters are never reported as unused.
+const (
+	variable = iota
+	param
+	imported
+)
+
+// binding is a name assigned in a scope, at the first place it is assigned.
+type binding struct {
+	name  string
+	token lexer.Token
+	kind  int
+	from  bool // imported with from ... import
+	used  bool
+}
+
+type reference struct {
+	name  string
+	token lexer.Token
+}
+
+// scope holds the variables of a module or a function. Blocks do not have
+// their own scope, as in the executor. Names are resolved once the whole
+// file has been walked, as a function may use variables that are assigned
+// after it is defined.
+type scope struct {
+	vars   map[string]*binding
+	order  []*binding
+	refs   []reference
+	parent *scope
+}
+
+type linter struct {
+	path     string
+	rules    map[string]bool
+	problems []Problem
+	scope    *scope
+	scopes   []*scope
+	exports  bool // the module exports names, so the others are private
+}
+
+func (l *linter) report(rule string, msg string, tok lexer.Token) {
+	if !l.rules[rule] {
+		return
+	}
+
+	l.problems = append(l.problems, Problem{Path: l.path, Token: tok, Rule: rule, Message: msg})
+}
+
+func (l *linter) push() {
+	l.scope = &scope{vars: map[string]*binding{}, parent: l.scope}
+	l.scopes = append(l.scopes, l.scope)
+}
+
+func (l *linter) program(p *parser.Program) {
+	l.push()
+	l.block(p.Body)
+
+	for _, s := range l.scopes {
+		l.resolve(s)
+	}
+	for _, s := range l.scopes {
+		l.bindings(s)
+	}
+}
+
+// ignored reports whether the name is exempt from the unused and shadow
+// rules. Names starting with _ are unused on purpose, and names starting
+// with $ are made by the parser for parameters that match a value.
+func ignored(name string) bool {
+	return strings.HasPrefix(name, "_") || strings.HasPrefix(name, "$")
+}
+
+func (l *linter) define(name string, tok lexer.Token, kind int) *binding {
+	if b, ok := l.scope.vars[name]; ok {
+		return b
+	}
+
+	b := &binding{name: name, token: tok, kind: kind}
+	l.scope.vars[name] = b
+	l.scope.order = append(l.scope.order, b)
+
+	return b
+}
+
+func (l *linter) ref(name string, tok lexer.Token) {
+	l.scope.refs = append(l.scope.refs, reference{name, tok})
+}
+
+// resolve finds the variable each name in s refers to, reporting names
+// that are not defined anywhere they could be found.
+func (l *linter) resolve(s *scope) {
+	for _, r := range s.refs {
+		found := false
+		for scope := s; scope != nil; scope = scope.parent {
+			if b, ok := scope.vars[r.name]; ok {
+				b.used = true
+				found = true
+				break
+			}
+		}
+
+		if !found && !builtins[r.name] && r.name != "this" && r.name != "super" {
+			l.report("undefined", "Unable to find variable '"+r.name+"'", r.token)
+		}
+	}
+}
+
+// bindings reports the variables of s that are never used or that hide
+// another variable or a builtin. Top level variables are only reported as
+// unused when the module exports names, as otherwise every one of them is
+// exported. A variable only hides variables assigned before it.
+func (l *linter) bindings(s *scope) {
+	for _, b := range s.order {
+		if ignored(b.name) {
+			continue
+		}
+
+		if !b.used {
+			switch {
+			case b.kind == imported && b.from:
+				l.report("unused-import", "'"+b.name+"' is imported but never used", b.token)
+			case b.kind == imported:
+				l.report("unused-import", "Module '"+b.name+"' is imported but never used", b.token)
+			case b.kind == variable && (s.parent != nil || l.exports):
+				l.report("unused-variable", "'"+b.name+"' is assigned but never used", b.token)
+			}
+		}
+
+		// Parameters are often named after builtins such as str and list
+		if builtins[b.name] {
+			if b.kind != param {
+				l.report("shadow", "'"+b.name+"' shadows a builtin", b.token)
+			}
+			continue
+		}
+
+		for outer := s.parent; outer != nil; outer = outer.parent {
+			if o, ok := outer.vars[b.name]; ok && before(o.token, b.token) {
+				l.report("shadow", fmt.Sprintf("'%s' shadows the variable on line %d", b.name, o.token.Line), b.token)
+				break
+			}
+		}
+	}
+}
+
+// before reports whether a comes before b in the file.
+func before(a lexer.Token, b lexer.Token) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+
+	return a.Column < b.Column
+}
+
+func (l *linter) block(body []parser.Statement) {
+	var end string
+
+	for _, s := range body {
+		if end != "" {
+			l.report("unreachable", "Unreachable code after "+end, s.Token())
+			end = ""
+		}
+
+		l.statement(s)
+
+		switch s.(type) {
+		case *parser.Return:
+			end = "return"
+		case *parser.Break:
+			end = "break"
+		case *parser.Continue:
+			end = "continue"
+		case *parser.Throw:
+			end = "throw"
+		}
+	}
+}
+
+func (l *linter) statement(s parser.Statement) {
+	switch s := s.(type) {
+	case *parser.Let:
+		l.expr(s.Value)
+		l.bind(s.Target, variable)
+
+	case *parser.ExpressionStatement:
+		l.expr(s.Value)
+
+	case *parser.Print:
+		l.expr(s.Value)
+
+	case *parser.Throw:
+		l.expr(s.Value)
+
+	case *parser.Return:
+		l.expr(s.Value)
+
+	case *parser.If:
+		l.condition(s.Test)
+		l.expr(s.Test)
+		l.block(s.Body)
+		l.block(s.Else)
+
+	case *parser.While:
+		l.condition(s.Test)
+		l.expr(s.Test)
+		l.block(s.Body)
+
+	case *parser.For:
+		l.expr(s.Iter)
+		l.bind(s.Target, variable)
+		l.block(s.Body)
+
+	case *parser.Try:
+		l.block(s.Body)
+		l.block(s.Catch)
+		l.block(s.Finally)
+
+	case *parser.Import:
+		if len(s.Names) > 0 {
+			for _, n := range s.Names {
+				l.define(n.Alias, s.Token(), imported).from = true
+			}
+			break
+		}
+
+		alias := s.Alias
+		if alias == "" {
+			alias = filepath.Base(filepath.FromSlash(s.Name))
+		}
+		l.define(alias, s.Token(), imported)
+
+	case *parser.Export:
+		l.exports = true
+		if s.Value != nil {
+			l.statement(s.Value)
+		}
+		for _, name := range s.Names {
+			l.ref(name, s.Token())
+		}
+	}
+}
+
+// bind defines the names assigned to by target. Indexes and attributes
+// assign to a part of a value, so they use the variable holding it.
+func (l *linter) bind(target parser.Assign, kind int) {
+	switch a := target.(type) {
+	case *parser.AssignName:
+		l.define(a.Name, a.Token(), kind)
+
+	case *parser.AssignList:
+		for _, part := range a.Parts {
+			l.bind(part, kind)
+		}
+
+	case *parser.AssignSpread:
+		l.bind(a.Target, kind)
+
+	case *parser.AssignMap:
+		l.bind(a.KeyAssign, kind)
+
+	case *parser.AssignIndex:
+		l.use(a.Target)
+		l.expr(a.Index)
+
+	case *parser.AssignAttribute:
+		l.expr(a.Target)
+	}
+}
+
+// use records the variables read to find the value that target changes.
+func (l *linter) use(target parser.Assign) {
+	switch a := target.(type) {
+	case *parser.AssignName:
+		l.ref(a.Name, a.Token())
+	case *parser.AssignIndex:
+		l.use(a.Target)
+		l.expr(a.Index)
+	case *parser.AssignAttribute:
+		l.expr(a.Target)
+	}
+}
+
+func (l *linter) expr(expr parser.Expression) {
+	switch e := expr.(type) {
+	case *parser.Name:
+		l.ref(e.Name, e.Token())
+
+	case *parser.List:
+		for _, part := range e.Parts {
+			l.expr(part)
+		}
+
+	case *parser.Set:
+		for _, v := range e.Values {
+			l.expr(v)
+		}
+
+	case *parser.Map:
+		lines := map[string]int{}
+		for i, k := range e.Keys {
+			if line, ok := lines[k]; ok {
+				l.report("duplicate-key", fmt.Sprintf("Key '%s' is already set on line %d, only the last value is used", k, line), e.KeyToken(i))
+			} else {
+				lines[k] = e.KeyToken(i).Line
+			}
+			l.expr(e.Values[i])
+		}
+
+	case *parser.BinOp:
+		l.expr(e.Left)
+		l.expr(e.Right)
+
+	case *parser.UnaryOp:
+		l.expr(e.Value)
+
+	case *parser.IfExpression:
+		l.condition(e.Test)
+		l.expr(e.Test)
+		l.expr(e.IfTrue)
+		l.expr(e.IfFalse)
+
+	case *parser.AssignExpression:
+		l.expr(e.Value)
+		if e.Op != "=" {
+			if n, ok := e.Target.(*parser.AssignName); ok {
+				l.ref(n.Name, n.Token())
+			}
+		}
+		l.bind(e.Target, variable)
+
+	case *parser.IncDec:
+		if n, ok := e.Target.(*parser.AssignName); ok {
+			l.ref(n.Name, n.Token())
+		}
+		l.bind(e.Target, variable)
+
+	case *parser.Yield:
+		l.expr(e.Value)
+
+	case *parser.FunctionDef:
+		l.function(e)
+
+	case *parser.Overload:
+		l.overload(e)
+		for i := range e.Cases {
+			l.function(&e.Cases[i])
+		}
+
+	case *parser.FunctionCall:
+		l.expr(e.Target)
+		l.expr(e.Arg)
+
+	case *parser.Attribute:
+		l.expr(e.Target)
+
+	case *parser.Index:
+		l.expr(e.Target)
+		l.expr(e.Index)
+
+	case *parser.Slice:
+		l.expr(e.Target)
+		l.expr(e.Start)
+		l.expr(e.End)
+
+	case *parser.Spread:
+		l.expr(e.Target)
+	}
+}
+
+func (l *linter) function(def *parser.FunctionDef) {
+	outer := l.scope
+	l.push()
+
+	l.bind(def.Arg, param)
+	l.expr(def.Condition)
+	l.block(def.Body)
+
+	l.scope = outer
+}
+
+// condition reports assignments used as the test of an if or while, which
+// are usually comparisons missing an '='.
+func (l *linter) condition(test parser.Expression) {
+	switch e := test.(type) {
+	case *parser.AssignExpression:
+		if e.Op == "=" {
+			l.report("assign-in-condition", "Assignment '"+e.ToString()+"' is used as a condition, use '==' to compare", e.Token())
+		}
+	case *parser.BinOp:
+		if e.Op == "and" || e.Op == "or" {
+			l.condition(e.Left)
+			l.condition(e.Right)
+		}
+	case *parser.UnaryOp:
+		l.condition(e.Value)
+	}
+}
+
+// overload reports the cases of an overload that can never be called. A
+// case without a condition accepts every argument, so the cases after it
+// are never tried, and a case that matches the same arguments as an
+// earlier one is never reached.
+func (l *linter) overload(o *parser.Overload) {
+	for i := range o.Cases {
+		c := &o.Cases[i]
+
+		for j := 0; j < i; j++ {
+			prev := &o.Cases[j]
+
+			if prev.Condition == nil {
+				l.report("unreachable-case", fmt.Sprintf("Overload case can never match, the case on line %d matches every argument", prev.Token().Line), c.Token())
+				break
+			}
+
+			if c.Condition != nil && c.Arg.ToString() == prev.Arg.ToString() && c.Condition.ToString() == prev.Condition.ToString() {
+				l.report("unreachable-case", fmt.Sprintf("Overload case can never match, the case on line %d matches the same arguments", prev.Token().Line), c.Token())
+				break
+			}
+		}
+	}
+}
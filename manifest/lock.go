@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Lock records the dependencies a project was vendored with, so that they
+// can be verified later.
+type Lock struct {
+	Dependencies map[string]LockEntry `json:"dependencies"`
+}
+
+// LockEntry is a vendored dependency. Source is the path it was copied
+// from, relative to the project.
+type LockEntry struct {
+	Source string `json:"source"`
+	Hash   string `json:"hash"`
+}
+
+// LoadLock reads the lock file in dir. A missing lock file is an empty lock.
+func LoadLock(dir string) (*Lock, error) {
+	l := &Lock{Dependencies: map[string]LockEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(dir, LOCK_FILE))
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(dir, LOCK_FILE), err)
+	}
+
+	if l.Dependencies == nil {
+		l.Dependencies = map[string]LockEntry{}
+	}
+
+	return l, nil
+}
+
+func (l *Lock) Write(dir string) error {
+	data, err := json.MarshalIndent(l, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, LOCK_FILE), append(data, '\n'), 0644)
+}
+
+// Vendor copies every dependency of the project into its vendor directory,
+// replacing anything already there, and writes a lock file recording them.
+func (m *Manifest) Vendor() (*Lock, error) {
+	deps, err := m.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	vendor := filepath.Join(m.Dir, VENDOR_DIR)
+	if err := os.RemoveAll(vendor); err != nil {
+		return nil, err
+	}
+
+	l := &Lock{Dependencies: map[string]LockEntry{}}
+
+	for _, d := range deps {
+		if err := copyDir(d.Source, filepath.Join(vendor, d.Name)); err != nil {
+			return nil, fmt.Errorf("unable to vendor %s: %w", d.Name, err)
+		}
+
+		hash, err := Hash(d.Source)
+		if err != nil {
+			return nil, fmt.Errorf("unable to hash %s: %w", d.Name, err)
+		}
+
+		source, err := filepath.Rel(m.Dir, d.Source)
+		if err != nil {
+			source = d.Source
+		}
+
+		l.Dependencies[d.Name] = LockEntry{filepath.ToSlash(source), hash}
+	}
+
+	m.deps = nil
+
+	return l, l.Write(m.Dir)
+}
+
+// Verify checks the project's dependencies against its lock file, and
+// returns a description of each problem found. Vendored dependencies are
+// checked when they exist, otherwise their sources are.
+func (m *Manifest) Verify() ([]string, error) {
+	deps, err := m.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := LoadLock(m.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	required := map[string]bool{}
+
+	for _, d := range deps {
+		required[d.Name] = true
+
+		entry, ok := l.Dependencies[d.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is missing from %s", d.Name, LOCK_FILE))
+			continue
+		}
+
+		if filepath.Clean(filepath.Join(m.Dir, filepath.FromSlash(entry.Source))) != d.Source {
+			problems = append(problems, fmt.Sprintf("%s was locked from %s, but is now required from %s", d.Name, entry.Source, d.Source))
+			continue
+		}
+
+		dir, _, err := m.ModuleDir(d.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := Hash(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if hash != entry.Hash {
+			problems = append(problems, fmt.Sprintf("%s has been modified, %s does not match %s", d.Name, dir, entry.Hash))
+		}
+	}
+
+	names := []string{}
+	for name := range l.Dependencies {
+		if !required[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		problems = append(problems, fmt.Sprintf("%s is in %s but is not a dependency", name, LOCK_FILE))
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,295 @@
+// Package manifest reads Owl project manifests, and vendors and verifies
+// the dependencies they declare.
+//
+// A project is a directory containing an owl.json manifest, such as
+//
+//	{
+//	    "name": "app",
+//	    "version": "1.0.0",
+//	    "dependencies": {
+//	        "json": "../json"
+//	    }
+//	}
+//
+// Each dependency is a directory of Owl files, given as a path relative to
+// the manifest. Dependencies can have manifests of their own, whose
+// dependencies are included as well. Vendoring copies every dependency into
+// the project's vendor directory and records a hash of each in owl.lock, so
+// that later changes to them can be detected.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	MANIFEST_FILE = "owl.json"
+	LOCK_FILE     = "owl.lock"
+	VENDOR_DIR    = "vendor"
+)
+
+type Manifest struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies"`
+
+	// Dir is the directory the manifest was loaded from
+	Dir string `json:"-"`
+
+	deps map[string]string
+}
+
+// Dependency is a module the project depends on, directly or through
+// another dependency. Source is the absolute path of its directory.
+type Dependency struct {
+	Name   string
+	Source string
+}
+
+// Load reads the manifest in dir. Dependency names are used as directory
+// names in the vendor directory, so they cannot contain path separators or
+// "..".
+func Load(dir string) (*Manifest, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(abs, MANIFEST_FILE))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(dir, MANIFEST_FILE), err)
+	}
+
+	if m.Dependencies == nil {
+		m.Dependencies = map[string]string{}
+	}
+	m.Dir = abs
+
+	names := []string{}
+	for name := range m.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !validName(name) {
+			return nil, fmt.Errorf("invalid %s: dependency name '%s' must not be empty or contain path separators or '..'", filepath.Join(dir, MANIFEST_FILE), name)
+		}
+	}
+
+	return m, nil
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && !strings.Contains(name, "..") && !strings.ContainsAny(name, `/\`)
+}
+
+// Find loads the manifest of the project containing dir, searching dir and
+// then each of its parents. It returns nil if dir is not in a project.
+func Find(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, MANIFEST_FILE)); err == nil {
+			return Load(dir)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Resolve returns every dependency of the project, including dependencies
+// of dependencies, sorted by name. Two dependencies with the same name but
+// different sources are an error.
+func (m *Manifest) Resolve() ([]Dependency, error) {
+	sources := map[string]string{}
+	if err := resolve(m, sources); err != nil {
+		return nil, err
+	}
+
+	deps := []Dependency{}
+	for name, source := range sources {
+		deps = append(deps, Dependency{name, source})
+	}
+
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+
+	return deps, nil
+}
+
+func resolve(m *Manifest, sources map[string]string) error {
+	names := []string{}
+	for name := range m.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		source := filepath.Clean(filepath.Join(m.Dir, m.Dependencies[name]))
+
+		if existing, ok := sources[name]; ok {
+			if existing != source {
+				return fmt.Errorf("dependency %s is required from both %s and %s", name, existing, source)
+			}
+			continue
+		}
+
+		info, err := os.Stat(source)
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("dependency %s: %s is not a directory", name, source)
+		}
+
+		sources[name] = source
+
+		if _, err := os.Stat(filepath.Join(source, MANIFEST_FILE)); err != nil {
+			continue
+		}
+
+		dep, err := Load(source)
+		if err != nil {
+			return err
+		}
+
+		if err := resolve(dep, sources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ModuleDir returns the directory to import the dependency name from. A
+// vendored copy is used when there is one, otherwise the dependency's
+// source.
+func (m *Manifest) ModuleDir(name string) (string, bool, error) {
+	vendored := filepath.Join(m.Dir, VENDOR_DIR, name)
+	if info, err := os.Stat(vendored); err == nil && info.IsDir() {
+		return vendored, true, nil
+	}
+
+	if m.deps == nil {
+		deps, err := m.Resolve()
+		if err != nil {
+			return "", false, err
+		}
+
+		m.deps = map[string]string{}
+		for _, d := range deps {
+			m.deps[d.Name] = d.Source
+		}
+	}
+
+	source, ok := m.deps[name]
+	return source, ok, nil
+}
+
+// Hash returns a hash of the files in dir, ignoring any vendor directory
+// inside of it.
+func Hash(dir string) (string, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+
+	for _, rel := range files {
+		f, err := os.Open(filepath.Join(dir, rel))
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		f.Close()
+
+		if err != nil {
+			return "", err
+		}
+
+		h.Write([]byte{0})
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// listFiles returns the paths of the regular files in dir relative to it,
+// sorted.
+func listFiles(dir string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && (d.Name() == VENDOR_DIR || d.Name() == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, rel)
+		return nil
+	})
+
+	sort.Strings(files)
+
+	return files, err
+}
+
+func copyDir(src string, dst string) error {
+	files, err := listFiles(src)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		data, err := os.ReadFile(filepath.Join(src, rel))
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
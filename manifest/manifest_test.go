@@ -0,0 +1,180 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/internal/testutil"
+)
+
+// project creates an app depending on a, which depends on b.
+func project(t *testing.T) string {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"app/owl.json":    `{"name": "app", "version": "1.0.0", "dependencies": {"a": "../a"}}`,
+		"app/main.hoot":   `import "a"`,
+		"app/src/x.hoot":  ``,
+		"a/owl.json":      `{"name": "a", "dependencies": {"b": "../b"}}`,
+		"a/main.hoot":     `import "b"`,
+		"a/util.hoot":     `x = 1`,
+		"b/main.hoot":     `y = 2`,
+		"b/vendor/c.hoot": `ignored`,
+	})
+
+	return filepath.Join(dir, "app")
+}
+
+func TestFind(t *testing.T) {
+	dir := project(t)
+
+	m, err := Find(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil || m.Name != "app" || m.Version != "1.0.0" || m.Dir != dir {
+		t.Fatalf("Unexpected manifest %+v", m)
+	}
+
+	m, err = Find(t.TempDir())
+	if err != nil || m != nil {
+		t.Errorf("Expected no manifest, got %+v, %v", m, err)
+	}
+
+	testutil.WriteFiles(t, dir, map[string]string{"bad/owl.json": `{"name": `})
+	if _, err := Load(filepath.Join(dir, "bad")); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("Expected an invalid manifest error, got %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := project(t)
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := m.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Dir(dir)
+	expected := []Dependency{{"a", filepath.Join(root, "a")}, {"b", filepath.Join(root, "b")}}
+
+	if len(deps) != len(expected) || deps[0] != expected[0] || deps[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, deps)
+	}
+
+	// A second copy of b under the same name is a conflict
+	testutil.WriteFiles(t, root, map[string]string{
+		"b2/main.hoot": ``,
+		"app/owl.json": `{"name": "app", "dependencies": {"a": "../a", "b": "../b2"}}`,
+	})
+
+	m, _ = Load(dir)
+	if _, err := m.Resolve(); err == nil || !strings.Contains(err.Error(), "dependency b is required from both") {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+
+	testutil.WriteFiles(t, root, map[string]string{"app/owl.json": `{"name": "app", "dependencies": {"z": "../z"}}`})
+
+	m, _ = Load(dir)
+	if _, err := m.Resolve(); err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Errorf("Expected a missing dependency error, got %v", err)
+	}
+
+	for _, name := range []string{"../x", "a/b", `a\\b`, "..", ""} {
+		testutil.WriteFiles(t, root, map[string]string{"app/owl.json": `{"name": "app", "dependencies": {"` + name + `": "../a"}}`})
+
+		if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "dependency name") {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestVendorVerify(t *testing.T) {
+	dir := project(t)
+	root := filepath.Dir(dir)
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := m.Vendor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Dependencies["a"].Source != "../a" || !strings.HasPrefix(l.Dependencies["b"].Hash, "sha256:") {
+		t.Errorf("Unexpected lock %+v", l)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "vendor", "a", "util.hoot")); err != nil || string(data) != "x = 1" {
+		t.Errorf("Expected a to be vendored, got %q, %v", data, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "vendor", "b", "vendor")); !os.IsNotExist(err) {
+		t.Errorf("Expected b's vendor directory to be skipped")
+	}
+
+	saved, err := LoadLock(dir)
+	if err != nil || len(saved.Dependencies) != 2 || saved.Dependencies["a"] != l.Dependencies["a"] {
+		t.Errorf("Expected the lock to be saved, got %+v, %v", saved, err)
+	}
+
+	if problems, err := m.Verify(); err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v, %v", problems, err)
+	}
+
+	dep, vendored, _ := m.ModuleDir("a")
+	if !vendored || dep != filepath.Join(dir, "vendor", "a") {
+		t.Errorf("Expected a to be imported from vendor, got %s", dep)
+	}
+
+	// Changing the source does not affect the vendored copy
+	testutil.WriteFiles(t, root, map[string]string{"a/util.hoot": "x = 2"})
+	if problems, _ := m.Verify(); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+
+	testutil.WriteFiles(t, dir, map[string]string{"vendor/b/main.hoot": "y = 3"})
+	problems, _ := m.Verify()
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "b has been modified") {
+		t.Errorf("Expected b to be modified, got %v", problems)
+	}
+
+	// Without a vendor directory the sources are checked
+	os.RemoveAll(filepath.Join(dir, "vendor"))
+	testutil.WriteFiles(t, root, map[string]string{"app/owl.json": `{"name": "app", "dependencies": {"a": "../a", "d": "../d"}}`, "d/main.hoot": ``})
+	delete(l.Dependencies, "b")
+	l.Dependencies["old"] = LockEntry{"../old", "sha256:00"}
+	l.Write(dir)
+
+	m, _ = Load(dir)
+	problems, err = m.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"a has been modified",
+		"b is missing from owl.lock",
+		"d is missing from owl.lock",
+		"old is in owl.lock but is not a dependency",
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, problems)
+	}
+
+	for i, p := range expected {
+		if !strings.HasPrefix(problems[i], p) {
+			t.Errorf("Expected %q, got %q", p, problems[i])
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package owl embeds the Owl interpreter in Go programs. A Runtime holds a
+// set of global variables that Owl code can be evaluated against, and Go
+// values and functions can be shared with that code. Errors in Owl code are
+// returned as errors rather than panics.
+package owl
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/lexer"
+	"github.com/AnthonyEdvalson/owl/parser"
+)
+
+// EVAL_FILE is the file name reported in errors from code run with Eval.
+const EVAL_FILE = "eval.hoot"
+
+// ParseError is returned when code given to Eval cannot be parsed.
+type ParseError struct {
+	Errors []parser.ParserError
+	Source string
+}
+
+func (e *ParseError) Error() string {
+	return strings.TrimSpace(parser.FormatErrors(e.Errors, e.Source))
+}
+
+type Runtime struct {
+	exec *exec.TreeExecutor
+}
+
+// NewRuntime creates a runtime with no globals. Relative imports in code
+// run by the runtime are resolved from dir.
+func NewRuntime(dir string) *Runtime {
+	t := exec.NewTreeExecutor(filepath.Join(dir, EVAL_FILE))
+	t.ExecProgram(&parser.Program{Body: []parser.Statement{}}, map[string]*exec.OwlObj{})
+
+	return &Runtime{t}
+}
+
+// Executor returns the executor the runtime runs code with, for hooking up a
+// debugger or profiler.
+func (r *Runtime) Executor() *exec.TreeExecutor {
+	return r.exec
+}
+
+// SetPolicy restricts what code run by the runtime can do. Limits apply to
+// each call to Eval or Call separately.
+func (r *Runtime) SetPolicy(p *exec.Policy) {
+	r.exec.Policy = p
+	r.exec.ResetLimits()
+}
+
+// start prepares the executor for a call to Eval or Call under ctx, and
+// returns a function that ends the call. Each call runs under its own
+// context, which is cancelled when the call ends so that generators left
+// paused by the call are stopped.
+func (r *Runtime) start(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+
+	r.exec.ResetLimits()
+	r.exec.SetContext(ctx)
+
+	return func() {
+		cancel()
+		r.exec.SetContext(context.Background())
+	}
+}
+
+func (r *Runtime) globals() exec.Frame {
+	return r.exec.Frames[0]
+}
+
+// Eval runs src, and returns the value it returns. If src does not return,
+// the value of its last statement is returned when it is an expression, and
+// null otherwise. Variables assigned at the top level of src become globals
+// and are visible to later calls. Generators src leaves paused are stopped
+// when it returns.
+func (r *Runtime) Eval(src string) (*exec.OwlObj, error) {
+	return r.EvalContext(context.Background(), src)
+}
+
+// EvalContext runs src like Eval, stopping it with an *exec.CancelledError
+// if ctx is cancelled first.
+func (r *Runtime) EvalContext(ctx context.Context, src string) (*exec.OwlObj, error) {
+	l := lexer.NewLexer(src)
+	p := parser.NewParser(l.Tokenize(EVAL_FILE))
+	program := p.Parse()
+
+	if len(p.Errors) > 0 {
+		return nil, &ParseError{p.Errors, src}
+	}
+
+	body := program.Body
+	var last *parser.ExpressionStatement
+	if n := len(body); n > 0 {
+		if stmt, ok := body[n-1].(*parser.ExpressionStatement); ok {
+			last = stmt
+			body = body[:n-1]
+		}
+	}
+
+	result := exec.NewNull()
+	defer r.start(ctx)()
+
+	err := r.exec.Try(func() {
+		state := r.exec.ExecBlock(body)
+
+		if state.State == exec.RETURN {
+			result = state.Return
+		} else if last != nil {
+			result = r.exec.EvalExpression(last.Value)
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		result = exec.NewNull()
+	}
+
+	return result, nil
+}
+
+// Set converts v with exec.ToOwl and assigns it to a global variable.
+func (r *Runtime) Set(name string, v interface{}) error {
+	o, err := exec.ToOwl(v)
+	if err != nil {
+		return fmt.Errorf("unable to set %s: %w", name, err)
+	}
+
+	r.globals()[name] = o
+	return nil
+}
+
+// Get returns the value of a global variable.
+func (r *Runtime) Get(name string) (*exec.OwlObj, bool) {
+	o, ok := r.globals()[name]
+	return o, ok
+}
+
+// GetAs converts a global variable into the Go value that out points to,
+// using exec.FromOwl.
+func (r *Runtime) GetAs(name string, out interface{}) error {
+	o, ok := r.Get(name)
+	if !ok {
+		return fmt.Errorf("%s is not defined", name)
+	}
+
+	if err := exec.FromOwl(o, out); err != nil {
+		return fmt.Errorf("unable to get %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Register makes a Go function available to Owl code as a global. Owl
+// arguments are converted to the types of fn's parameters, and its results
+// are converted back. If fn's last result is an error, returning a non nil
+// error fails the call in Owl.
+func (r *Runtime) Register(name string, fn interface{}) error {
+	if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		return fmt.Errorf("unable to register %s, expected a function, got %T", name, fn)
+	}
+
+	return r.Set(name, fn)
+}
+
+// Call calls the global function name with args, which are converted with
+// exec.ToOwl.
+func (r *Runtime) Call(name string, args ...interface{}) (*exec.OwlObj, error) {
+	return r.CallContext(context.Background(), name, args...)
+}
+
+// CallContext calls a global function like Call, stopping it with an
+// *exec.CancelledError if ctx is cancelled first.
+func (r *Runtime) CallContext(ctx context.Context, name string, args ...interface{}) (*exec.OwlObj, error) {
+	fn, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+
+	return r.CallValue(ctx, fn, args...)
+}
+
+// CallValue calls fn with args, which are converted with exec.ToOwl.
+func (r *Runtime) CallValue(ctx context.Context, fn *exec.OwlObj, args ...interface{}) (*exec.OwlObj, error) {
+	owlArgs := make([]*exec.OwlObj, len(args))
+	for i, arg := range args {
+		o, err := exec.ToOwl(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		owlArgs[i] = o
+	}
+
+	var result *exec.OwlObj
+	var ok bool
+	defer r.start(ctx)()
+
+	err := r.exec.Try(func() { result, ok = exec.CallArgs(fn, owlArgs...) })
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		if result == nil {
+			return nil, fmt.Errorf("value is not callable")
+		}
+		return nil, fmt.Errorf("%s", result.TrueStr())
+	}
+
+	if result == nil {
+		result = exec.NewNull()
+	}
+
+	return result, nil
+}
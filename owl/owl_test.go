@@ -0,0 +1,376 @@
+package owl
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+)
+
+type point struct {
+	X     int
+	Y     int
+	Label string `owl:"label"`
+	skip  bool
+}
+
+func newRuntime(t *testing.T) *Runtime {
+	return NewRuntime(t.TempDir())
+}
+
+func TestEval(t *testing.T) {
+	r := newRuntime(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2", "3"},
+		{"a = 5\nreturn a * 2", "10"},
+		{"a", "5"},
+		{"x = 1", "1"},
+		{"if (true) {\n    b = 1\n}", "null"},
+	}
+
+	for _, tt := range tests {
+		v, err := r.Eval(tt.input)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.input, err)
+			continue
+		}
+
+		if v.TrueStr() != tt.expected {
+			t.Errorf("Eval(%q) expected %s, got %s", tt.input, tt.expected, v.TrueStr())
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	r := newRuntime(t)
+
+	_, err := r.Eval("x = (")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+
+	_, err = r.Eval("f = () => missing\nf()")
+	var runtimeErr *exec.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected a RuntimeError, got %v", err)
+	}
+
+	if runtimeErr.Token.Line != 1 || !strings.Contains(err.Error(), "eval.hoot:1:11: Unable to find variable 'missing'") {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// The runtime can still be used after an error
+	v, err := r.Eval("f = () => 7\nf()")
+	if err != nil || v.TrueStr() != "7" {
+		t.Errorf("Expected the runtime to recover, got %v, %v", v, err)
+	}
+
+	if len(r.Executor().Frames) != 1 {
+		t.Errorf("Expected the stack to be unwound, got %d frames", len(r.Executor().Frames))
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	r := newRuntime(t)
+
+	if err := r.Set("p", point{1, 2, "origin", true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set("nums", []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set("m", map[string]float64{"a": 1.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := r.Eval("return [p.X + p.Y, p.label, nums[2], m.a]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []interface{}
+	if err := exec.FromOwl(v, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != 4 || out[0] != int64(3) || out[1] != "origin" || out[2] != int64(3) || out[3] != 1.5 {
+		t.Errorf("Unexpected result %v", out)
+	}
+
+	if _, err := r.Eval(`q = {X: 3, Y: 4, label: "q", extra: true}`); err != nil {
+		t.Fatal(err)
+	}
+
+	var q point
+	if err := r.GetAs("q", &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.X != 3 || q.Y != 4 || q.Label != "q" {
+		t.Errorf("Unexpected point %+v", q)
+	}
+
+	var s string
+	if err := r.GetAs("q", &s); err == nil {
+		t.Errorf("Expected converting an object to a string to fail")
+	}
+
+	if _, ok := r.Get("undefined"); ok {
+		t.Errorf("Expected undefined to not be set")
+	}
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestSetCycles(t *testing.T) {
+	r := newRuntime(t)
+
+	n := &node{Name: "a"}
+	n.Next = &node{Name: "b", Next: n}
+	m := map[string]interface{}{"name": "m"}
+	m["self"] = m
+
+	if err := r.Set("n", n); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set("m", m); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Set("big", uint64(math.MaxUint64)); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := r.Eval("return [n.Next.Next.Name, n.Next.Next == n, m.self.self.name, big::str()]::str()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.TrueStr() != "[a, true, m, 18446744073709551615]" {
+		t.Errorf("Unexpected result %s", v.TrueStr())
+	}
+
+	var big uint64
+	if err := r.GetAs("big", &big); err != nil || big != math.MaxUint64 {
+		t.Errorf("Expected the largest uint64, got %d, %v", big, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := newRuntime(t)
+
+	err := r.Register("scale", func(p point, by int) point {
+		return point{p.X * by, p.Y * by, p.Label, false}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Register("sum", func(nums []int) int {
+		total := 0
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	})
+
+	r.Register("check", func(n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("negative")
+		}
+		return "ok", nil
+	})
+
+	v, err := r.Eval(`p = scale({X: 1, Y: 2, label: "a"}, 3)
+return [p.X, p.Y, sum([1, 2, 3]), sum([4]), check(1)]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.TrueStr() != "[3, 6, 6, 4, ok]" {
+		t.Errorf("Unexpected result %s", v.TrueStr())
+	}
+
+	_, err = r.Eval("check(-1)")
+	if err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("Expected the Go error to fail the call, got %v", err)
+	}
+
+	_, err = r.Eval(`scale("a", 1)`)
+	if err == nil || !strings.Contains(err.Error(), "Argument 1") {
+		t.Errorf("Expected a conversion error, got %v", err)
+	}
+
+	if err := r.Register("x", 5); err == nil {
+		t.Errorf("Expected registering a non function to fail")
+	}
+}
+
+func TestCall(t *testing.T) {
+	r := newRuntime(t)
+
+	_, err := r.Eval(`add = (a, b) => a + b
+double = (x) => x * 2
+fail = () => missing`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := r.Call("add", 2, 3)
+	if err != nil || v.TrueStr() != "5" {
+		t.Errorf("Expected 5, got %v, %v", v, err)
+	}
+
+	v, err = r.Call("double", 4.5)
+	if err != nil || v.TrueStr() != "9" {
+		t.Errorf("Expected 9, got %v, %v", v, err)
+	}
+
+	if _, err := r.Call("fail"); err == nil {
+		t.Errorf("Expected an error")
+	}
+
+	if _, err := r.Call("nope"); err == nil {
+		t.Errorf("Expected an error for an undefined function")
+	}
+
+	var add func(int, int) (int, error)
+	if err := r.GetAs("add", &add); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := add(10, 20)
+	if err != nil || n != 30 {
+		t.Errorf("Expected 30, got %d, %v", n, err)
+	}
+}
+
+func TestFailedCallback(t *testing.T) {
+	r := newRuntime(t)
+
+	r.Register("apply", func(f func(int) (int, error), n int) int {
+		if v, err := f(n); err == nil {
+			return v
+		}
+		return -1
+	})
+
+	v, err := r.Eval(`fail = (x) => {
+	y = x
+	return missing
+}
+r = apply(fail, 1)
+z = 2
+return r`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.TrueStr() != "-1" {
+		t.Errorf("Expected -1, got %s", v.TrueStr())
+	}
+
+	if z, ok := r.Get("z"); !ok || z.TrueStr() != "2" {
+		t.Errorf("Expected z to be set after the failed callback, got %v, %v", z, ok)
+	}
+
+	if _, ok := r.Get("y"); ok {
+		t.Errorf("Expected the callback's local to be gone")
+	}
+}
+
+// TestGeneratorsStopped checks that generators left paused by Eval do not
+// keep their goroutines, and are finished when used again.
+func TestGeneratorsStopped(t *testing.T) {
+	r := newRuntime(t)
+	before := runtime.NumGoroutine()
+
+	v, err := r.Eval(`count = () => {
+	i = 0
+	while true {
+		yield i
+		i += 1
+	}
+}
+g = count()
+g::next()`)
+	if err != nil || v.TrueStr() != "0" {
+		t.Fatalf("Expected 0, got %v, %v", v, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Left %d goroutines running", n-before)
+	}
+
+	v, err = r.Eval("g::next() == Done")
+	if err != nil || v.TrueStr() != "true" {
+		t.Errorf("Expected the generator to be finished, got %v, %v", v, err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	r := newRuntime(t)
+	r.SetPolicy(&exec.Policy{MaxInstructions: 100})
+
+	_, err := r.Eval("while (true) {\n}")
+	var limitErr *exec.LimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected a LimitError, got %v", err)
+	}
+
+	// Limits start over for each call
+	for i := 0; i < 3; i++ {
+		if _, err := r.Eval("i = 0\nwhile (i < 40) {\n    i++\n}"); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+
+	if _, err := r.Eval(`import "os"`); err == nil {
+		t.Errorf("Expected importing os to fail")
+	}
+}
+
+func TestContext(t *testing.T) {
+	r := newRuntime(t)
+
+	if _, err := r.Eval("spin = () => {\n    while (true) {\n    }\n}"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := r.CallContext(ctx, "spin")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, err = r.EvalContext(ctx, "spin()")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the call to be cancelled, got %v", err)
+	}
+
+	// Later calls are not affected by the cancelled context
+	v, err := r.Eval("1 + 1")
+	if err != nil || v.TrueStr() != "2" {
+		t.Errorf("Expected 2, got %v, %v", v, err)
+	}
+}
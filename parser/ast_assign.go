@@ -20,8 +20,11 @@ type Assign interface {
 	enforeceAssign()
 }
 
+// AssignName assigns to a name. Type is the name's annotation, or nil when
+// it has none.
 type AssignName struct {
 	Name  string
+	Type  *Type
 	token lexer.Token
 }
 
@@ -62,6 +65,10 @@ func (a *AssignName) ToString() string {
 	var b strings.Builder
 
 	b.WriteString(a.Name)
+	if a.Type != nil {
+		b.WriteString(": ")
+		b.WriteString(a.Type.ToString())
+	}
 
 	return b.String()
 }
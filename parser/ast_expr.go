@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"math/big"
 	"strconv"
 	"strings"
 
+	"github.com/AnthonyEdvalson/owl/decimal"
 	"github.com/AnthonyEdvalson/owl/lexer"
 )
 
@@ -11,6 +13,7 @@ import (
 expression = AssignExpression(target assign, op binop, value expr)
 		   | BinOp(left expr, op binop, right expr)
            | UnaryOp(op unaryop, right expr)
+           | Yield(value expr)
 		   | FunctionCall(target expr, args []expr)
 		   | FunctionDef(args []string, body expr)
 		   | IfExpression(test expr, iftrue expr, iffalse expr)
@@ -54,6 +57,13 @@ type UnaryOp struct {
 	token lexer.Token
 }
 
+// Yield pauses a generator, giving Value to its caller. Its value is what
+// the caller sends back when the generator resumes.
+type Yield struct {
+	Value Expression
+	token lexer.Token
+}
+
 type IncDec struct {
 	Target Assign
 	Op     string
@@ -67,11 +77,15 @@ type FunctionCall struct {
 	token      lexer.Token
 }
 
+// FunctionDef is a function. Returns is the annotated type of its result,
+// or nil when it has none.
 type FunctionDef struct {
 	Arg       Assign
 	Condition Expression
 	Body      []Statement
 	Else      *FunctionDef
+	Generator bool
+	Returns   *Type
 	token     lexer.Token
 }
 
@@ -82,10 +96,13 @@ type IfExpression struct {
 	token   lexer.Token
 }
 
+// Map is an object literal. A key may appear more than once, in which case
+// the last value is used.
 type Map struct {
-	Keys   []string
-	Values []Expression
-	token  lexer.Token
+	Keys      []string
+	Values    []Expression
+	keyTokens []lexer.Token
+	token     lexer.Token
 }
 
 type Set struct {
@@ -220,6 +237,14 @@ func (u *UnaryOp) ToString() string {
 	return b.String()
 }
 
+func (y *Yield) ToString() string {
+	if y.Value == nil {
+		return "(yield)"
+	}
+
+	return "(yield " + y.Value.ToString() + ")"
+}
+
 func (o *IncDec) ToString() string {
 	var b strings.Builder
 
@@ -254,6 +279,23 @@ func (f *FunctionCall) ToString() string {
 	return b.String()
 }
 
+// ArgCount returns the number of arguments the call is written with. The
+// arguments of f(a, b) are a list, like those of f([a, b]), but the first
+// has two arguments and the second one. Only lists made by commas keep the
+// comma as their token.
+func (f *FunctionCall) ArgCount() int {
+	switch a := f.Arg.(type) {
+	case nil:
+		return 0
+	case *List:
+		if a.token.Type == "COMMA" {
+			return len(a.Parts)
+		}
+	}
+
+	return 1
+}
+
 func (f *FunctionDef) ToString() string {
 	var b strings.Builder
 
@@ -266,7 +308,12 @@ func (f *FunctionDef) ToString() string {
 	if f.Arg != nil {
 		b.WriteString(f.Arg.ToString())
 	}
-	b.WriteString(") => {\n")
+	b.WriteString(")")
+	if f.Returns != nil {
+		b.WriteString(" -> ")
+		b.WriteString(f.Returns.ToString())
+	}
+	b.WriteString(" => {\n")
 
 	printBlock(&b, f.Body)
 
@@ -359,6 +406,10 @@ func (c *Const) ToString() string {
 		return strconv.FormatInt(int64(v), 10)
 	case float64:
 		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *big.Int:
+		return v.String()
+	case decimal.Decimal:
+		return v.String() + "d"
 	case string:
 		return "\"" + v + "\""
 	case bool:
@@ -478,6 +529,7 @@ func (a *AssignExpression) enforceExpression() {}
 func (b *BinOp) enforceExpression()            {}
 func (c *List) enforceExpression()             {}
 func (u *UnaryOp) enforceExpression()          {}
+func (y *Yield) enforceExpression()            {}
 func (i *IncDec) enforceExpression()           {}
 func (f *FunctionCall) enforceExpression()     {}
 func (f *FunctionDef) enforceExpression()      {}
@@ -497,6 +549,7 @@ func (n *AssignExpression) Token() lexer.Token { return n.token }
 func (n *BinOp) Token() lexer.Token            { return n.token }
 func (n *List) Token() lexer.Token             { return n.token }
 func (n *UnaryOp) Token() lexer.Token          { return n.token }
+func (n *Yield) Token() lexer.Token            { return n.token }
 func (n *IncDec) Token() lexer.Token           { return n.token }
 func (n *FunctionCall) Token() lexer.Token     { return n.token }
 func (n *FunctionDef) Token() lexer.Token      { return n.token }
@@ -511,3 +564,6 @@ func (n *Slice) Token() lexer.Token            { return n.token }
 func (n *Name) Token() lexer.Token             { return n.token }
 func (n *Spread) Token() lexer.Token           { return n.token }
 func (n *Overload) Token() lexer.Token         { return n.token }
+
+// KeyToken returns the token of the i'th key of the object.
+func (n *Map) KeyToken(i int) lexer.Token { return n.keyTokens[i] }
@@ -17,7 +17,8 @@ statement = Let(target assign, value expr)
 		  | Return(value expr)
 		  | Break()
 		  | Continue()
-		  | Import(name string)
+		  | Import(name string, alias string, names []ImportName)
+		  | Export(names []string, value statement)
 */
 
 type Statement interface {
@@ -84,6 +85,23 @@ type Continue struct {
 type Import struct {
 	token lexer.Token
 	Name  string
+	Alias string
+	Names []ImportName
+}
+
+// ImportName is a name imported from a module with from ... import, and the
+// name it is assigned to.
+type ImportName struct {
+	Name  string
+	Alias string
+}
+
+// Export marks top level names as the public names of a module. Value is the
+// assignment that defines them, or nil when they were defined earlier.
+type Export struct {
+	token lexer.Token
+	Names []string
+	Value Statement
 }
 
 type Print struct {
@@ -234,13 +252,45 @@ func (c *Continue) ToString() string {
 func (i *Import) ToString() string {
 	var b strings.Builder
 
+	if len(i.Names) > 0 {
+		b.WriteString("from ")
+		b.WriteString(i.Name)
+		b.WriteString(" import ")
+
+		for j, n := range i.Names {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(n.Name)
+			if n.Alias != n.Name {
+				b.WriteString(" as ")
+				b.WriteString(n.Alias)
+			}
+		}
+
+		b.WriteString("\n")
+		return b.String()
+	}
+
 	b.WriteString("import ")
 	b.WriteString(i.Name)
+	if i.Alias != "" {
+		b.WriteString(" as ")
+		b.WriteString(i.Alias)
+	}
 	b.WriteString("\n")
 
 	return b.String()
 }
 
+func (e *Export) ToString() string {
+	if e.Value != nil {
+		return "export " + e.Value.ToString()
+	}
+
+	return "export " + strings.Join(e.Names, ", ") + "\n"
+}
+
 func (p *Print) ToString() string {
 	var b strings.Builder
 
@@ -266,6 +316,7 @@ func (r *Return) enforceStatement()              {}
 func (b *Break) enforceStatement()               {}
 func (c *Continue) enforceStatement()            {}
 func (i *Import) enforceStatement()              {}
+func (e *Export) enforceStatement()              {}
 func (p *Print) enforceStatement()               {}
 
 func (n *Let) Token() lexer.Token                 { return n.token }
@@ -279,4 +330,5 @@ func (n *Return) Token() lexer.Token              { return n.token }
 func (n *Break) Token() lexer.Token               { return n.token }
 func (n *Continue) Token() lexer.Token            { return n.token }
 func (n *Import) Token() lexer.Token              { return n.token }
+func (n *Export) Token() lexer.Token              { return n.token }
 func (n *Print) Token() lexer.Token               { return n.token }
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/AnthonyEdvalson/owl/lexer"
+)
+
+/*
+type = Type(name string)
+     | Type(name "list", elem type)
+     | Type(options []type)
+*/
+
+// Type is an optional type annotation on a name or a function's result. It
+// is a named type such as int, a list with Elem as the type of its items, or
+// a union of Options when Name is empty. Annotations are only read by the
+// type checker, the executor ignores them.
+type Type struct {
+	Name    string
+	Elem    *Type
+	Options []*Type
+	token   lexer.Token
+}
+
+func (t *Type) ToString() string {
+	var b strings.Builder
+
+	if len(t.Options) > 0 {
+		for i, o := range t.Options {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(o.ToString())
+		}
+
+		return b.String()
+	}
+
+	b.WriteString(t.Name)
+	if t.Elem != nil {
+		b.WriteString("[")
+		b.WriteString(t.Elem.ToString())
+		b.WriteString("]")
+	}
+
+	return b.String()
+}
+
+func (t *Type) Token() lexer.Token { return t.token }
@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AnthonyEdvalson/owl/lexer"
+)
+
+// maxErrors is the number of errors the parser will report before giving up
+// on the rest of the input.
+const maxErrors = 100
+
+// tokenNames maps token types to the text used for them in error messages.
+// Keywords that are not listed are described by their lowercase type name.
+var tokenNames = map[lexer.TokenType]string{
+	"NEWLINE":             "end of line",
+	"EOF":                 "end of file",
+	"NAME":                "a name",
+	"NUMBER":              "a number",
+	"STRING":              "a string",
+	"BOOL":                "a boolean",
+	"ARROW":               "'=>'",
+	"RARROW":              "'->'",
+	"COMPARE":             "a comparison",
+	"ASSIGN":              "'='",
+	"LPAREN":              "'('",
+	"RPAREN":              "')'",
+	"LBRACE":              "'{'",
+	"RBRACE":              "'}'",
+	"LBRACKET":            "'['",
+	"RBRACKET":            "']'",
+	"QUESTIONLPAREN":      "'?('",
+	"COMMA":               "','",
+	"QUESTIONDOT":         "'?.'",
+	"QUESTIONDOUBLECOLON": "'?::'",
+	"INCDEC":              "'++' or '--'",
+	"MINUS":               "'-'",
+	"PLUS":                "'+'",
+	"SLASH":               "'/'",
+	"DOUBLESTAR":          "'**'",
+	"STAR":                "'*'",
+	"DOUBLEQUESTION":      "'??'",
+	"PERCENT":             "'%'",
+	"QUESTION":            "'?'",
+	"DOUBLECOLON":         "'::'",
+	"COLON":               "':'",
+	"PIPE":                "'|'",
+	"TRIPLEDOT":           "'...'",
+	"DOT":                 "'.'",
+}
+
+// describeType returns the human readable name of a token type, used for
+// the "expected" half of error messages.
+func describeType(t lexer.TokenType) string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+
+	return "'" + strings.ToLower(t) + "'"
+}
+
+// describeToken returns the human readable text of a specific token, used
+// for the "got" half of error messages.
+func describeToken(tok lexer.Token) string {
+	switch tok.Type {
+	case "NEWLINE", "EOF":
+		return tokenNames[tok.Type]
+	case "NAME":
+		return "name '" + tok.Literal + "'"
+	case "NUMBER":
+		return "number " + tok.Literal
+	case "STRING":
+		return "string " + tok.Literal
+	default:
+		return "'" + tok.Literal + "'"
+	}
+}
+
+// hintFor suggests a fix for tokens that usually come from habits picked up
+// in other languages.
+func hintFor(tok lexer.Token, prev lexer.Token) string {
+	switch tok.Literal {
+	case ";":
+		return "statements are separated by newlines, semicolons are not needed"
+	case "&":
+		return "use 'and' for logical and"
+	case "|":
+		return "use 'or' for logical or"
+	}
+
+	if prev.Type == "NAME" {
+		switch prev.Literal {
+		case "function", "func", "def", "fn":
+			return "functions are written as name = (args) => body"
+		case "var", "const":
+			return "variables are declared with 'let', or by assigning to them"
+		}
+	}
+
+	return ""
+}
+
+// FormatError renders an error with the offending source line and a caret
+// underline beneath the token. source is the full text of the file the error
+// came from, if it is not available the excerpt is omitted.
+func FormatError(e ParserError, source string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s:%d:%d: %s\n", e.Token.File, e.Token.Line, e.Token.Column, e.Message))
+
+	lines := strings.Split(source, "\n")
+	if source != "" && e.Token.Line >= 1 && e.Token.Line <= len(lines) {
+		line := strings.TrimRight(lines[e.Token.Line-1], "\r")
+		gutter := fmt.Sprintf("%4d | ", e.Token.Line)
+
+		b.WriteString(gutter)
+		b.WriteString(line)
+		b.WriteString("\n")
+
+		b.WriteString(strings.Repeat(" ", len(gutter)-2))
+		b.WriteString("| ")
+
+		// Keep tabs so the caret lines up with the excerpt above it
+		for i, r := range line {
+			if i >= e.Token.Column-1 {
+				break
+			}
+			if r == '\t' {
+				b.WriteString("\t")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+
+		width := utf8.RuneCountInString(strings.TrimRight(e.Token.Literal, "\r\n"))
+		if width < 1 {
+			width = 1
+		}
+		b.WriteString(strings.Repeat("^", width))
+		b.WriteString("\n")
+	}
+
+	if e.Hint != "" {
+		b.WriteString("  hint: ")
+		b.WriteString(e.Hint)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// FormatErrors renders every error in errs using FormatError.
+func FormatErrors(errs []ParserError, source string) string {
+	var b strings.Builder
+
+	for _, e := range errs {
+		b.WriteString(FormatError(e, source))
+	}
+
+	return b.String()
+}
+
+func (p *Parser) previous() lexer.Token {
+	if p.position == 0 {
+		return lexer.Token{}
+	}
+
+	return p.input[p.position-1]
+}
+
+// atStatementEnd reports whether the parser is at a point where a statement
+// may end. Some constructs (blocks, arrow bodies) consume the newline that
+// ends them, so the previous token is checked as well.
+func (p *Parser) atStatementEnd() bool {
+	switch p.current().Type {
+	case "NEWLINE", "RBRACE", "EOF":
+		return true
+	}
+
+	switch p.previous().Type {
+	case "NEWLINE", "RBRACE":
+		return true
+	}
+
+	return false
+}
+
+// synchronize skips tokens after an error until the next statement boundary,
+// which is either a newline or the brace closing the current block. Tokens
+// inside nested brackets are skipped as a unit so that a newline inside a
+// broken list or call does not end recovery early.
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for p.current().Type != "EOF" {
+		switch p.current().Type {
+		case "LPAREN", "QUESTIONLPAREN", "LBRACKET", "LBRACE":
+			depth++
+		case "RPAREN", "RBRACKET":
+			if depth > 0 {
+				depth--
+			}
+		case "RBRACE":
+			if depth == 0 {
+				p.recovering = false
+				return
+			}
+			depth--
+		case "NEWLINE":
+			if depth == 0 {
+				p.recovering = false
+				return
+			}
+		}
+
+		p.next()
+	}
+
+	p.recovering = false
+}
+
+// skipPast skips tokens up to and including the close token that matches an
+// already consumed open token. It is used to recover inside literals that
+// span multiple lines, where newlines are not a useful boundary.
+func (p *Parser) skipPast(open lexer.TokenType, close lexer.TokenType) {
+	depth := 0
+
+	for p.current().Type != "EOF" {
+		switch p.current().Type {
+		case open:
+			depth++
+		case close:
+			if depth == 0 {
+				p.next()
+				return
+			}
+			depth--
+		}
+
+		p.next()
+	}
+}
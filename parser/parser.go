@@ -2,9 +2,11 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
+	"github.com/AnthonyEdvalson/owl/decimal"
 	"github.com/AnthonyEdvalson/owl/lexer"
 )
 
@@ -23,6 +25,7 @@ type (
 
 type ParserError struct {
 	Message string
+	Hint    string
 	Token   lexer.Token
 }
 
@@ -32,6 +35,15 @@ type Parser struct {
 
 	Errors []ParserError
 
+	// recovering is set after an error is reported, and suppresses further
+	// errors until the parser resynchronizes at the next statement.
+	recovering bool
+
+	// functions is the number of function bodies being parsed, and yields
+	// counts the yields in the innermost one, which make it a generator.
+	functions int
+	yields    int
+
 	prefixParseFns map[lexer.TokenType]prefixParseFn
 	infixParseFns  map[lexer.TokenType]infixParseFn
 }
@@ -62,9 +74,13 @@ var precedences = map[lexer.TokenType]int{
 	"DOUBLESTAR":          POWER,
 	"PERCENT":             MULDIV,
 	"SLASH":               MULDIV,
+	"DOUBLESLASH":         MULDIV,
 	"STAR":                MULDIV,
 	"PLUS":                ADDSUB,
 	"MINUS":               ADDSUB,
+	"SHIFT":               SHIFT,
+	"AMP":                 BITAND,
+	"CARET":               BITXOR,
 	"COMPARE":             COMPARE,
 	"HAS":                 COMPARE,
 	"AND":                 AND,
@@ -91,6 +107,10 @@ const (
 	OR
 	AND
 	COMPARE
+	BITOR
+	BITXOR
+	BITAND
+	SHIFT
 	ADDSUB
 	MULDIV
 	PREFIX
@@ -110,6 +130,7 @@ func NewParser(input []lexer.Token) *Parser {
 	p.registerPrefix("BOOL", p.parseBool)
 	p.registerPrefix("NOT", p.parsePrefix)
 	p.registerPrefix("MINUS", p.parsePrefix)
+	p.registerPrefix("YIELD", p.parseYield)
 	p.registerPrefix("LPAREN", p.parseParens)
 	p.registerPrefix("LBRACE", p.parseBrace)
 	p.registerPrefix("LBRACKET", p.parseBracket)
@@ -123,6 +144,10 @@ func NewParser(input []lexer.Token) *Parser {
 	p.registerInfix("STAR", p.parseBinOp)
 	p.registerInfix("DOUBLESTAR", p.parseBinOp)
 	p.registerInfix("PERCENT", p.parseBinOp)
+	p.registerInfix("DOUBLESLASH", p.parseBinOp)
+	p.registerInfix("SHIFT", p.parseBinOp)
+	p.registerInfix("AMP", p.parseBinOp)
+	p.registerInfix("CARET", p.parseBinOp)
 	p.registerInfix("COMPARE", p.parseBinOp)
 	p.registerInfix("HAS", p.parseBinOp)
 	p.registerInfix("AND", p.parseBinOp)
@@ -140,7 +165,7 @@ func NewParser(input []lexer.Token) *Parser {
 	p.registerInfix("LPAREN", p.parseCall)
 	p.registerInfix("QUESTIONLPAREN", p.parseCall)
 	p.registerInfix("INCDEC", p.parseIncDec)
-	p.registerInfix("PIPE", p.parseOverload)
+	p.registerInfix("PIPE", p.parsePipe)
 
 	return p
 }
@@ -167,12 +192,36 @@ func (p *Parser) next() {
 	p.position++
 }
 
-func (p *Parser) consume(t lexer.TokenType) {
+// consume moves past a token of type t. If the current token is of a
+// different type an error is reported and the parser does not move, leaving
+// the token for synchronize to deal with.
+func (p *Parser) consume(t lexer.TokenType) bool {
 	if p.current().Type != t {
-		p.error(fmt.Sprintf("Expected token %s, got %s", t, p.current().Type), p.current())
+		p.expected(describeType(t))
+		return false
 	}
 
 	p.next()
+	return true
+}
+
+// consumeClosing consumes a closing bracket, pointing at the opening bracket
+// in the hint if it is missing.
+func (p *Parser) consumeClosing(t lexer.TokenType, open lexer.Token) bool {
+	if p.current().Type == t {
+		p.next()
+		return true
+	}
+
+	hint := fmt.Sprintf("'%s' opened at %d:%d is never closed", open.Literal, open.Line, open.Column)
+	p.errorWithHint(fmt.Sprintf("Expected %s, got %s", describeType(t), describeToken(p.current())), hint, p.current())
+	return false
+}
+
+// expected reports that the current token is not what the parser wanted.
+func (p *Parser) expected(what string) {
+	tok := p.current()
+	p.errorWithHint(fmt.Sprintf("Expected %s, got %s", what, describeToken(tok)), hintFor(tok, p.previous()), tok)
 }
 
 func (p *Parser) consumeAny(t lexer.TokenType) {
@@ -182,11 +231,24 @@ func (p *Parser) consumeAny(t lexer.TokenType) {
 }
 
 func (p *Parser) error(msg string, token lexer.Token) {
-	p.Errors = append(p.Errors, ParserError{Message: msg, Token: token})
+	p.errorWithHint(msg, "", token)
+}
+
+func (p *Parser) errorWithHint(msg string, hint string, token lexer.Token) {
+	if p.recovering {
+		return
+	}
+
+	p.recovering = true
 
-	if len(p.Errors) > 100 {
-		panic("Too many errors")
+	if len(p.Errors) >= maxErrors {
+		// Give up on the rest of the input
+		p.Errors = append(p.Errors, ParserError{Message: "Too many errors, stopping", Token: token})
+		p.position = len(p.input) - 1
+		return
 	}
+
+	p.Errors = append(p.Errors, ParserError{Message: msg, Hint: hint, Token: token})
 }
 
 func (p *Parser) Parse() *Program {
@@ -198,6 +260,15 @@ func (p *Parser) parseProgram() *Program {
 	program := &Program{}
 	program.Body = p.parseBlock(false)
 
+	// A top level block only stops early on a stray closing brace, report it
+	// and carry on with the rest of the file
+	for p.current().Type != "EOF" {
+		p.errorWithHint("Unexpected "+describeToken(p.current()), "there is no open block for it to close", p.current())
+		p.next()
+		p.recovering = false
+		program.Body = append(program.Body, p.parseBlock(false)...)
+	}
+
 	return program
 }
 
@@ -344,6 +415,10 @@ func (p *Parser) parseStatement() Statement {
 		return p.parseContinue()
 	case "IMPORT":
 		return p.parseImport()
+	case "FROM":
+		return p.parseFromImport()
+	case "EXPORT":
+		return p.parseExport()
 	case "PRINT":
 		return p.parsePrint()
 	default:
@@ -356,8 +431,14 @@ func (p *Parser) parseBlock(braces bool) []Statement {
 
 	p.consumeAny("NEWLINE")
 
+	open := p.current()
+	if braces && p.current().Type != "LBRACE" {
+		p.errorWithHint("Expected '{', got "+describeToken(p.current()), "blocks must be wrapped in '{' and '}'", p.current())
+		return block
+	}
+
 	if braces {
-		p.consume("LBRACE")
+		p.next()
 	}
 
 	for p.current().Type != "RBRACE" && p.current().Type != "EOF" {
@@ -370,14 +451,28 @@ func (p *Parser) parseBlock(braces bool) []Statement {
 		stmt := p.parseStatement()
 		block = append(block, stmt)
 
+		if !p.recovering && !p.atStatementEnd() {
+			p.expected("end of statement")
+		}
+
+		if p.recovering {
+			if p.previous().Type == "NEWLINE" {
+				// The statement already ran up to the next line
+				p.recovering = false
+			} else {
+				p.synchronize()
+			}
+		}
+
+		// Always move forward, otherwise a token no statement can start
+		// with would be reported forever
 		if i == p.position {
-			p.error("Parser did not progress on statement "+p.current().Type+", '"+p.current().Literal+"'", p.current())
-			break
+			p.next()
 		}
 	}
 
 	if braces {
-		p.consume("RBRACE")
+		p.consumeClosing("RBRACE", open)
 	}
 
 	p.consumeAny("NEWLINE")
@@ -392,6 +487,17 @@ func (p *Parser) parseLet() *Let {
 	p.consume("LET")
 
 	target := p.parseAssign()
+	if p.current().Type == "COLON" {
+		tok := p.current()
+		p.next()
+		t := p.parseType()
+
+		if name, ok := target.(*AssignName); ok {
+			name.Type = t
+		} else {
+			p.errorWithHint("Only a single name can have a type annotation", "use a separate let for each name", tok)
+		}
+	}
 	p.consume("ASSIGN")
 	value := p.parseExpression(LOW)
 
@@ -510,11 +616,148 @@ func (p *Parser) parseImport() *Import {
 	i.token = p.current()
 
 	p.consume("IMPORT")
+
+	if p.current().Type != "STRING" {
+		p.errorWithHint("Expected a module path, got "+describeToken(p.current()), "module paths are strings, such as import \"./util\"", p.current())
+		return i
+	}
+
 	i.Name = p.parseString().(*Const).Value.(string)
 
+	if p.current().Type == "AS" {
+		p.next()
+
+		if p.current().Type != "NAME" {
+			p.expected("a name for the module")
+			return i
+		}
+
+		i.Alias = p.current().Literal
+		p.next()
+	}
+
+	return i
+}
+
+func (p *Parser) parseFromImport() *Import {
+	i := &Import{}
+	i.token = p.current()
+
+	p.consume("FROM")
+
+	if p.current().Type != "STRING" {
+		p.errorWithHint("Expected a module path, got "+describeToken(p.current()), "module paths are strings, such as from \"./util\" import a", p.current())
+		return i
+	}
+
+	i.Name = p.parseString().(*Const).Value.(string)
+
+	if !p.consume("IMPORT") {
+		return i
+	}
+
+	for {
+		if p.current().Type != "NAME" {
+			p.expected("a name to import")
+			return i
+		}
+
+		n := ImportName{p.current().Literal, p.current().Literal}
+		p.next()
+
+		if p.current().Type == "AS" {
+			p.next()
+
+			if p.current().Type != "NAME" {
+				p.expected("a name for the import")
+				return i
+			}
+
+			n.Alias = p.current().Literal
+			p.next()
+		}
+
+		i.Names = append(i.Names, n)
+
+		if p.current().Type != "COMMA" {
+			break
+		}
+		p.next()
+	}
+
 	return i
 }
 
+// parseExport parses either an assignment to export, such as export x = 1,
+// or a list of names that are already defined, such as export x, y.
+func (p *Parser) parseExport() *Export {
+	e := &Export{}
+	e.token = p.current()
+
+	p.consume("EXPORT")
+
+	var target Assign
+
+	switch p.current().Type {
+	case "LET":
+		l := p.parseLet()
+		target = l.Target
+		e.Value = l
+	default:
+		stmt := p.parseExpressionStatement()
+
+		if assign, ok := stmt.Value.(*AssignExpression); ok {
+			target = assign.Target
+			e.Value = stmt
+		} else if isNameList(stmt.Value) {
+			target = p.expressionToAssign(stmt.Value)
+		}
+	}
+
+	e.Names = p.exportNames(target)
+
+	if len(e.Names) == 0 {
+		p.error("Expected a name or an assignment to export", e.token)
+	}
+
+	return e
+}
+
+func isNameList(expr Expression) bool {
+	switch e := expr.(type) {
+	case *Name:
+		return true
+	case *List:
+		for _, part := range e.Parts {
+			if _, ok := part.(*Name); !ok {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Parser) exportNames(target Assign) []string {
+	switch a := target.(type) {
+	case *AssignName:
+		return []string{a.Name}
+	case *AssignList:
+		names := []string{}
+		for _, part := range a.Parts {
+			n, ok := part.(*AssignName)
+			if !ok {
+				return nil
+			}
+			names = append(names, n.Name)
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
 func (p *Parser) parsePrint() *Print {
 	print := &Print{}
 	print.token = p.current()
@@ -535,6 +778,76 @@ func Precedence(tokenType lexer.TokenType) int {
 	return LOW
 }
 
+// precedence is the precedence of the current token. A | is a bitwise or
+// unless it is followed by a function definition, when it joins the
+// functions into an overload.
+func (p *Parser) precedence() int {
+	if p.current().Type == "PIPE" && !p.overloadFollows() {
+		return BITOR
+	}
+
+	return Precedence(p.current().Type)
+}
+
+// overloadFollows reports whether the | at the current token is followed by
+// a function definition, or a when clause.
+func (p *Parser) overloadFollows() bool {
+	i := p.position + 1
+	for i < len(p.input) && p.input[i].Type == "NEWLINE" {
+		i++
+	}
+
+	if i >= len(p.input) {
+		return false
+	}
+
+	switch p.input[i].Type {
+	case "WHEN":
+		return true
+	case "NAME":
+		return i+1 < len(p.input) && p.input[i+1].Type == "ARROW"
+	case "LPAREN":
+		switch p.afterParens(i) {
+		case "ARROW", "RARROW", "QUESTION":
+			return true
+		}
+	}
+
+	return false
+}
+
+// paramsFollow reports whether the parens at the current token are the
+// parameters of a function definition.
+func (p *Parser) paramsFollow() bool {
+	switch p.afterParens(p.position) {
+	case "ARROW", "RARROW":
+		return true
+	}
+
+	return false
+}
+
+// afterParens is the type of the token after the parens opened at i, or EOF
+// if they are never closed.
+func (p *Parser) afterParens(i int) lexer.TokenType {
+	depth := 0
+	for ; i < len(p.input); i++ {
+		switch p.input[i].Type {
+		case "LPAREN", "QUESTIONLPAREN":
+			depth++
+		case "RPAREN":
+			depth--
+			if depth == 0 && i+1 < len(p.input) {
+				return p.input[i+1].Type
+			}
+		case "EOF":
+			return "EOF"
+		}
+	}
+
+	return "EOF"
+}
+
 // ======================================================================================
 //
 //                                    Expression Parsing
@@ -547,13 +860,13 @@ func (p *Parser) parseExpression(precedence int) Expression {
 	prefix := p.prefixParseFns[t.Type]
 
 	if prefix == nil {
-		p.error("Unexpected token "+t.Type+" '"+t.Literal+"' is not a registered prefix or infix operator", t)
+		p.expected("an expression")
 		return nil
 	}
 
 	leftExp := prefix()
 
-	for precedence < Precedence(p.current().Type) {
+	for precedence < p.precedence() {
 		t = p.current()
 
 		infix := p.infixParseFns[t.Type]
@@ -584,10 +897,33 @@ func (p *Parser) parsePrefix() Expression {
 	return unop
 }
 
+func (p *Parser) parseYield() Expression {
+	y := &Yield{}
+	y.token = p.current()
+
+	if p.functions == 0 {
+		p.errorWithHint("yield can only be used inside a function", "a function containing yield is a generator", p.current())
+	}
+	p.yields++
+
+	p.consume("YIELD")
+
+	switch p.current().Type {
+	case "NEWLINE", "EOF", "RBRACE", "RPAREN", "RBRACKET", "COMMA":
+	default:
+		y.Value = p.parseExpression(ASSIGN)
+	}
+
+	return y
+}
+
 func (p *Parser) parseParens() Expression {
 	// Question parens are used for coalesce calls
+	open := p.current()
 	if p.current().Type == "QUESTIONLPAREN" {
 		p.consume("QUESTIONLPAREN")
+	} else if p.paramsFollow() {
+		return p.parseParams()
 	} else {
 		p.consume("LPAREN")
 	}
@@ -599,11 +935,20 @@ func (p *Parser) parseParens() Expression {
 
 	inner := p.parseExpression(LOW)
 
-	p.consume("RPAREN")
+	// Parens may span lines, but if they are never closed the error belongs
+	// at the end of the line rather than on the next statement
+	end := p.position
+	p.consumeAny("NEWLINE")
+	if p.current().Type != "RPAREN" {
+		p.position = end
+	}
+
+	p.consumeClosing("RPAREN", open)
 	return inner
 }
 
 func (p *Parser) parseBracket() Expression {
+	open := p.current()
 	p.consume("LBRACKET")
 	p.consumeAny("NEWLINE")
 
@@ -615,7 +960,7 @@ func (p *Parser) parseBracket() Expression {
 	}
 
 	p.consumeAny("NEWLINE")
-	p.consume("RBRACKET")
+	p.consumeClosing("RBRACKET", open)
 
 	switch t := inner.(type) {
 	case *List:
@@ -635,31 +980,45 @@ func (p *Parser) parseBrace() Expression {
 	m.Keys = make([]string, 0)
 	m.Values = make([]Expression, 0)
 
+	open := p.current()
 	p.consume("LBRACE")
+	p.consumeAny("NEWLINE")
 
 	for p.current().Type != "RBRACE" && p.current().Type != "EOF" {
-		p.consumeAny("NEWLINE")
-
 		var name string
+		key := p.current()
 
 		if p.current().Type == "NAME" {
 			name = p.parseName().Name
 		} else if p.current().Type == "STRING" {
 			name = p.parseString().(*Const).Value.(string)
 		} else {
-			p.error("Expected NAME or STRING, got "+p.current().Type, p.current())
+			p.expected("a key name or string")
+			p.skipPast("LBRACE", "RBRACE")
+			return m
 		}
 
-		p.consume("COLON")
+		if p.current().Type != "COLON" {
+			p.errorWithHint("Expected ':', got "+describeToken(p.current()), "object entries are written as key: value", p.current())
+			p.skipPast("LBRACE", "RBRACE")
+			return m
+		}
+		p.next()
+
 		value := p.parseExpression(COMMA)
 		p.consumeAny("COMMA")
 		p.consumeAny("NEWLINE")
 
 		m.Keys = append(m.Keys, name)
 		m.Values = append(m.Values, value)
+		m.keyTokens = append(m.keyTokens, key)
+
+		if p.recovering {
+			return m
+		}
 	}
 
-	p.consume("RBRACE")
+	p.consumeClosing("RBRACE", open)
 
 	return m
 }
@@ -671,7 +1030,7 @@ func (p *Parser) parseBinOp(left Expression) Expression {
 	bop.Left = left
 	bop.Op = p.current().Literal
 
-	precedence := Precedence(p.current().Type)
+	precedence := p.precedence()
 	p.next()
 	bop.Right = p.parseExpression(precedence)
 
@@ -721,7 +1080,7 @@ func (p *Parser) parseIndex(left Expression) Expression {
 	if p.current().Type != "COLON" {
 		index = p.parseExpression(LOW)
 		if p.current().Type != "COLON" {
-			p.consume("RBRACKET")
+			p.consumeClosing("RBRACKET", token)
 			return &Index{target, index, token}
 		}
 	}
@@ -734,7 +1093,7 @@ func (p *Parser) parseIndex(left Expression) Expression {
 		end = p.parseExpression(LOW)
 	}
 
-	p.consume("RBRACKET")
+	p.consumeClosing("RBRACKET", token)
 
 	return &Slice{target, start, end, token}
 }
@@ -756,24 +1115,178 @@ func (p *Parser) parseIfExpression(left Expression) Expression {
 	return ie
 }
 
-func (p *Parser) parseArrow(left Expression) Expression {
+// parseParams parses a function whose parameters are in parens. Each
+// parameter may have a type annotation, and the parens may be followed by
+// the type of the function's result.
+func (p *Parser) parseParams() Expression {
+	open := p.current()
+	p.consume("LPAREN")
+	p.consumeAny("NEWLINE")
+
+	params := &List{}
+	types := []*Type{}
+	colons := []lexer.Token{}
+
+	for p.current().Type != "RPAREN" && p.current().Type != "EOF" {
+		part := p.parseExpression(COMMA)
+		var t *Type
+
+		colon := p.current()
+		if colon.Type == "COLON" {
+			p.next()
+			t = p.parseType()
+		}
+
+		if part != nil {
+			params.Parts = append(params.Parts, part)
+			types = append(types, t)
+			colons = append(colons, colon)
+		}
+
+		p.consumeAny("NEWLINE")
+		if p.current().Type != "COMMA" {
+			break
+		}
+		if params.token.Type == "" {
+			params.token = p.current()
+		}
+		p.next()
+		p.consumeAny("NEWLINE")
+	}
+
+	p.consumeClosing("RPAREN", open)
+
+	var left Expression
+	switch len(params.Parts) {
+	case 0:
+	case 1:
+		left = params.Parts[0]
+	default:
+		left = params
+	}
+
 	fd := &FunctionDef{}
-	fd.token = p.current()
+	fd.Arg, fd.Condition, _ = p.expressionToAssignWithMatching(left, 0)
 
+	for i, t := range types {
+		if t == nil {
+			continue
+		}
+
+		target := fd.Arg
+		if list, ok := fd.Arg.(*AssignList); ok && len(types) > 1 {
+			target = list.Parts[i]
+		}
+
+		p.annotate(params.Parts[i], target, t, colons[i])
+	}
+
+	if p.current().Type == "RARROW" {
+		p.next()
+		fd.Returns = p.parseType()
+	}
+
+	p.parseFunctionBody(fd)
+	return fd
+}
+
+// annotate puts the type annotation of the parameter expr onto target, the
+// assignment it was converted to. Only names and spread names can have
+// annotations.
+func (p *Parser) annotate(expr Expression, target Assign, t *Type, colon lexer.Token) {
+	switch e := expr.(type) {
+	case *Name:
+		target.(*AssignName).Type = t
+		return
+	case *Spread:
+		if _, ok := e.Target.(*Name); ok {
+			target.(*AssignSpread).Target.(*AssignName).Type = t
+			return
+		}
+	}
+
+	p.errorWithHint("Only names can have type annotations", "annotate the names inside the pattern instead", colon)
+}
+
+// parseType parses a type annotation: a type name, list[T] for a list of T,
+// T? for T or null, or a union of these separated by |.
+func (p *Parser) parseType() *Type {
+	t := p.parseTypeTerm()
+	if p.current().Type != "PIPE" {
+		return t
+	}
+
+	union := &Type{Options: []*Type{t}}
+	union.token = t.token
+
+	for p.current().Type == "PIPE" {
+		p.next()
+		union.Options = append(union.Options, p.parseTypeTerm())
+	}
+
+	return union
+}
+
+func (p *Parser) parseTypeTerm() *Type {
+	t := &Type{}
+	t.token = p.current()
+
+	switch p.current().Type {
+	case "NAME", "NULL":
+		t.Name = p.current().Literal
+		p.next()
+	default:
+		p.expected("a type")
+		return t
+	}
+
+	if p.current().Type == "LBRACKET" {
+		open := p.current()
+		p.next()
+		t.Elem = p.parseType()
+		p.consumeClosing("RBRACKET", open)
+	}
+
+	if p.current().Type == "QUESTION" {
+		p.next()
+		null := &Type{Name: "null", token: t.token}
+		return &Type{Options: []*Type{t, null}, token: t.token}
+	}
+
+	return t
+}
+
+func (p *Parser) parseArrow(left Expression) Expression {
+	fd := &FunctionDef{}
 	fd.Arg, fd.Condition, _ = p.expressionToAssignWithMatching(left, 0)
 
+	p.parseFunctionBody(fd)
+	return fd
+}
+
+// parseFunctionBody parses the => and body of fd.
+func (p *Parser) parseFunctionBody(fd *FunctionDef) {
+	fd.token = p.current()
 	p.consume("ARROW")
 
+	outer := p.yields
+	p.functions++
+	p.yields = 0
+
 	if p.current().Type == "LBRACE" {
 		fd.Body = p.parseBlock(true)
 	} else {
-		ret := &Return{Value: p.parseExpression(ARROW)}
+		ret := &Return{}
+		ret.token = p.current()
+		ret.Value = p.parseExpression(ARROW)
 
 		fd.Body = []Statement{ret}
 	}
 	p.consumeAny("NEWLINE")
 
-	return fd
+	fd.Generator = p.yields > 0
+	p.functions--
+	p.yields = outer
 }
 
 func (p *Parser) parseCall(left Expression) Expression {
@@ -819,7 +1332,7 @@ func (p *Parser) parseAttribute(left Expression) Expression {
 		isCoalesce = true
 		isDeep = true
 	} else {
-		p.error("Unexpected token "+p.current().Type+" '"+p.current().Literal+"' is not a valid attribute operator", p.current())
+		p.expected("'.' or '::'")
 		return nil
 	}
 
@@ -833,7 +1346,9 @@ func (p *Parser) parseAttribute(left Expression) Expression {
 	d.Attribute = p.current().Literal
 	d.IsCoalesce = isCoalesce
 	d.IsDeep = isDeep
-	p.consume("NAME")
+	if !p.consume("NAME") {
+		d.Attribute = ""
+	}
 	return d
 }
 
@@ -853,25 +1368,44 @@ func (p *Parser) parseNumber() Expression {
 	s := p.current().Literal
 	p.consume("NUMBER")
 
-	var v interface{}
+	fail := func() Expression {
+		p.error(fmt.Sprintf("Could not parse %q as a number", s), c.token)
+		return nil
+	}
 
-	fVal, fErr := strconv.ParseFloat(s, 64)
-	iVal, iErr := strconv.ParseInt(s, 0, 64)
+	// Underscores can only separate digits
+	if strings.HasSuffix(s, "_") || strings.Contains(s, "__") || strings.Contains(s, "_.") || strings.Contains(s, "._") {
+		return fail()
+	}
+	digits := strings.ReplaceAll(s, "_", "")
+
+	var v interface{}
 
-	if iErr == nil {
+	if strings.HasSuffix(digits, "d") && !isBasePrefixed(digits) {
+		d, err := decimal.Parse(strings.TrimSuffix(digits, "d"))
+		if err != nil {
+			return fail()
+		}
+		v = d
+	} else if iVal, err := strconv.ParseInt(digits, 0, 64); err == nil {
 		v = iVal
-	} else if fErr == nil {
+	} else if bVal, ok := new(big.Int).SetString(digits, 0); ok {
+		// Integers too large for an int64
+		v = bVal
+	} else if fVal, err := strconv.ParseFloat(digits, 64); err == nil && !isBasePrefixed(digits) {
 		v = fVal
 	} else {
-		msg := fmt.Sprintf("could not parse %q as integer", s)
-		p.error(msg, p.current())
-		return nil
+		return fail()
 	}
 
 	c.Value = v
 	return c
 }
 
+func isBasePrefixed(s string) bool {
+	return len(s) > 1 && s[0] == '0' && strings.ContainsRune("xXoObB", rune(s[1]))
+}
+
 func (p *Parser) parseString() Expression {
 	c := &Const{}
 	c.token = p.current()
@@ -922,6 +1456,14 @@ func (p *Parser) parseSpread() Expression {
 	return s
 }
 
+func (p *Parser) parsePipe(left Expression) Expression {
+	if p.overloadFollows() {
+		return p.parseOverload(left)
+	}
+
+	return p.parseBinOp(left)
+}
+
 func (p *Parser) parseOverload(left Expression) Expression {
 	o := &Overload{}
 	o.token = p.current()
@@ -930,7 +1472,7 @@ func (p *Parser) parseOverload(left Expression) Expression {
 	// This restriction might not be necessary. It might be possible to allow
 	// any expression, and have overflow be a generic binop
 	if !isFunc {
-		p.error("Left side of | operator must be a function definition", p.current())
+		p.errorWithHint("Left side of | operator must be a function definition", "use 'or' for logical or", p.current())
 		return nil
 	}
 
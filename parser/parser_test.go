@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/AnthonyEdvalson/owl/lexer"
@@ -264,6 +265,34 @@ func TestFunctionDef(t *testing.T) {
 	}
 }
 
+func TestTypeAnnotations(t *testing.T) {
+	input := []string{
+		"let x: int = 5",
+		"let x: list[string] | null = null",
+		"(a: int, b: string) -> list => [a, b]",
+		"(a, ...rest: list[int?]) => a",
+		"(n: float) => n",
+		"() -> bool => true",
+		"(\n\ta: int,\n\tb: int,\n) -> int => a + b",
+		"a = (n: int) => n | (s: string) -> string => s",
+	}
+
+	expected := []string{
+		"let x: int = 5",
+		"let x: list[string] | null = null",
+		"(a: int, b: string) -> list => {\nreturn [a, b]\n}",
+		"(a, ...rest: list[int | null]) => {\nreturn a\n}",
+		"(n: float) => {\nreturn n\n}",
+		"(<>) -> bool => {\nreturn true\n}",
+		"(a: int, b: int) -> int => {\nreturn (a + b)\n}",
+		"a = <(n: int) => {\nreturn n\n} | (s: string) -> string => {\nreturn s\n}>",
+	}
+
+	for i := 0; i < len(input); i++ {
+		compareTrees(t, expected[i], parse(t, input[i]))
+	}
+}
+
 func TestFunctionCall(t *testing.T) {
 	input := []string{
 		"f()",
@@ -443,15 +472,133 @@ func TestMap(t *testing.T) {
 func TestImport(t *testing.T) {
 	input := []string{
 		"import 'foo'",
+		"import './foo/bar' as baz",
+		"from './util' import a",
+		"from './util' import a, b as c",
 	}
 
 	expected := []string{
 		"import foo",
+		"import ./foo/bar as baz",
+		"from ./util import a",
+		"from ./util import a, b as c",
+	}
+
+	for i := 0; i < len(input); i++ {
+		compareTrees(t, expected[i], parse(t, input[i]))
+	}
+}
+
+func TestExport(t *testing.T) {
+	input := []string{
+		"export a = 1",
+		"export a, b = 1, 2",
+		"export let a = 1",
+		"export a, b",
+	}
+
+	expected := []string{
+		"export a = 1",
+		"export a, b = [1, 2]",
+		"export let a = 1",
+		"export a, b",
+	}
+
+	for i := 0; i < len(input); i++ {
+		compareTrees(t, expected[i], parse(t, input[i]))
+	}
+}
+
+func TestYield(t *testing.T) {
+	input := []string{
+		"() => {\nyield\nx = yield 1, 2\n}",
+		"() => yield 1",
+	}
+
+	expected := []string{
+		"(<>) => {\n(yield)\nx = (yield [1, 2])\n}",
+		"(<>) => {\nreturn (yield 1)\n}",
 	}
 
 	for i := 0; i < len(input); i++ {
 		compareTrees(t, expected[i], parse(t, input[i]))
 	}
+
+	generator := func(s string) []bool {
+		fd := parse(t, s).(*Program).Body[0].(*ExpressionStatement).Value.(*FunctionDef)
+		inner := fd.Body[0].(*Return).Value.(*FunctionDef)
+		return []bool{fd.Generator, inner.Generator}
+	}
+
+	if g := generator("() => {\nreturn () => yield 1\n}"); g[0] || !g[1] {
+		t.Errorf("Expected only the inner function to be a generator, got %v", g)
+	}
+
+	if g := generator("() => {\nreturn () => 1\nyield 2\n}"); !g[0] || g[1] {
+		t.Errorf("Expected only the outer function to be a generator, got %v", g)
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	input := []string{
+		"a | b & c",
+		"a ^ b | c",
+		"a & b == c",
+		"a == b | c",
+		"a << 1 + b",
+		"a + b // c",
+		"x |= y & 1",
+		"f = (a) => a | (b) => b | 1",
+	}
+
+	expected := []string{
+		"(a | (b & c))",
+		"((a ^ b) | c)",
+		"((a & b) == c)",
+		"(a == (b | c))",
+		"(a << (1 + b))",
+		"(a + (b // c))",
+		"x |= (y & 1)",
+		"f = <(a) => {\nreturn a\n} | (b) => {\nreturn (b | 1)\n}>",
+	}
+
+	for i := 0; i < len(input); i++ {
+		compareTrees(t, expected[i], parse(t, input[i]))
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0xff", int64(255)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"1_000_000", int64(1000000)},
+		{"1_000.5", 1000.5},
+		{"99999999999999999999", "99999999999999999999"},
+		{"19.99d", "19.99d"},
+		{"1_0.5_0d", "10.50d"},
+	}
+
+	for _, tt := range tests {
+		c := parse(t, tt.input).(*Program).Body[0].(*ExpressionStatement).Value.(*Const)
+
+		if s, ok := tt.expected.(string); ok {
+			if c.ToString() != s {
+				t.Errorf("Expected %s to parse as %s, got %s", tt.input, s, c.ToString())
+			}
+		} else if c.Value != tt.expected {
+			t.Errorf("Expected %s to parse as %v, got %v", tt.input, tt.expected, c.Value)
+		}
+	}
+
+	for _, input := range []string{"1__0", "1_", "1_.5"} {
+		if errs := parseErrors(input); len(errs) == 0 {
+			t.Errorf("Expected %s not to parse", input)
+		}
+	}
 }
 
 func TestPrint(t *testing.T) {
@@ -595,3 +742,104 @@ func TestFunctionCaseMatching(t *testing.T) {
 		compareTrees(t, expected[i], parse(t, input[i]))
 	}
 }
+
+func parseErrors(s string) []ParserError {
+	l := lexer.NewLexer(s)
+	tokens := l.Tokenize("parser_test.hoot")
+	p := NewParser(tokens)
+	p.Parse()
+
+	return p.Errors
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x = (1 + 2\ny = 3", []string{"Expected ')', got end of line"}},
+		{"x = [1, 2 3]\ny = 3", []string{"Expected ']', got number 3"}},
+		{"x = {a: 1, 2: 3}\ny = {b 2}", []string{"Expected a key name or string, got number 2", "Expected ':', got number 2"}},
+		{"if x {\n  y = (\n}\nz = 1", []string{"Expected an expression, got '}'"}},
+		{"a = 1 b = 2\nc = )\nd = 4", []string{"Expected end of statement, got name 'b'", "Expected an expression, got ')'"}},
+		{"}\nx = 1", []string{"Unexpected '}'"}},
+		{"while x {\n  y = 1\n", []string{"Expected '}', got end of file"}},
+		{"import foo", []string{"Expected a module path, got name 'foo'"}},
+		{"import 'foo' as 'bar'", []string{"Expected a name for the module, got string 'bar'"}},
+		{"from 'foo' import 1", []string{"Expected a name to import, got number 1"}},
+		{"from 'foo' a", []string{"Expected 'import', got name 'a'"}},
+		{"export 1", []string{"Expected a name or an assignment to export"}},
+		{"export a.b = 1", []string{"Expected a name or an assignment to export"}},
+		{"yield 1", []string{"yield can only be used inside a function"}},
+		{"let a, b: int = 1, 2", []string{"Only a single name can have a type annotation"}},
+		{"f = (1: int) => 1", []string{"Only names can have type annotations"}},
+		{"f = (a: 1) => a", []string{"Expected a type, got number 1"}},
+		{"f = (a) -> => a", []string{"Expected a type, got '=>'"}},
+	}
+
+	for _, tt := range tests {
+		errs := parseErrors(tt.input)
+
+		if len(errs) != len(tt.expected) {
+			t.Errorf("Expected %d errors for %q, got %d: %v", len(tt.expected), tt.input, len(errs), errs)
+			continue
+		}
+
+		for i, e := range errs {
+			if e.Message != tt.expected[i] {
+				t.Errorf("Expected error %q, got %q", tt.expected[i], e.Message)
+			}
+		}
+	}
+}
+
+func TestErrorHints(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = (1 + 2", "'(' opened at 1:5 is never closed"},
+		{"function foo() {}", "functions are written as name = (args) => body"},
+		{"x = a && b", "use 'and' for logical and"},
+		{"x = 1;", "statements are separated by newlines, semicolons are not needed"},
+		{"if x y = 1", "blocks must be wrapped in '{' and '}'"},
+	}
+
+	for _, tt := range tests {
+		errs := parseErrors(tt.input)
+
+		if len(errs) != 1 {
+			t.Errorf("Expected 1 error for %q, got %d: %v", tt.input, len(errs), errs)
+			continue
+		}
+
+		if errs[0].Hint != tt.expected {
+			t.Errorf("Expected hint %q, got %q", tt.expected, errs[0].Hint)
+		}
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	input := "let a = 1\n\tlet b = (a + 2\n"
+	errs := parseErrors(input)
+
+	expected := "parser_test.hoot:2:16: Expected ')', got end of line\n" +
+		"   2 | \tlet b = (a + 2\n" +
+		"     | \t              ^\n" +
+		"  hint: '(' opened at 2:10 is never closed\n"
+
+	actual := FormatErrors(errs, input)
+
+	if actual != expected {
+		t.Errorf("Expected\n%s\nGot\n%s", expected, actual)
+	}
+}
+
+func TestTooManyErrors(t *testing.T) {
+	input := strings.Repeat("x = )\n", maxErrors+20)
+	errs := parseErrors(input)
+
+	if len(errs) != maxErrors+1 {
+		t.Errorf("Expected %d errors, got %d", maxErrors+1, len(errs))
+	}
+}
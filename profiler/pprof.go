@@ -0,0 +1,196 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// The pprof format is a gzipped protocol buffer described in
+// https://github.com/google/pprof/blob/main/proto/profile.proto. Only the
+// handful of messages needed to describe Owl stacks are written here, which
+// is small enough to not warrant a protobuf dependency.
+
+// Field numbers from profile.proto
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+	profileTimeNanos   = 9
+	profileDuration    = 10
+	profilePeriodType  = 11
+	profilePeriod      = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID        = 1
+	functionName      = 2
+	functionSystem    = 3
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.data = append(b.data, byte(v)|0x80)
+		v >>= 7
+	}
+	b.data = append(b.data, byte(v))
+}
+
+func (b *protoBuffer) key(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(v)
+}
+
+func (b *protoBuffer) int(field int, v int64) {
+	b.uint(field, uint64(v))
+}
+
+func (b *protoBuffer) bytes(field int, v []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+func (b *protoBuffer) message(field int, m *protoBuffer) {
+	b.bytes(field, m.data)
+}
+
+func (b *protoBuffer) packed(field int, vs []uint64) {
+	p := &protoBuffer{}
+	for _, v := range vs {
+		p.varint(v)
+	}
+	b.bytes(field, p.data)
+}
+
+type stringTable struct {
+	strings []string
+	index   map[string]int64
+}
+
+func (s *stringTable) id(str string) int64 {
+	if i, ok := s.index[str]; ok {
+		return i
+	}
+
+	s.index[str] = int64(len(s.strings))
+	s.strings = append(s.strings, str)
+	return s.index[str]
+}
+
+type locationKey struct {
+	fn   uint64
+	line int
+}
+
+// WritePprof writes the profile in pprof format, so that it can be explored
+// with go tool pprof. Each sample is a stack of Owl functions, with the line
+// each one was executing.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	strs := &stringTable{index: map[string]int64{}}
+	strs.id("")
+
+	out := &protoBuffer{}
+
+	valueType := func(field int, typ string, unit string) {
+		v := &protoBuffer{}
+		v.int(valueTypeType, strs.id(typ))
+		v.int(valueTypeUnit, strs.id(unit))
+		out.message(field, v)
+	}
+
+	valueType(profileSampleType, "statements", "count")
+	valueType(profileSampleType, "time", "nanoseconds")
+
+	locations := map[locationKey]uint64{}
+	locationOrder := []locationKey{}
+
+	for _, s := range p.samples {
+		if s.hits == 0 && s.time == 0 {
+			continue
+		}
+
+		ids := make([]uint64, len(s.stack))
+
+		// pprof stacks start with the leaf
+		for i, f := range s.stack {
+			key := locationKey{f.fn.id, f.line}
+
+			id, ok := locations[key]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[key] = id
+				locationOrder = append(locationOrder, key)
+			}
+
+			ids[len(s.stack)-1-i] = id
+		}
+
+		sm := &protoBuffer{}
+		sm.packed(sampleLocationID, ids)
+		sm.packed(sampleValue, []uint64{uint64(s.hits), uint64(s.time.Nanoseconds())})
+		out.message(profileSample, sm)
+	}
+
+	for _, key := range locationOrder {
+		line := &protoBuffer{}
+		line.uint(lineFunctionID, key.fn)
+		line.int(lineLine, int64(key.line))
+
+		loc := &protoBuffer{}
+		loc.uint(locationID, locations[key])
+		loc.message(locationLine, line)
+		out.message(profileLocation, loc)
+	}
+
+	for _, f := range p.Funcs {
+		fn := &protoBuffer{}
+		fn.uint(functionID, f.id)
+		fn.int(functionName, strs.id(f.Name))
+		fn.int(functionSystem, strs.id(f.Name))
+		fn.int(functionFilename, strs.id(f.File))
+		fn.int(functionStartLine, int64(f.Line))
+		out.message(profileFunction, fn)
+	}
+
+	out.int(profileTimeNanos, p.start.UnixNano())
+	out.int(profileDuration, p.Duration().Nanoseconds())
+	valueType(profilePeriodType, "time", "nanoseconds")
+	out.int(profilePeriod, 1)
+
+	// The string table has to be written last, once every string is known
+	for _, str := range strs.strings {
+		out.bytes(profileStringTable, []byte(str))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(out.data); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
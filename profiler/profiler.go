@@ -0,0 +1,308 @@
+// Package profiler measures where time is spent in Owl programs. It attaches
+// to a TreeExecutor as its Profiler and records time and call counts per Owl
+// function and per source line.
+//
+// Time is attributed by instrumentation rather than sampling. Every time the
+// executor reports a statement, call or return, the time since the previous
+// report is charged to the line and function stack that was running.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/lexer"
+)
+
+// MAIN and ANONYMOUS are the names given to top level code and to functions
+// that were never assigned to a name. pprof strips anything in angle brackets
+// from names, so they are left plain.
+const MAIN = "main"
+const ANONYMOUS = "anonymous"
+
+type FuncStats struct {
+	Name   string
+	File   string
+	Line   int
+	Column int
+	Calls  int64
+	Self   time.Duration
+	Total  time.Duration
+
+	id     uint64
+	active int
+}
+
+type LineStats struct {
+	File string
+	Line int
+	Hits int64
+	Time time.Duration
+}
+
+type lineKey struct {
+	File string
+	Line int
+}
+
+type funcKey struct {
+	Name   string
+	File   string
+	Line   int
+	Column int
+}
+
+type frame struct {
+	fn      *FuncStats
+	file    string
+	line    int
+	entered time.Time
+}
+
+// sample is the time spent with a particular stack of functions, each at a
+// particular line.
+type sample struct {
+	stack []frame
+	hits  int64
+	time  time.Duration
+}
+
+type Profiler struct {
+	Funcs map[funcKey]*FuncStats
+	Lines map[lineKey]*LineStats
+
+	samples map[string]*sample
+	stack   []*frame
+	start   time.Time
+	end     time.Time
+	last    time.Time
+	now     func() time.Time
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		Funcs:   map[funcKey]*FuncStats{},
+		Lines:   map[lineKey]*LineStats{},
+		samples: map[string]*sample{},
+		now:     time.Now,
+	}
+}
+
+// Start begins profiling, the program's top level code is recorded as the
+// function MAIN in the file at path.
+func (p *Profiler) Start(path string) {
+	p.start = p.now()
+	p.last = p.start
+
+	main := p.function(MAIN, lexer.Token{File: path})
+	main.Calls++
+	main.active++
+	p.stack = []*frame{{fn: main, file: path, entered: p.start}}
+}
+
+// Stop ends profiling, charging any remaining time to the running code.
+func (p *Profiler) Stop() {
+	p.tick()
+	p.end = p.last
+
+	for _, f := range p.stack {
+		f.fn.active--
+		if f.fn.active == 0 {
+			f.fn.Total += p.end.Sub(f.entered)
+		}
+	}
+
+	p.stack = nil
+}
+
+// Duration returns the total time between Start and Stop.
+func (p *Profiler) Duration() time.Duration {
+	return p.end.Sub(p.start)
+}
+
+func (p *Profiler) function(name string, token lexer.Token) *FuncStats {
+	key := funcKey{name, token.File, token.Line, token.Column}
+
+	stats, ok := p.Funcs[key]
+	if !ok {
+		stats = &FuncStats{Name: name, File: token.File, Line: token.Line, Column: token.Column, id: uint64(len(p.Funcs) + 1)}
+		p.Funcs[key] = stats
+	}
+
+	return stats
+}
+
+func (p *Profiler) line(file string, line int) *LineStats {
+	key := lineKey{file, line}
+
+	stats, ok := p.Lines[key]
+	if !ok {
+		stats = &LineStats{File: file, Line: line}
+		p.Lines[key] = stats
+	}
+
+	return stats
+}
+
+// sample returns the sample for the current stack.
+func (p *Profiler) sample() *sample {
+	var b strings.Builder
+
+	for _, f := range p.stack {
+		b.WriteString(strconv.FormatUint(f.fn.id, 10))
+		b.WriteString(":")
+		b.WriteString(f.file)
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(f.line))
+		b.WriteString(";")
+	}
+
+	key := b.String()
+
+	s, ok := p.samples[key]
+	if !ok {
+		s = &sample{stack: make([]frame, len(p.stack))}
+		for i, f := range p.stack {
+			s.stack[i] = *f
+		}
+		p.samples[key] = s
+	}
+
+	return s
+}
+
+// tick charges the time since the last event to the code that was running.
+func (p *Profiler) tick() {
+	now := p.now()
+	delta := now.Sub(p.last)
+	p.last = now
+
+	if len(p.stack) == 0 {
+		return
+	}
+
+	top := p.stack[len(p.stack)-1]
+	top.fn.Self += delta
+	p.line(top.file, top.line).Time += delta
+	p.sample().time += delta
+}
+
+func (p *Profiler) OnStatement(t *exec.TreeExecutor, token lexer.Token) {
+	p.tick()
+
+	if len(p.stack) == 0 {
+		return
+	}
+
+	top := p.stack[len(p.stack)-1]
+	top.file = token.File
+	top.line = token.Line
+
+	p.line(token.File, token.Line).Hits++
+	p.sample().hits++
+}
+
+func (p *Profiler) OnCall(t *exec.TreeExecutor, fn *exec.FuncData) {
+	p.tick()
+
+	name := fn.Name
+	if name == "" {
+		name = ANONYMOUS
+	}
+
+	stats := p.function(name, fn.Token)
+	stats.Calls++
+	stats.active++
+
+	p.stack = append(p.stack, &frame{fn: stats, file: fn.Token.File, line: fn.Token.Line, entered: p.last})
+}
+
+func (p *Profiler) OnReturn(t *exec.TreeExecutor, fn *exec.FuncData) {
+	p.tick()
+
+	if len(p.stack) <= 1 {
+		return
+	}
+
+	top := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	// Only the outermost call of a recursive function counts towards its
+	// total, otherwise time would be counted once per level
+	top.fn.active--
+	if top.fn.active == 0 {
+		top.fn.Total += p.last.Sub(top.entered)
+	}
+}
+
+func percent(d time.Duration, total time.Duration) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return 100 * float64(d) / float64(total)
+}
+
+// WriteReport writes the n functions and lines that took the most time.
+func (p *Profiler) WriteReport(w io.Writer, n int) {
+	total := p.Duration()
+
+	funcs := make([]*FuncStats, 0, len(p.Funcs))
+	for _, f := range p.Funcs {
+		funcs = append(funcs, f)
+	}
+
+	sort.Slice(funcs, func(i, j int) bool {
+		if funcs[i].Self != funcs[j].Self {
+			return funcs[i].Self > funcs[j].Self
+		}
+		return funcs[i].id < funcs[j].id
+	})
+
+	lines := make([]*LineStats, 0, len(p.Lines))
+	for _, l := range p.Lines {
+		lines = append(lines, l)
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Time != lines[j].Time {
+			return lines[i].Time > lines[j].Time
+		}
+		if lines[i].File != lines[j].File {
+			return lines[i].File < lines[j].File
+		}
+		return lines[i].Line < lines[j].Line
+	})
+
+	fmt.Fprintf(w, "Total time: %v\n\n", total)
+
+	fmt.Fprintf(w, "Functions (top %d by self time)\n", n)
+	fmt.Fprintf(w, "%12s %7s %12s %10s  %s\n", "self", "self%", "total", "calls", "function")
+	for i, f := range funcs {
+		if i >= n {
+			break
+		}
+
+		loc := f.File
+		if f.Name != MAIN {
+			loc = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+
+		fmt.Fprintf(w, "%12v %6.1f%% %12v %10d  %s (%s)\n", f.Self, percent(f.Self, total), f.Total, f.Calls, f.Name, loc)
+	}
+
+	fmt.Fprintf(w, "\nLines (top %d by time)\n", n)
+	fmt.Fprintf(w, "%12s %7s %10s  %s\n", "time", "time%", "hits", "line")
+	for i, l := range lines {
+		if i >= n {
+			break
+		}
+
+		fmt.Fprintf(w, "%12v %6.1f%% %10d  %s:%d\n", l.Time, percent(l.Time, total), l.Hits, l.File, l.Line)
+	}
+}
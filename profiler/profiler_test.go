@@ -0,0 +1,179 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+)
+
+const program = `fib = (n) => {
+    if (n < 2) {
+        return n
+    }
+    return fib(n - 1) + fib(n - 2)
+}
+square = (x) => x * x
+a = fib(4)
+b = square(a)
+return b`
+
+// profile runs the test program with a clock that advances by one
+// millisecond every time it is read.
+func profile(t *testing.T) *Profiler {
+	params, errs := exec.LoadProgram(program, "profile_test.hoot")
+	if len(errs) > 0 {
+		t.Fatalf("Failed to parse program: %v", errs)
+	}
+
+	clock := time.Unix(0, 0)
+
+	p := NewProfiler()
+	p.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+
+	e := exec.NewTreeExecutor(params.Path)
+	e.Profiler = p
+
+	p.Start("profile_test.hoot")
+	result := e.ExecProgram(params.Program, params.Globals)
+	p.Stop()
+
+	if result.TrueStr() != "9" {
+		t.Fatalf("Expected the program to return 9, got %s", result.TrueStr())
+	}
+
+	return p
+}
+
+func findFunc(p *Profiler, name string) *FuncStats {
+	for _, f := range p.Funcs {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func TestCallCounts(t *testing.T) {
+	p := profile(t)
+
+	expected := map[string]int64{
+		MAIN:     1,
+		"fib":    9,
+		"square": 1,
+	}
+
+	for name, calls := range expected {
+		f := findFunc(p, name)
+		if f == nil {
+			t.Errorf("Expected function %s to be profiled", name)
+			continue
+		}
+
+		if f.Calls != calls {
+			t.Errorf("Expected %s to be called %d times, got %d", name, calls, f.Calls)
+		}
+	}
+
+	fib := findFunc(p, "fib")
+	if fib.Line != 1 || fib.File != "profile_test.hoot" {
+		t.Errorf("Expected fib to be defined at profile_test.hoot:1, got %s:%d", fib.File, fib.Line)
+	}
+}
+
+func TestLineHits(t *testing.T) {
+	p := profile(t)
+
+	expected := map[int]int64{
+		2:  9,
+		3:  5,
+		5:  4,
+		8:  1,
+		10: 1,
+	}
+
+	for line, hits := range expected {
+		l := p.Lines[lineKey{"profile_test.hoot", line}]
+		if l == nil {
+			t.Errorf("Expected line %d to be profiled", line)
+			continue
+		}
+
+		if l.Hits != hits {
+			t.Errorf("Expected line %d to be hit %d times, got %d", line, hits, l.Hits)
+		}
+	}
+}
+
+func TestTimes(t *testing.T) {
+	p := profile(t)
+
+	var self time.Duration
+	for _, f := range p.Funcs {
+		self += f.Self
+
+		if f.Total < f.Self {
+			t.Errorf("Expected %s total time %v to be at least its self time %v", f.Name, f.Total, f.Self)
+		}
+	}
+
+	if self != p.Duration() {
+		t.Errorf("Expected self times to add up to %v, got %v", p.Duration(), self)
+	}
+
+	main := findFunc(p, MAIN)
+	if main.Total != p.Duration() {
+		t.Errorf("Expected %s total time to be %v, got %v", MAIN, p.Duration(), main.Total)
+	}
+}
+
+func TestReport(t *testing.T) {
+	p := profile(t)
+
+	out := &bytes.Buffer{}
+	p.WriteReport(out, 2)
+
+	report := out.String()
+	for _, e := range []string{"Total time:", "fib (profile_test.hoot:1)", "profile_test.hoot:5"} {
+		if !strings.Contains(report, e) {
+			t.Errorf("Expected report to contain %q, got:\n%s", e, report)
+		}
+	}
+
+	if strings.Contains(report, "square") {
+		t.Errorf("Expected report to only list the top 2 functions, got:\n%s", report)
+	}
+}
+
+func TestPprof(t *testing.T) {
+	p := profile(t)
+
+	out := &bytes.Buffer{}
+	if err := p.WritePprof(out); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+
+	r, err := gzip.NewReader(out)
+	if err != nil {
+		t.Fatalf("Expected a gzipped profile: %v", err)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read profile: %v", err)
+	}
+
+	for _, e := range []string{"fib", "square", MAIN, "profile_test.hoot", "nanoseconds"} {
+		if !bytes.Contains(data, []byte(e)) {
+			t.Errorf("Expected profile to contain %q", e)
+		}
+	}
+}
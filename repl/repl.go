@@ -46,8 +46,9 @@ func tryRun(line string, env *exec.TreeExecutor) {
 	p := parser.NewParser(toks)
 	program := p.Parse()
 
-	for _, error := range p.Errors {
-		fmt.Println(error)
+	if len(p.Errors) > 0 {
+		fmt.Print(parser.FormatErrors(p.Errors, line))
+		return
 	}
 
 	state := env.ExecBlock(program.Body)
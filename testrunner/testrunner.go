@@ -0,0 +1,269 @@
+// Package testrunner finds and runs Owl test files. A test file is any file
+// ending in _test.hoot, it registers tests with the test module and each
+// file is run in its own executor so that tests in different files cannot
+// affect each other.
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AnthonyEdvalson/owl/exec"
+	"github.com/AnthonyEdvalson/owl/manifest"
+)
+
+const SUFFIX = "_test.hoot"
+
+// FileResult is the outcome of running a single test file. Error is set if
+// the file could not be loaded or failed outside of a test.
+type FileResult struct {
+	Path     string
+	Tests    []exec.TestResult
+	Error    string
+	Duration time.Duration
+}
+
+func (f *FileResult) Failed() int {
+	failed := 0
+	for _, r := range f.Tests {
+		if !r.Passed {
+			failed++
+		}
+	}
+
+	if f.Error != "" {
+		failed++
+	}
+
+	return failed
+}
+
+func (f *FileResult) Passed() int {
+	passed := 0
+	for _, r := range f.Tests {
+		if r.Passed {
+			passed++
+		}
+	}
+
+	return passed
+}
+
+// Discover returns every test file in dir and its subdirectories, sorted by
+// path. Vendored dependencies are skipped.
+func Discover(dir string) ([]string, error) {
+	paths := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && path != dir && d.Name() == manifest.VENDOR_DIR {
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() && strings.HasSuffix(d.Name(), SUFFIX) {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	sort.Strings(paths)
+
+	return paths, err
+}
+
+// RunFile executes the test file at path, then runs the tests it registered
+// whose names match filter. A nil filter runs every test.
+func RunFile(path string, filter *regexp.Regexp) (result FileResult) {
+	result.Path = path
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Error = strings.TrimSpace(fmt.Sprint(r))
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	ok, params, parseErr := exec.LoadProgramFromPath(path)
+	if !ok {
+		if parseErr == nil {
+			result.Error = "Failed to read " + path
+		} else {
+			result.Error = strings.TrimSpace(exec.FormatParserErrors(path, parseErr))
+		}
+		return result
+	}
+
+	t := exec.NewTreeExecutor(params.Path)
+	t.Tests = exec.NewTestSuite(t)
+	t.ExecProgram(params.Program, params.Globals)
+
+	result.Tests = t.Tests.Run(filter)
+
+	return result
+}
+
+// Run runs every test file in paths.
+func Run(paths []string, filter *regexp.Regexp) []FileResult {
+	results := make([]FileResult, len(paths))
+
+	for i, path := range paths {
+		results[i] = RunFile(path, filter)
+	}
+
+	return results
+}
+
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+// WriteReport writes a summary of the results, with the details of every
+// failure, and returns true if everything passed.
+func WriteReport(w io.Writer, results []FileResult) bool {
+	passed, failed := 0, 0
+
+	for _, f := range results {
+		for _, r := range f.Tests {
+			if r.Passed {
+				continue
+			}
+
+			fmt.Fprintf(w, "--- FAIL: %s (%s:%d)\n", r.Name, r.Token.File, r.Token.Line)
+			fmt.Fprintln(w, indent(r.Message))
+		}
+
+		if f.Error != "" {
+			fmt.Fprintf(w, "--- FAIL: %s\n", f.Path)
+			fmt.Fprintln(w, indent(f.Error))
+		}
+
+		status := "ok  "
+		if f.Failed() > 0 {
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(w, "%s  %s  %d passed, %d failed (%.3fs)\n", status, f.Path, f.Passed(), f.Failed(), f.Duration.Seconds())
+
+		passed += f.Passed()
+		failed += f.Failed()
+	}
+
+	fmt.Fprintf(w, "\n%d files, %d passed, %d failed\n", len(results), passed, failed)
+
+	return failed == 0
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func failure(msg string) *junitFailure {
+	first := strings.SplitN(msg, "\n", 2)[0]
+	return &junitFailure{first, msg}
+}
+
+// WriteJUnit writes the results as JUnit XML, with one test suite per file.
+func WriteJUnit(w io.Writer, results []FileResult) error {
+	out := junitSuites{}
+	var total time.Duration
+
+	for _, f := range results {
+		suite := junitSuite{
+			Name:     f.Path,
+			Failures: f.Failed(),
+			Time:     seconds(f.Duration),
+		}
+
+		for _, r := range f.Tests {
+			c := junitCase{
+				Name:      r.Name,
+				ClassName: f.Path,
+				File:      f.Path,
+				Line:      r.Token.Line,
+				Time:      seconds(r.Duration),
+			}
+
+			if !r.Passed {
+				c.Failure = failure(r.Message)
+			}
+
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		// Errors outside of a test are reported as a failed test named after
+		// the file, so that they are not lost by tools that only read cases
+		if f.Error != "" {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      filepath.Base(f.Path),
+				ClassName: f.Path,
+				File:      f.Path,
+				Time:      seconds(f.Duration),
+				Failure:   failure(f.Error),
+			})
+		}
+
+		suite.Tests = len(suite.Cases)
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		total += f.Duration
+
+		out.Suites = append(out.Suites, suite)
+	}
+
+	out.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(out); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,181 @@
+package testrunner
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/AnthonyEdvalson/owl/internal/testutil"
+)
+
+const passing = `import "test"
+
+state = {log: []}
+
+test.Setup(() => {
+    state.log = [...state.log, "setup"]
+})
+
+test.Teardown(() => {
+    state.log = [...state.log, "teardown"]
+})
+
+test.Test("first", () => {
+    test.AssertEq(state.log, ["setup"])
+})
+
+test.Test("second", () => {
+    test.AssertEq(state.log, ["setup", "teardown", "setup"])
+    test.Assert(true)
+})`
+
+const failing = `import "test"
+
+test.Test("lists", () => {
+    test.AssertEq([1, 5, 3], [1, 2, 3])
+})
+
+test.Test("objects", () => {
+    test.AssertEq({a: 1, b: "x"}, {a: 1, c: 2})
+})
+
+test.Test("assert", () => {
+    test.Assert(false, "nope")
+})
+
+test.Test("error", () => {
+    return missing
+})
+
+test.Test("after errors", () => {
+    test.AssertEq(1 + 1, 2)
+})`
+
+func TestDiscover(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{
+		"a_test.hoot":     passing,
+		"sub/b_test.hoot": passing,
+		"main.hoot":       "return 1",
+	})
+
+	paths, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(dir, "a_test.hoot"), filepath.Join(dir, "sub", "b_test.hoot")}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
+
+func TestPassing(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{"passing_test.hoot": passing})
+
+	r := RunFile(filepath.Join(dir, "passing_test.hoot"), nil)
+
+	if r.Error != "" {
+		t.Fatalf("Unexpected error: %s", r.Error)
+	}
+
+	if r.Passed() != 2 || r.Failed() != 0 {
+		for _, tr := range r.Tests {
+			t.Log(tr.Name, tr.Message)
+		}
+		t.Errorf("Expected 2 passed and 0 failed, got %d and %d", r.Passed(), r.Failed())
+	}
+}
+
+func TestFailing(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{"failing_test.hoot": failing})
+
+	r := RunFile(filepath.Join(dir, "failing_test.hoot"), nil)
+
+	expected := []struct {
+		name    string
+		passed  bool
+		message []string
+	}{
+		{"lists", false, []string{"failing_test.hoot:4:", "Expected [1, 2, 3], got [1, 5, 3]", "[1]: expected 2, got 5"}},
+		{"objects", false, []string{".b: unexpected \"x\"", ".c: missing, expected 2"}},
+		{"assert", false, []string{"failing_test.hoot:12:", "Assertion failed: nope"}},
+		{"error", false, []string{"Unable to find variable 'missing'"}},
+		{"after errors", true, nil},
+	}
+
+	if len(r.Tests) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(r.Tests))
+	}
+
+	for i, e := range expected {
+		tr := r.Tests[i]
+
+		if tr.Name != e.name || tr.Passed != e.passed {
+			t.Errorf("Expected %s passed=%v, got %s passed=%v", e.name, e.passed, tr.Name, tr.Passed)
+		}
+
+		for _, m := range e.message {
+			if !strings.Contains(tr.Message, m) {
+				t.Errorf("Expected %s message to contain %q, got %q", e.name, m, tr.Message)
+			}
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{"failing_test.hoot": failing})
+
+	r := RunFile(filepath.Join(dir, "failing_test.hoot"), regexp.MustCompile("^a"))
+
+	if len(r.Tests) != 2 || r.Tests[0].Name != "assert" || r.Tests[1].Name != "after errors" {
+		t.Errorf("Expected only tests starting with a to run, got %+v", r.Tests)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{
+		"parse_test.hoot":   "x = (",
+		"runtime_test.hoot": "import \"test\"\nx = missing",
+	})
+
+	for _, name := range []string{"parse_test.hoot", "runtime_test.hoot"} {
+		r := RunFile(filepath.Join(dir, name), nil)
+		if r.Error == "" || r.Failed() != 1 {
+			t.Errorf("Expected %s to fail with an error, got %+v", name, r)
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{
+		"failing_test.hoot": failing,
+		"passing_test.hoot": passing,
+	})
+
+	paths, _ := Discover(dir)
+	results := Run(paths, nil)
+
+	out := &bytes.Buffer{}
+	if WriteReport(out, results) {
+		t.Errorf("Expected the report to fail")
+	}
+
+	for _, e := range []string{"--- FAIL: lists (failing_test.hoot:3)", "2 files, 3 passed, 4 failed"} {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("Expected report to contain %q, got:\n%s", e, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := WriteJUnit(out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []string{`<testsuites tests="7" failures="4"`, `<testcase name="lists"`, `<failure message=`} {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("Expected JUnit XML to contain %q, got:\n%s", e, out.String())
+		}
+	}
+}